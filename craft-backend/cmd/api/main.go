@@ -3,21 +3,28 @@ package main
 import (
 	"context"
 	"craft/internal/db"
+	"craft/internal/jobs"
+	"craft/internal/notify"
+	"craft/internal/partials"
 	"craft/internal/server"
+	"craft/internal/uploads"
+	"craft/internal/webhooks"
 	"craft/pkg"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata" // analytics time zones on hosts without zoneinfo
 
 	_ "github.com/joho/godotenv/autoload"
 	"github.com/supabase-community/supabase-go"
 )
 
-func gracefulShutdown(fiberServer *server.FiberServer, done chan bool) {
+func gracefulShutdown(fiberServer *server.FiberServer, workers *jobs.Pool, done chan bool) {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
@@ -32,12 +39,66 @@ func gracefulShutdown(fiberServer *server.FiberServer, done chan bool) {
 		log.Printf("Server forced to shutdown with error: %v", err)
 	}
 
+	// no new jobs arrive once requests stop, so let the running ones finish
+	ctx, cancel = context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := workers.Shutdown(ctx); err != nil {
+		log.Printf("Job workers forced to stop: %v", err)
+	}
+
 	log.Println("Server exiting")
 
 	done <- true
 }
 
+// newNotifier sends mail through the configured SMTP server, or logs it when
+// none is configured.
+func newNotifier() (notify.Notifier, error) {
+	if pkg.Envs.SMTP_HOST == "" {
+		log.Println("SMTP_HOST not set, emails will be logged instead of sent")
+		return notify.LogNotifier{}, nil
+	}
+	return notify.NewSMTPNotifier(notify.SMTPConfig{
+		Host:     pkg.Envs.SMTP_HOST,
+		Port:     pkg.Envs.SMTP_PORT,
+		Username: pkg.Envs.SMTP_USERNAME,
+		Password: pkg.Envs.SMTP_PASSWORD,
+		From:     pkg.Envs.SMTP_FROM,
+	})
+}
+
+// newBlobStore keeps uploads on local disk or in an S3-compatible bucket,
+// as STORAGE_DRIVER says.
+func newBlobStore() (uploads.BlobStore, error) {
+	switch pkg.Envs.STORAGE_DRIVER {
+	case "local":
+		return uploads.NewLocalStore(pkg.Envs.STORAGE_DIR)
+	case "s3":
+		return uploads.NewS3Store(uploads.S3Config{
+			Endpoint:  pkg.Envs.S3_ENDPOINT,
+			Region:    pkg.Envs.S3_REGION,
+			Bucket:    pkg.Envs.S3_BUCKET,
+			AccessKey: pkg.Envs.S3_ACCESS_KEY,
+			SecretKey: pkg.Envs.S3_SECRET_KEY,
+			PathStyle: pkg.Envs.S3_PATH_STYLE,
+		})
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_DRIVER %q, want local or s3", pkg.Envs.STORAGE_DRIVER)
+	}
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(pkg.Envs.DATABASE_URL, os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+
+	if missing := pkg.Envs.Missing(); len(missing) > 0 {
+		log.Fatalf("required environment variables not set: %s", strings.Join(missing, ", "))
+	}
+
 	supabaseURL := pkg.Envs.PROJECT_URL
 	supabaseKey := pkg.Envs.ANON_KEY
 	supabaseDB := pkg.Envs.DATABASE_URL
@@ -54,7 +115,12 @@ func main() {
 		log.Fatalf("Failed to initalize the supabase client: %v", err)
 	}
 
-	server := server.New(database, supabaseClient)
+	blobs, err := newBlobStore()
+	if err != nil {
+		log.Fatalf("Failed to initialize upload storage: %v", err)
+	}
+
+	server := server.New(database, supabaseClient, blobs, uploads.NewLinks(pkg.Envs.SECRET_KEY))
 
 	server.RegisterFiberRoutes()
 
@@ -68,7 +134,54 @@ func main() {
 		}
 	}()
 
-	go gracefulShutdown(server, done)
+	notifier, err := newNotifier()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	registry := jobs.NewRegistry()
+	webhooks.NewDeliverer(server.Stores.Webhooks).Register(registry)
+	(&notify.OwnerMailer{
+		Forms:         server.Stores.Forms,
+		Users:         server.Stores.Users,
+		Submissions:   server.Stores.Submissions,
+		Notifications: server.Stores.Notifications,
+		Jobs:          server.Stores.Jobs,
+		Notifier:      notifier,
+		AppURL:        pkg.Envs.APP_URL,
+	}).Register(registry)
+	(&notify.ReceiptMailer{Notifier: notifier}).Register(registry)
+	(&notify.DigestMailer{
+		Digests:     server.Stores.Digests,
+		Users:       server.Stores.Users,
+		Forms:       server.Stores.Forms,
+		Submissions: server.Stores.Submissions,
+		Jobs:        server.Stores.Jobs,
+		Notifier:    notifier,
+		AppURL:      pkg.Envs.APP_URL,
+	}).Register(registry)
+	(&uploads.Cleaner{
+		Uploads: server.Stores.Uploads,
+		Blobs:   blobs,
+		Jobs:    server.Stores.Jobs,
+	}).Register(registry)
+	(&partials.Cleaner{
+		Partials: server.Stores.Partials,
+		Jobs:     server.Stores.Jobs,
+	}).Register(registry)
+	workers := jobs.NewPool(server.Stores.Jobs, registry)
+	workers.Start()
+	if err := notify.ScheduleDigests(context.Background(), server.Stores.Jobs); err != nil {
+		log.Printf("notify: scheduling digests: %v", err)
+	}
+	if err := uploads.ScheduleCleanup(context.Background(), server.Stores.Jobs); err != nil {
+		log.Printf("uploads: scheduling cleanup: %v", err)
+	}
+	if err := partials.ScheduleCleanup(context.Background(), server.Stores.Jobs); err != nil {
+		log.Printf("partials: scheduling cleanup: %v", err)
+	}
+
+	go gracefulShutdown(server, workers, done)
 
 	<-done
 	log.Println("Graceful shutdown complete.")
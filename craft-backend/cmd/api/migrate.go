@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"craft/internal/db"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+const migrateUsage = `usage: api migrate <command>
+
+commands:
+  up        apply all pending migrations
+  down N    roll back the last N applied migrations (default 1)
+  status    list migrations and whether they are applied`
+
+// runMigrate runs a migrate command against the database at connString, the
+// only setting migrations need.
+func runMigrate(connString string, args []string) error {
+	ctx := context.Background()
+
+	if len(args) == 0 {
+		return fmt.Errorf("%s", migrateUsage)
+	}
+	if connString == "" {
+		return fmt.Errorf("DATABASE_URL is not set")
+	}
+	database, err := db.NewDatabase(connString)
+	if err != nil {
+		return err
+	}
+	defer database.Pool.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := database.MigrateUp(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied  %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+		return nil
+
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			var err error
+			n, err = strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid step count %q: %w", args[1], err)
+			}
+		}
+		reverted, err := database.MigrateDown(ctx, n)
+		for _, m := range reverted {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Println("no applied migrations")
+		}
+		return nil
+
+	case "status":
+		status, err := database.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range status {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("unknown migrate command %q\n\n%s", args[0], migrateUsage)
+	}
+}
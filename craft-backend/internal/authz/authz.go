@@ -0,0 +1,76 @@
+// Package authz decides what a caller may do with a form.
+package authz
+
+import (
+	"context"
+	"craft/internal/model"
+	"craft/internal/store"
+
+	"github.com/google/uuid"
+)
+
+const RoleAdmin = "admin"
+
+// Access is a caller's access level to a form. Levels are ordered, so
+// comparisons like access >= AccessOwner are meaningful.
+type Access int
+
+const (
+	AccessNone Access = iota
+	// AccessAdmin lets an administrator read any form.
+	AccessAdmin
+	AccessOwner
+)
+
+func (a Access) String() string {
+	switch a {
+	case AccessOwner:
+		return "owner"
+	case AccessAdmin:
+		return "admin"
+	default:
+		return "none"
+	}
+}
+
+// CanRead reports whether the form and its submissions may be read.
+func (a Access) CanRead() bool {
+	return a != AccessNone
+}
+
+// Principal is the authenticated caller, as set by AuthMiddleware.
+type Principal struct {
+	UserID uuid.UUID
+	Role   string
+}
+
+// FormAccess returns p's access level to f.
+func FormAccess(f *model.Form, p Principal) Access {
+	switch {
+	case f.OwnerID == p.UserID:
+		return AccessOwner
+	case p.Role == RoleAdmin:
+		return AccessAdmin
+	default:
+		return AccessNone
+	}
+}
+
+type Authorizer struct {
+	Forms store.FormStore
+}
+
+func New(forms store.FormStore) *Authorizer {
+	return &Authorizer{Forms: forms}
+}
+
+// Form loads a form with its questions, regardless of owner, together with
+// p's access to it. It returns store.ErrNotFound when the form does not exist;
+// callers must check the access level before using the form.
+func (a *Authorizer) Form(ctx context.Context, formID uuid.UUID, p Principal) (*model.Form, Access, error) {
+	f, err := a.Forms.Get(ctx, formID)
+	if err != nil {
+		return nil, AccessNone, err
+	}
+	return f, FormAccess(f, p), nil
+}
@@ -0,0 +1,18 @@
+do $$
+begin
+if to_regclass('auth.users') is not null then
+   drop trigger if exists on_auth_user_created on auth.users;
+end if;
+end;
+$$;
+
+drop function if exists public.handle_new_user();
+
+drop table if exists answers;
+drop table if exists submissions;
+drop table if exists question_options;
+drop table if exists questions;
+drop table if exists forms;
+drop table if exists public.users;
+
+drop function if exists update_updated_at_column();
@@ -0,0 +1,3 @@
+alter table submissions
+   drop column if exists device_id,
+   drop column if exists fingerprint;
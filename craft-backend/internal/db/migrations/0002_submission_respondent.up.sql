@@ -0,0 +1,6 @@
+alter table submissions
+   add column device_id text,
+   add column fingerprint text;
+
+create index on submissions(form_id, device_id); -- For single-submission checks
+create index on submissions(form_id, fingerprint);
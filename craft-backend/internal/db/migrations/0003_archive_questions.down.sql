@@ -0,0 +1,9 @@
+alter table answers drop constraint if exists answers_question_id_fkey;
+alter table answers
+   add constraint answers_question_id_fkey foreign key (question_id) references questions(id) on delete cascade;
+
+delete from question_options where archived_at is not null;
+delete from questions where archived_at is not null;
+
+alter table question_options drop column archived_at;
+alter table questions drop column archived_at;
@@ -0,0 +1,9 @@
+alter table questions add column archived_at timestamptz;
+alter table question_options add column archived_at timestamptz;
+
+-- questions are archived instead of deleted once edited away, so refuse to
+-- drop a question that still has answers. Deleting a whole form still works
+-- because its submissions, and with them the answers, go in the same statement.
+alter table answers drop constraint if exists answers_question_id_fkey;
+alter table answers
+   add constraint answers_question_id_fkey foreign key (question_id) references questions(id);
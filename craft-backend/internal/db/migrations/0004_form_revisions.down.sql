@@ -0,0 +1,3 @@
+alter table submissions drop column if exists revision_id;
+
+drop table if exists form_revisions;
@@ -0,0 +1,22 @@
+create table form_revisions (
+id uuid primary key default gen_random_uuid(),
+form_id uuid references forms(id) on delete cascade not null,
+
+revision integer not null,
+reason text not null, -- save | publish | restore
+
+title text not null,
+description text,
+-- questions with their options, as returned by GET /user/forms/:id
+questions jsonb not null default '[]',
+
+created_at timestamptz default now(),
+
+constraint form_revisions_form_revision_key unique (form_id, revision),
+constraint valid_revision_reason check (reason in ('save', 'publish', 'restore'))
+);
+
+alter table submissions
+   add column revision_id uuid references form_revisions(id) on delete set null;
+
+create index on submissions(revision_id);
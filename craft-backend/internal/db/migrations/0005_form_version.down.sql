@@ -0,0 +1 @@
+alter table forms drop column if exists version;
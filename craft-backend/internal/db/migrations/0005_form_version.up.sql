@@ -0,0 +1,2 @@
+-- bumped on every write so concurrent editors can detect each other (ETag)
+alter table forms add column version integer not null default 1;
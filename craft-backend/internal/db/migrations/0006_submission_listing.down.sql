@@ -0,0 +1,3 @@
+drop index if exists answers_value_fts_idx;
+drop index if exists answers_value_idx;
+drop index if exists submissions_form_created_idx;
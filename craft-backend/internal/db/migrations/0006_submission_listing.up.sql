@@ -0,0 +1,10 @@
+-- keyset pagination over (created_at, id), newest first
+create index submissions_form_created_idx on submissions(form_id, created_at desc, id desc);
+
+-- answer value filters (jsonb containment)
+create index answers_value_idx on answers using gin (value jsonb_path_ops);
+
+-- full-text search across text answers
+create index answers_value_fts_idx on answers
+   using gin (to_tsvector('simple', value #>> '{}'))
+   where jsonb_typeof(value) = 'string';
@@ -0,0 +1,8 @@
+alter table questions drop column if exists rating;
+alter table questions drop column if exists number;
+
+update questions set type = 'short-text' where type in ('number', 'rating');
+alter table questions drop constraint valid_question_type;
+alter table questions add constraint valid_question_type check (
+   type in ('short-text', 'long-text', 'single-select', 'multi-select', 'dropdown')
+);
@@ -0,0 +1,8 @@
+alter table questions drop constraint valid_question_type;
+alter table questions add constraint valid_question_type check (
+   type in ('short-text', 'long-text', 'single-select', 'multi-select', 'dropdown', 'number', 'rating')
+);
+
+-- Bounds of number questions and the scale of rating questions.
+alter table questions add column number jsonb;
+alter table questions add column rating jsonb;
@@ -0,0 +1,19 @@
+-- Respondent activity on public forms, for the view -> start -> complete
+-- funnel. session_id is the respondent's device cookie, which submissions
+-- record as device_id.
+create table form_events (
+id uuid primary key default gen_random_uuid(),
+form_id uuid references forms(id) on delete cascade not null,
+session_id text not null,
+kind text not null, -- view | start | progress
+question_id uuid references questions(id) on delete cascade, -- progress only: the question reached
+
+created_at timestamptz default now(),
+
+constraint valid_event_kind check (kind in ('view', 'start', 'progress')),
+constraint progress_has_question check ((kind = 'progress') = (question_id is not null))
+);
+
+-- each session counts once per step, so repeated beacons are dropped
+create unique index form_events_once_idx
+   on form_events(form_id, session_id, kind, coalesce(question_id, '00000000-0000-0000-0000-000000000000'));
@@ -0,0 +1,38 @@
+create table webhooks (
+id uuid primary key default gen_random_uuid(),
+form_id uuid references forms(id) on delete cascade not null,
+
+url text not null,
+secret text not null, -- HMAC-SHA256 key for the X-Craft-Signature header
+events text[] not null default '{submission.created}',
+active boolean not null default true,
+
+created_at timestamptz default now(),
+updated_at timestamptz default now()
+);
+
+create index on webhooks(form_id);
+
+-- One row per event sent to a webhook. Pending rows double as the delivery
+-- queue: the dispatcher claims those whose next_attempt_at has passed.
+create table webhook_deliveries (
+id uuid primary key default gen_random_uuid(),
+webhook_id uuid references webhooks(id) on delete cascade not null,
+
+event text not null,
+payload jsonb not null,
+
+status text not null default 'pending', -- pending | succeeded | failed
+attempts integer not null default 0,
+next_attempt_at timestamptz default now(),
+last_status_code integer,
+last_error text,
+delivered_at timestamptz,
+
+created_at timestamptz default now(),
+
+constraint valid_delivery_status check (status in ('pending', 'succeeded', 'failed'))
+);
+
+create index on webhook_deliveries(next_attempt_at) where status = 'pending';
+create index on webhook_deliveries(webhook_id, created_at desc);
@@ -0,0 +1,3 @@
+create index on webhook_deliveries(next_attempt_at) where status = 'pending';
+
+drop table if exists jobs;
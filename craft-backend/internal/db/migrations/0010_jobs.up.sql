@@ -0,0 +1,27 @@
+-- Background jobs. Workers claim due pending rows with FOR UPDATE SKIP LOCKED
+-- and hold them for a lease; a running row whose lease ran out belongs to a
+-- worker that died and is claimed again. Finished jobs are deleted, failed
+-- ones out of attempts are kept as dead letters.
+create table jobs (
+id uuid primary key default gen_random_uuid(),
+kind text not null,
+payload jsonb not null,
+
+status text not null default 'pending', -- pending | running | dead
+attempts integer not null default 0,
+run_at timestamptz not null default now(),
+locked_until timestamptz,
+last_error text,
+
+created_at timestamptz default now(),
+updated_at timestamptz default now(),
+
+constraint valid_job_status check (status in ('pending', 'running', 'dead'))
+);
+
+create index jobs_due_idx on jobs(run_at) where status = 'pending';
+create index jobs_lease_idx on jobs(locked_until) where status = 'running';
+create index jobs_dead_idx on jobs(updated_at desc) where status = 'dead';
+
+-- webhook deliveries are now scheduled as jobs rather than polled
+drop index if exists webhook_deliveries_next_attempt_at_idx;
@@ -0,0 +1,6 @@
+drop table if exists notification_watermarks;
+
+drop index if exists jobs_unique_key_idx;
+alter table jobs drop column if exists unique_key;
+
+alter table forms drop column if exists notify_owner;
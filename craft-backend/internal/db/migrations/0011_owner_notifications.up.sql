@@ -0,0 +1,15 @@
+alter table forms add column notify_owner boolean not null default false;
+
+-- at most one pending job per key, so bursts of work collapse into one job
+alter table jobs add column unique_key text;
+create unique index jobs_unique_key_idx on jobs(unique_key) where status = 'pending';
+
+-- The last submission each notification stream (keyed by purpose and owner)
+-- has reported, in (created_at, id) order.
+create table notification_watermarks (
+key text primary key,
+created_at timestamptz not null,
+submission_id uuid not null,
+
+updated_at timestamptz default now()
+);
@@ -0,0 +1,7 @@
+drop table if exists form_autoresponders;
+
+update questions set type = 'short-text' where type = 'email';
+alter table questions drop constraint valid_question_type;
+alter table questions add constraint valid_question_type check (
+   type in ('short-text', 'long-text', 'single-select', 'multi-select', 'dropdown', 'number', 'rating')
+);
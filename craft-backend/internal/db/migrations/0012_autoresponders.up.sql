@@ -0,0 +1,18 @@
+alter table questions drop constraint valid_question_type;
+alter table questions add constraint valid_question_type check (
+   type in ('short-text', 'long-text', 'single-select', 'multi-select', 'dropdown', 'email', 'number', 'rating')
+);
+
+-- The receipt emailed to respondents, to the address they gave in
+-- question_id. Subject and body are templates over the answers.
+create table form_autoresponders (
+form_id uuid primary key references forms(id) on delete cascade,
+enabled boolean not null default false,
+question_id uuid references questions(id) on delete set null,
+
+subject text not null,
+body text not null,
+
+created_at timestamptz default now(),
+updated_at timestamptz default now()
+);
@@ -0,0 +1,9 @@
+-- How many emails went out under each key (a recipient, a sender IP...)
+-- since the window in which they are counted began.
+create table notification_throttles (
+key text primary key,
+count integer not null,
+expires_at timestamptz not null
+);
+
+create index on notification_throttles(expires_at);
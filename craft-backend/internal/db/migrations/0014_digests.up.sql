@@ -0,0 +1,16 @@
+-- How often each user wants a digest of new submissions to their forms.
+-- sent_through ends the period the last digest covered; the next one starts
+-- there.
+create table digest_preferences (
+user_id uuid primary key references public.users(id) on delete cascade,
+frequency text not null default 'off',
+timezone text not null default 'UTC',
+sent_through timestamptz,
+
+created_at timestamptz default now(),
+updated_at timestamptz default now(),
+
+constraint valid_digest_frequency check (frequency in ('off', 'daily', 'weekly'))
+);
+
+create index digest_preferences_enabled_idx on digest_preferences(user_id) where frequency <> 'off';
@@ -0,0 +1,9 @@
+drop table if exists uploads;
+
+alter table questions drop column if exists file_upload;
+
+update questions set type = 'short-text' where type = 'file_upload';
+alter table questions drop constraint valid_question_type;
+alter table questions add constraint valid_question_type check (
+   type in ('short-text', 'long-text', 'single-select', 'multi-select', 'dropdown', 'email', 'number', 'rating')
+);
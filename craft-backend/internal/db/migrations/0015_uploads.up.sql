@@ -0,0 +1,27 @@
+alter table questions drop constraint valid_question_type;
+alter table questions add constraint valid_question_type check (
+   type in ('short-text', 'long-text', 'single-select', 'multi-select', 'dropdown', 'email', 'number', 'rating', 'file_upload')
+);
+
+alter table questions add column file_upload jsonb;
+
+-- Files respondents attach to file_upload questions. An upload is stored
+-- before the submission that names it; uploads left without a submission,
+-- including those of deleted submissions and forms, are cleaned up with
+-- their blobs, so form_id and question_id are deliberately not foreign keys.
+create table uploads (
+id uuid primary key default gen_random_uuid(),
+form_id uuid not null,
+question_id uuid not null,
+submission_id uuid references submissions(id) on delete set null,
+
+blob_key text not null unique,
+filename text not null,
+content_type text not null,
+size bigint not null,
+
+created_at timestamptz default now()
+);
+
+create index on uploads(submission_id);
+create index uploads_unattached_idx on uploads(created_at) where submission_id is null;
@@ -0,0 +1 @@
+alter table questions drop column if exists visibility;
@@ -0,0 +1,2 @@
+-- Rules that show a question only for some answers to earlier questions.
+alter table questions add column visibility jsonb;
@@ -0,0 +1,5 @@
+alter table form_revisions drop column if exists sections;
+
+alter table questions drop column if exists section_id;
+
+drop table if exists form_sections;
@@ -0,0 +1,18 @@
+-- Pages of a form. Their questions follow one another by position, and jumps
+-- send respondents past pages depending on their answers.
+create table form_sections (
+id uuid primary key,
+form_id uuid not null references forms(id) on delete cascade,
+position int not null,
+title text not null default '',
+description text,
+jumps jsonb,
+
+created_at timestamptz default now()
+);
+
+create index on form_sections(form_id, position);
+
+alter table questions add column section_id uuid references form_sections(id) on delete set null;
+
+alter table form_revisions add column sections jsonb not null default '[]';
@@ -0,0 +1 @@
+drop table if exists partial_responses;
@@ -0,0 +1,18 @@
+-- Answers respondents saved to finish a form later. They are found by the
+-- hash of a resume token only the respondent holds, and are deleted on
+-- submit or once they expire.
+create table partial_responses (
+id uuid primary key default gen_random_uuid(),
+form_id uuid not null references forms(id) on delete cascade,
+token_hash text not null unique,
+answers jsonb not null default '[]',
+respondent_email text,
+section_id uuid,
+
+created_at timestamptz default now(),
+updated_at timestamptz default now(),
+expires_at timestamptz not null
+);
+
+create index on partial_responses(form_id);
+create index on partial_responses(expires_at);
@@ -0,0 +1,56 @@
+package export
+
+import (
+	"craft/internal/model"
+	"encoding/csv"
+	"io"
+)
+
+// CSVWriter writes one row per submission. Rows are not buffered beyond the
+// underlying csv.Writer, so large exports can be streamed.
+type CSVWriter struct {
+	w       *csv.Writer
+	columns []model.Question
+}
+
+func NewCSVWriter(w io.Writer, f *model.Form) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w), columns: Columns(f)}
+}
+
+func (cw *CSVWriter) WriteHeader() error {
+	header := append([]string{}, MetadataColumns...)
+	for _, q := range cw.columns {
+		header = append(header, ColumnTitle(q))
+	}
+	return cw.write(header)
+}
+
+func (cw *CSVWriter) Write(sub *model.SubmissionWithAnswers) error {
+	return cw.write(Row(cw.columns, sub))
+}
+
+// Flush writes buffered rows and reports any write error.
+func (cw *CSVWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *CSVWriter) write(record []string) error {
+	for i, cell := range record {
+		record[i] = escapeFormula(cell)
+	}
+	return cw.w.Write(record)
+}
+
+// escapeFormula keeps spreadsheet apps from evaluating respondent input that
+// starts like a formula.
+func escapeFormula(cell string) string {
+	if cell == "" {
+		return cell
+	}
+	switch cell[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + cell
+	}
+	return cell
+}
@@ -0,0 +1,99 @@
+package export
+
+import (
+	"bytes"
+	"craft/internal/model"
+	"encoding/csv"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCSVWriter(t *testing.T) {
+	vegan, halal := uuid.New(), uuid.New()
+	removed := time.Now()
+	f := &model.Form{Questions: []model.Question{
+		{ID: uuid.New(), Type: model.QuestionTypeMultiSelect, Title: "Diet", Position: 1, Options: []model.Option{
+			{ID: vegan, Label: "Vegan"},
+			{ID: halal, Label: "Halal"},
+		}},
+		{ID: uuid.New(), Type: model.QuestionTypeShortText, Title: "Nickname", Position: 0, ArchivedAt: &removed},
+		{ID: uuid.New(), Type: model.QuestionTypeShortText, Title: "Name", Position: 0},
+		{ID: uuid.New(), Type: model.QuestionTypeFileUpload, Title: "CV", Position: 2},
+	}}
+	diet, nickname, name, cv := f.Questions[0].ID, f.Questions[1].ID, f.Questions[2].ID, f.Questions[3].ID
+
+	var buf bytes.Buffer
+	cw := NewCSVWriter(&buf, f)
+	if err := cw.WriteHeader(); err != nil {
+		t.Fatal(err)
+	}
+	email := "=HYPERLINK(\"http://evil.example\")"
+	subs := []model.SubmissionWithAnswers{
+		{Submission: model.Submission{ID: uuid.New(), CreatedAt: time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC), RespondentEmail: &email}, Answers: []model.Answer{
+			{QuestionID: name, Value: json.RawMessage(`"+1 555 0100"`)},
+			{QuestionID: diet, Value: json.RawMessage(`["` + vegan.String() + `","` + halal.String() + `"]`)},
+			{QuestionID: nickname, Value: json.RawMessage(`"@ada"`)},
+			{QuestionID: cv, Value: json.RawMessage(`["` + uuid.NewString() + `","` + uuid.NewString() + `"]`), Files: []model.Upload{
+				{Filename: "cv.pdf"},
+				{Filename: "-photo.png"},
+			}},
+		}},
+		{Submission: model.Submission{ID: uuid.New(), CreatedAt: time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)}, Answers: []model.Answer{
+			{QuestionID: name, Value: json.RawMessage(`"Grace, \"Amazing\" Hopper"`)},
+			{QuestionID: diet, Value: json.RawMessage(`["Something else"]`)},
+		}},
+	}
+	for i := range subs {
+		if err := cw.Write(&subs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		// live questions by position, then the removed one
+		{"submission_id", "created_at", "respondent_email", "Name", "Diet", "CV", "Nickname (removed)"},
+		// cells that would start a formula are quoted; choices are joined
+		// by label and files named
+		{subs[0].ID.String(), "2026-03-01T09:30:00Z", "'" + email, "'+1 555 0100", "Vegan; Halal", "cv.pdf; -photo.png", "'@ada"},
+		// unknown choices are kept as given, unanswered questions are empty
+		{subs[1].ID.String(), "2026-03-02T09:30:00Z", "", `Grace, "Amazing" Hopper`, "Something else", "", ""},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("%d records, want %d: %q", len(records), len(want), records)
+	}
+	for i := range want {
+		if !slices.Equal(records[i], want[i]) {
+			t.Errorf("record %d:\n got %q\nwant %q", i, records[i], want[i])
+		}
+	}
+}
+
+func TestEscapeFormula(t *testing.T) {
+	for in, want := range map[string]string{
+		"=1+1":       "'=1+1",
+		"+1":         "'+1",
+		"-1":         "'-1",
+		"@SUM(A1)":   "'@SUM(A1)",
+		"\t=1":       "'\t=1",
+		"plain":      "plain",
+		"a=b":        "a=b",
+		"":           "",
+		"1-2":        "1-2",
+		"ada@ex.com": "ada@ex.com",
+	} {
+		if got := escapeFormula(in); got != want {
+			t.Errorf("escapeFormula(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Package export renders form submissions as downloadable files.
+package export
+
+import (
+	"craft/internal/model"
+	"encoding/json"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MultiValueSeparator joins the choices of a multi-select answer in a single cell.
+const MultiValueSeparator = "; "
+
+// MetadataColumns precede the question columns of every export.
+var MetadataColumns = []string{"submission_id", "created_at", "respondent_email"}
+
+// Columns returns the form's questions, one export column each: live ones
+// ordered by position, then archived ones, so answers given before a
+// question was removed are exported too.
+func Columns(f *model.Form) []model.Question {
+	questions := slices.Clone(f.Questions)
+	sort.SliceStable(questions, func(i, j int) bool {
+		if archived := questions[i].ArchivedAt != nil; archived != (questions[j].ArchivedAt != nil) {
+			return !archived
+		}
+		return questions[i].Position < questions[j].Position
+	})
+	return questions
+}
+
+// ColumnTitle heads the column of q, marking questions since removed.
+func ColumnTitle(q model.Question) string {
+	if q.ArchivedAt != nil {
+		return q.Title + " (removed)"
+	}
+	return q.Title
+}
+
+// Row flattens a submission into cells matching MetadataColumns followed by
+// one cell per question in columns.
+func Row(columns []model.Question, sub *model.SubmissionWithAnswers) []string {
+	row := make([]string, 0, len(MetadataColumns)+len(columns))
+	email := ""
+	if sub.RespondentEmail != nil {
+		email = *sub.RespondentEmail
+	}
+	row = append(row, sub.ID.String(), sub.CreatedAt.UTC().Format(time.RFC3339), email)
+
+	answers := make(map[uuid.UUID]model.Answer, len(sub.Answers))
+	for _, a := range sub.Answers {
+		answers[a.QuestionID] = a
+	}
+	for _, q := range columns {
+		row = append(row, answerText(q, answers[q.ID]))
+	}
+	return row
+}
+
+// answerText is FormatAnswer, except that file_upload answers with their
+// Files attached are shown by file name rather than upload ID.
+func answerText(q model.Question, a model.Answer) string {
+	if q.Type != model.QuestionTypeFileUpload || len(a.Files) == 0 {
+		return FormatAnswer(q, a.Value)
+	}
+	names := make([]string, len(a.Files))
+	for i, f := range a.Files {
+		names[i] = f.Filename
+	}
+	return strings.Join(names, MultiValueSeparator)
+}
+
+// FormatAnswer renders an answer as text. Choices stored as option IDs are
+// shown by label and multi-select choices are joined with MultiValueSeparator.
+func FormatAnswer(q model.Question, raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return string(raw)
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return optionLabel(q, v)
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				parts = append(parts, optionLabel(q, s))
+			}
+		}
+		return strings.Join(parts, MultiValueSeparator)
+	default:
+		return string(raw)
+	}
+}
+
+func optionLabel(q model.Question, choice string) string {
+	for _, opt := range q.Options {
+		if opt.ID.String() == choice {
+			return opt.Label
+		}
+	}
+	return choice
+}
@@ -0,0 +1,64 @@
+package export
+
+import (
+	"craft/internal/model"
+	"craft/internal/store"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NDJSONRecord is one line of an NDJSON export.
+type NDJSONRecord struct {
+	ID               uuid.UUID  `json:"id"`
+	FormID           uuid.UUID  `json:"form_id"`
+	RevisionID       *uuid.UUID `json:"revision_id"`
+	RespondentEmail  *string    `json:"respondent_email"`
+	RespondentUserID *uuid.UUID `json:"respondent_user_id"`
+	CreatedAt        time.Time  `json:"created_at"`
+	// Answers is keyed by question ID.
+	Answers map[string]NDJSONAnswer `json:"answers"`
+	// Cursor resumes the export after this record when passed as ?since=.
+	Cursor string `json:"cursor"`
+}
+
+type NDJSONAnswer struct {
+	QuestionTitle    string          `json:"question_title"`
+	QuestionType     string          `json:"question_type"`
+	QuestionArchived bool            `json:"question_archived,omitempty"`
+	Value            json.RawMessage `json:"value"`
+}
+
+// NDJSONWriter writes one JSON object per line per submission.
+type NDJSONWriter struct {
+	enc *json.Encoder
+}
+
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	return &NDJSONWriter{enc: json.NewEncoder(w)}
+}
+
+func (nw *NDJSONWriter) Write(sub *model.SubmissionWithAnswers) error {
+	rec := NDJSONRecord{
+		ID:               sub.ID,
+		FormID:           sub.FormID,
+		RevisionID:       sub.RevisionID,
+		RespondentEmail:  sub.RespondentEmail,
+		RespondentUserID: sub.RespondentUserID,
+		CreatedAt:        sub.CreatedAt,
+		Answers:          make(map[string]NDJSONAnswer, len(sub.Answers)),
+		Cursor:           store.SubmissionCursor{CreatedAt: sub.CreatedAt, ID: sub.ID}.String(),
+	}
+	for _, a := range sub.Answers {
+		rec.Answers[a.QuestionID.String()] = NDJSONAnswer{
+			QuestionTitle:    a.QuestionTitle,
+			QuestionType:     a.QuestionType,
+			QuestionArchived: a.QuestionArchived,
+			Value:            a.Value,
+		}
+	}
+	// Encode terminates every value with a newline
+	return nw.enc.Encode(rec)
+}
@@ -0,0 +1,259 @@
+package export
+
+import (
+	"craft/internal/model"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	// CurrentSheet holds submissions that have no recorded revision.
+	CurrentSheet   = "Current"
+	QuestionsSheet = "Questions"
+
+	minColumnWidth = 10
+	maxColumnWidth = 60
+)
+
+var frozenHeader = &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}
+
+// XLSXWriter builds a workbook with one sheet of submissions per form
+// revision, whose columns are the questions of that revision, and a sheet
+// describing every question shown. Sheets are written through excelize
+// stream writers, which spill to temporary files for large exports.
+type XLSXWriter struct {
+	file      *excelize.File
+	form      *model.Form
+	revisions map[uuid.UUID]*model.FormRevision
+	sheets    map[uuid.UUID]*xlsxSheet
+	order     []*xlsxSheet
+	header    int
+}
+
+type xlsxSheet struct {
+	name     string
+	revision int // 0 for CurrentSheet
+	columns  []model.Question
+	stream   *excelize.StreamWriter
+	row      int
+}
+
+// NewXLSXWriter prepares a workbook for f. revisions must include, with their
+// questions, every revision the written submissions reference; submissions of
+// other revisions go to CurrentSheet with f's questions as columns.
+func NewXLSXWriter(f *model.Form, revisions []model.FormRevision) (*XLSXWriter, error) {
+	file := excelize.NewFile()
+	header, err := file.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	x := &XLSXWriter{
+		file:      file,
+		form:      f,
+		revisions: make(map[uuid.UUID]*model.FormRevision, len(revisions)),
+		sheets:    make(map[uuid.UUID]*xlsxSheet),
+		header:    header,
+	}
+	for i := range revisions {
+		x.revisions[revisions[i].ID] = &revisions[i]
+	}
+	return x, nil
+}
+
+func (x *XLSXWriter) Write(sub *model.SubmissionWithAnswers) error {
+	key := uuid.Nil
+	if sub.RevisionID != nil {
+		if _, ok := x.revisions[*sub.RevisionID]; ok {
+			key = *sub.RevisionID
+		}
+	}
+
+	sheet, err := x.sheet(key)
+	if err != nil {
+		return err
+	}
+
+	row := make([]any, 0, len(MetadataColumns)+len(sheet.columns))
+	var email any
+	if sub.RespondentEmail != nil {
+		email = *sub.RespondentEmail
+	}
+	row = append(row, sub.ID.String(), sub.CreatedAt.UTC(), email)
+
+	answers := make(map[uuid.UUID]model.Answer, len(sub.Answers))
+	for _, a := range sub.Answers {
+		answers[a.QuestionID] = a
+	}
+	for _, q := range sheet.columns {
+		row = append(row, cellValue(q, answers[q.ID]))
+	}
+
+	sheet.row++
+	cell, _ := excelize.CoordinatesToCellName(1, sheet.row)
+	return sheet.stream.SetRow(cell, row)
+}
+
+// sheet returns the sheet for a revision ID, or uuid.Nil for CurrentSheet,
+// creating it with its header row on first use.
+func (x *XLSXWriter) sheet(key uuid.UUID) (*xlsxSheet, error) {
+	if s, ok := x.sheets[key]; ok {
+		return s, nil
+	}
+
+	s := &xlsxSheet{name: CurrentSheet, columns: Columns(x.form)}
+	if rev, ok := x.revisions[key]; ok {
+		s.name = fmt.Sprintf("Revision %d", rev.Revision)
+		s.revision = rev.Revision
+		s.columns = Columns(&model.Form{Questions: rev.Questions})
+	}
+
+	if _, err := x.file.NewSheet(s.name); err != nil {
+		return nil, err
+	}
+	stream, err := x.file.NewStreamWriter(s.name)
+	if err != nil {
+		return nil, err
+	}
+	s.stream = stream
+
+	header := append([]string{}, MetadataColumns...)
+	for _, q := range s.columns {
+		header = append(header, ColumnTitle(q))
+	}
+	// widths and panes must be set before the first row
+	for i, title := range header {
+		width := float64(min(max(utf8.RuneCountInString(title)+2, minColumnWidth), maxColumnWidth))
+		if i < len(MetadataColumns) {
+			width = 24
+		}
+		if err := stream.SetColWidth(i+1, i+1, width); err != nil {
+			return nil, err
+		}
+	}
+	if err := stream.SetPanes(frozenHeader); err != nil {
+		return nil, err
+	}
+
+	cells := make([]any, len(header))
+	for i, title := range header {
+		cells[i] = excelize.Cell{StyleID: x.header, Value: title}
+	}
+	s.row = 1
+	if err := stream.SetRow("A1", cells); err != nil {
+		return nil, err
+	}
+
+	x.sheets[key] = s
+	x.order = append(x.order, s)
+	return s, nil
+}
+
+// WriteTo finishes the workbook and writes it to w. The writer must not be
+// used afterwards except to Close it.
+func (x *XLSXWriter) WriteTo(w io.Writer) (int64, error) {
+	if len(x.order) == 0 {
+		if _, err := x.sheet(uuid.Nil); err != nil {
+			return 0, err
+		}
+	}
+	for _, s := range x.order {
+		if err := s.stream.Flush(); err != nil {
+			return 0, err
+		}
+	}
+	if err := x.writeQuestions(); err != nil {
+		return 0, err
+	}
+
+	// drop the default sheet excelize starts with
+	if err := x.file.DeleteSheet("Sheet1"); err != nil {
+		return 0, err
+	}
+	x.file.SetActiveSheet(0)
+	return x.file.WriteTo(w)
+}
+
+func (x *XLSXWriter) Close() error {
+	return x.file.Close()
+}
+
+// writeQuestions adds QuestionsSheet, describing the columns of every
+// submission sheet so the workbook is self-describing.
+func (x *XLSXWriter) writeQuestions() error {
+	if _, err := x.file.NewSheet(QuestionsSheet); err != nil {
+		return err
+	}
+	stream, err := x.file.NewStreamWriter(QuestionsSheet)
+	if err != nil {
+		return err
+	}
+
+	header := []string{"sheet", "revision", "position", "question_id", "title", "type", "required", "options"}
+	widths := []float64{14, 10, 10, 38, 40, 14, 10, 60}
+	for i, w := range widths {
+		if err := stream.SetColWidth(i+1, i+1, w); err != nil {
+			return err
+		}
+	}
+	if err := stream.SetPanes(frozenHeader); err != nil {
+		return err
+	}
+	cells := make([]any, len(header))
+	for i, title := range header {
+		cells[i] = excelize.Cell{StyleID: x.header, Value: title}
+	}
+	if err := stream.SetRow("A1", cells); err != nil {
+		return err
+	}
+
+	sheets := append([]*xlsxSheet{}, x.order...)
+	sort.SliceStable(sheets, func(i, j int) bool { return sheets[i].revision < sheets[j].revision })
+
+	row := 1
+	for _, s := range sheets {
+		for _, q := range s.columns {
+			options := make([]string, len(q.Options))
+			for i, opt := range q.Options {
+				options[i] = opt.Label
+			}
+			var revision any
+			if s.revision > 0 {
+				revision = s.revision
+			}
+
+			row++
+			cell, _ := excelize.CoordinatesToCellName(1, row)
+			err := stream.SetRow(cell, []any{
+				s.name, revision, q.Position + 1, q.ID.String(), q.Title, q.Type, q.Required,
+				strings.Join(options, MultiValueSeparator),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return stream.Flush()
+}
+
+// cellValue is the text of an answer with numbers kept numeric.
+func cellValue(q model.Question, a model.Answer) any {
+	var n json.Number
+	if len(a.Value) > 0 && a.Value[0] != '"' && json.Unmarshal(a.Value, &n) == nil {
+		if f, err := n.Float64(); err == nil {
+			return f
+		}
+	}
+	if s := answerText(q, a); s != "" {
+		return s
+	}
+	return nil
+}
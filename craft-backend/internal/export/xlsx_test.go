@@ -0,0 +1,116 @@
+package export
+
+import (
+	"bytes"
+	"craft/internal/model"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestXLSXWriter(t *testing.T) {
+	name := model.Question{ID: uuid.New(), Type: model.QuestionTypeShortText, Title: "Name", Position: 0}
+	age := model.Question{ID: uuid.New(), Type: model.QuestionTypeNumber, Title: "Age", Position: 1}
+	first := model.FormRevision{ID: uuid.New(), Revision: 1, Questions: []model.Question{name}}
+	second := model.FormRevision{ID: uuid.New(), Revision: 2, Questions: []model.Question{name, age}}
+	f := &model.Form{Questions: second.Questions}
+
+	// the first revision was never answered and gets no sheet
+	x, err := NewXLSXWriter(f, []model.FormRevision{second, first})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer x.Close()
+
+	created := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	subs := []model.SubmissionWithAnswers{
+		{Submission: model.Submission{ID: uuid.New(), RevisionID: &second.ID, CreatedAt: created}, Answers: []model.Answer{
+			{QuestionID: name.ID, Value: json.RawMessage(`"Ada"`)},
+			{QuestionID: age.ID, Value: json.RawMessage(`36`)},
+		}},
+		// from before revisions were recorded
+		{Submission: model.Submission{ID: uuid.New(), CreatedAt: created}, Answers: []model.Answer{
+			{QuestionID: name.ID, Value: json.RawMessage(`"Grace"`)},
+		}},
+	}
+	for i := range subs {
+		if err := x.Write(&subs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if _, err := x.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if got, want := file.GetSheetList(), []string{"Revision 2", CurrentSheet, QuestionsSheet}; !slices.Equal(got, want) {
+		t.Fatalf("sheets %q, want %q", got, want)
+	}
+
+	rows, err := file.GetRows("Revision 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := append(slices.Clone(MetadataColumns), "Name", "Age"); !slices.Equal(rows[0], want) {
+		t.Fatalf("header %q, want %q", rows[0], want)
+	}
+	if len(rows) != 2 || rows[1][len(MetadataColumns)] != "Ada" {
+		t.Fatalf("rows %q", rows)
+	}
+
+	// the answer to the number question and the submission time are
+	// numbers to Excel, the latter formatted as a date
+	ageCell, _ := excelize.CoordinatesToCellName(len(MetadataColumns)+2, 2)
+	for _, cell := range []string{"B2", ageCell} {
+		typ, err := file.GetCellType("Revision 2", cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typ != excelize.CellTypeNumber && typ != excelize.CellTypeUnset {
+			t.Fatalf("%s has type %v, want a number", cell, typ)
+		}
+	}
+	if v, _ := file.GetCellValue("Revision 2", ageCell, excelize.Options{RawCellValue: true}); v != "36" {
+		t.Fatalf("age %q, want 36", v)
+	}
+	if v, _ := file.GetCellValue("Revision 2", "B2"); v == "" || v == created.Format(time.RFC3339) {
+		t.Fatalf("created_at %q, want a formatted date", v)
+	}
+
+	rows, err = file.GetRows(CurrentSheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1][len(MetadataColumns)] != "Grace" {
+		t.Fatalf("current rows %q", rows)
+	}
+
+	rows, err = file.GetRows(QuestionsSheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"sheet", "revision", "position", "question_id", "title", "type", "required", "options"},
+		{CurrentSheet, "", "1", name.ID.String(), "Name", model.QuestionTypeShortText, "FALSE"},
+		{CurrentSheet, "", "2", age.ID.String(), "Age", model.QuestionTypeNumber, "FALSE"},
+		{"Revision 2", "2", "1", name.ID.String(), "Name", model.QuestionTypeShortText, "FALSE"},
+		{"Revision 2", "2", "2", age.ID.String(), "Age", model.QuestionTypeNumber, "FALSE"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("questions %q, want %q", rows, want)
+	}
+	for i := range want {
+		if !slices.Equal(rows[i], want[i]) {
+			t.Fatalf("questions row %d %q, want %q", i, rows[i], want[i])
+		}
+	}
+}
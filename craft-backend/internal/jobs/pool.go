@@ -0,0 +1,165 @@
+package jobs
+
+import (
+	"context"
+	"craft/internal/model"
+	"craft/internal/store"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultWorkers      = 4
+	DefaultPollInterval = 2 * time.Second
+
+	// leaseMargin is added to the longest handler timeout so a job is not
+	// claimed again while its first run is still finishing.
+	leaseMargin  = time.Minute
+	storeTimeout = 10 * time.Second
+)
+
+// Pool runs jobs of the kinds in its registry. Pools in several processes may
+// share a store; each job is claimed by one worker at a time.
+type Pool struct {
+	Store        store.JobStore
+	Registry     *Registry
+	Workers      int
+	PollInterval time.Duration
+
+	stop   chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewPool(jobs store.JobStore, registry *Registry) *Pool {
+	return &Pool{
+		Store:        jobs,
+		Registry:     registry,
+		Workers:      DefaultWorkers,
+		PollInterval: DefaultPollInterval,
+	}
+}
+
+// Start launches the workers. It must be called once.
+func (p *Pool) Start() {
+	kinds := p.Registry.Kinds()
+	lease := leaseMargin
+	for _, h := range p.Registry.handlers {
+		lease = max(lease, h.Timeout+leaseMargin)
+	}
+
+	var ctx context.Context
+	ctx, p.cancel = context.WithCancel(context.Background())
+	p.stop = make(chan struct{})
+	for range p.Workers {
+		p.wg.Add(1)
+		go p.work(ctx, kinds, lease)
+	}
+}
+
+// Shutdown stops claiming jobs and waits for those running to finish. If ctx
+// ends first, running jobs are cancelled and retried later.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	close(p.stop)
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) work(ctx context.Context, kinds []string, lease time.Duration) {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		default:
+		}
+
+		claimed, err := p.Store.Claim(ctx, kinds, 1, lease)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("jobs: claiming: %v", err)
+		}
+		if len(claimed) == 0 {
+			select {
+			case <-p.stop:
+				return
+			case <-time.After(p.PollInterval):
+			}
+			continue
+		}
+
+		p.run(ctx, claimed[0])
+	}
+}
+
+func (p *Pool) run(ctx context.Context, job model.Job) {
+	h := p.Registry.handlers[job.Kind]
+
+	var retryAt *time.Time
+	if job.Attempts < h.MaxAttempts {
+		t := time.Now().Add(h.Backoff(job.Attempts))
+		retryAt = &t
+	}
+
+	var err error
+	// a job whose worker died mid-run may come back with no attempts left
+	if job.Attempts > h.MaxAttempts {
+		err = fmt.Errorf("gave up after %d attempts", h.MaxAttempts)
+	} else {
+		err = p.call(ctx, h, job, retryAt)
+	}
+
+	// record the outcome even when shutdown cancelled the run
+	storeCtx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	if err == nil {
+		if err := p.Store.Complete(storeCtx, job.ID, job.Attempts); err != nil {
+			log.Printf("jobs: completing %s %s: %v", job.Kind, job.ID, err)
+		}
+		return
+	}
+
+	if isPermanent(err) {
+		retryAt = nil
+	}
+	if ctx.Err() != nil {
+		now := time.Now()
+		retryAt = &now
+	}
+	if retryAt == nil {
+		log.Printf("jobs: %s %s failed for good after %d attempts: %v", job.Kind, job.ID, job.Attempts, err)
+	}
+	if err := p.Store.Fail(storeCtx, job.ID, job.Attempts, err.Error(), retryAt); err != nil {
+		log.Printf("jobs: recording failure of %s %s: %v", job.Kind, job.ID, err)
+	}
+}
+
+// call runs the handler with its timeout, turning a panic into an error.
+func (p *Pool) call(ctx context.Context, h *handler, job model.Job, retryAt *time.Time) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, h.Timeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h.run(ctx, job, retryAt)
+}
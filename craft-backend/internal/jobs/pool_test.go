@@ -0,0 +1,232 @@
+package jobs
+
+import (
+	"context"
+	"craft/internal/model"
+	"craft/internal/store"
+	"errors"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+type greeting struct {
+	Name string `json:"name"`
+}
+
+const greet Kind[greeting] = "greet"
+
+// runPool runs a pool over st for d of fake time, then shuts it down.
+func runPool(t *testing.T, st store.JobStore, r *Registry, d time.Duration) {
+	t.Helper()
+	p := NewPool(st, r)
+	p.Workers = 1
+	p.PollInterval = time.Second
+	p.Start()
+	time.Sleep(d)
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPoolRetriesWithBackoffThenDeadLetters(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		st := store.NewMemory().Stores().Jobs
+		var runs []Job[greeting]
+		var at []time.Time
+		r := NewRegistry()
+		Handle(r, greet, func(ctx context.Context, job Job[greeting]) error {
+			runs = append(runs, job)
+			at = append(at, time.Now())
+			return errors.New("mailbox full")
+		}, Options{MaxAttempts: 3, Backoff: func(attempts int) time.Duration { return time.Duration(attempts) * time.Minute }})
+
+		if _, err := Enqueue(t.Context(), st, greet, greeting{Name: "Ada"}); err != nil {
+			t.Fatal(err)
+		}
+		runPool(t, st, r, time.Hour)
+
+		if len(runs) != 3 {
+			t.Fatalf("%d runs, want 3", len(runs))
+		}
+		for i, job := range runs {
+			if job.Attempt != i+1 || job.MaxAttempts != 3 || job.Payload.Name != "Ada" {
+				t.Fatalf("run %d: %+v", i, job)
+			}
+			if last := i == len(runs)-1; (job.RetryAt == nil) != last {
+				t.Fatalf("run %d: retry at %v", i, job.RetryAt)
+			}
+		}
+		// each retry waits out its backoff, give or take a poll
+		for i, want := range []time.Duration{time.Minute, 2 * time.Minute} {
+			if gap := at[i+1].Sub(at[i]); gap < want || gap > want+time.Second {
+				t.Fatalf("retry %d after %v, want %v", i+1, gap, want)
+			}
+		}
+
+		dead, err := st.ListDead(t.Context(), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dead) != 1 || dead[0].Attempts != 3 || dead[0].LastError == nil || *dead[0].LastError != "mailbox full" {
+			t.Fatalf("dead letters %+v", dead)
+		}
+	})
+}
+
+func TestPoolDeadLettersPermanentErrors(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		st := store.NewMemory().Stores().Jobs
+		runs := 0
+		r := NewRegistry()
+		Handle(r, greet, func(ctx context.Context, job Job[greeting]) error {
+			runs++
+			return Permanent(errors.New("no such mailbox"))
+		}, Options{})
+
+		if _, err := Enqueue(t.Context(), st, greet, greeting{Name: "Ada"}); err != nil {
+			t.Fatal(err)
+		}
+		// a payload that does not decode is never handed to the handler
+		if err := st.Enqueue(t.Context(), &model.Job{Kind: string(greet), Payload: []byte(`{"name":42}`)}); err != nil {
+			t.Fatal(err)
+		}
+		runPool(t, st, r, time.Hour)
+
+		if runs != 1 {
+			t.Fatalf("%d runs, want 1", runs)
+		}
+		dead, err := st.ListDead(t.Context(), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dead) != 2 || dead[0].Attempts != 1 || dead[1].Attempts != 1 {
+			t.Fatalf("dead letters %+v, want both after one attempt", dead)
+		}
+	})
+}
+
+func TestPoolRecoversFromPanics(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		st := store.NewMemory().Stores().Jobs
+		var attempts []int
+		r := NewRegistry()
+		Handle(r, greet, func(ctx context.Context, job Job[greeting]) error {
+			attempts = append(attempts, job.Attempt)
+			if job.Attempt == 1 {
+				panic("nil mailbox")
+			}
+			return nil
+		}, Options{})
+
+		if _, err := Enqueue(t.Context(), st, greet, greeting{Name: "Ada"}); err != nil {
+			t.Fatal(err)
+		}
+		runPool(t, st, r, time.Hour)
+
+		if len(attempts) != 2 {
+			t.Fatalf("attempts %v, want a retry after the panic", attempts)
+		}
+		// the job completed and is gone
+		claimed, err := st.Claim(t.Context(), []string{string(greet)}, 10, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dead, err := st.ListDead(t.Context(), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(claimed) != 0 || len(dead) != 0 {
+			t.Fatalf("job left behind: claimed %+v, dead %+v", claimed, dead)
+		}
+	})
+}
+
+func TestEnqueueOnce(t *testing.T) {
+	ctx := t.Context()
+	st := store.NewMemory().Stores().Jobs
+	kinds := []string{string(greet)}
+
+	queued, err := EnqueueOnce(ctx, st, greet, "greet:ada", greeting{Name: "Ada"}, time.Time{})
+	if err != nil || !queued {
+		t.Fatalf("first: queued %v, err %v", queued, err)
+	}
+	queued, err = EnqueueOnce(ctx, st, greet, "greet:ada", greeting{Name: "Ada"}, time.Time{})
+	if err != nil || queued {
+		t.Fatalf("while pending: queued %v, err %v", queued, err)
+	}
+	queued, err = EnqueueOnce(ctx, st, greet, "greet:grace", greeting{Name: "Grace"}, time.Time{})
+	if err != nil || !queued {
+		t.Fatalf("other key: queued %v, err %v", queued, err)
+	}
+
+	// a claimed job no longer holds its key, so work arriving while it runs
+	// is queued for the next run
+	if claimed, err := st.Claim(ctx, kinds, 10, time.Minute); err != nil || len(claimed) != 2 {
+		t.Fatalf("claimed %d, err %v", len(claimed), err)
+	}
+	queued, err = EnqueueOnce(ctx, st, greet, "greet:ada", greeting{Name: "Ada"}, time.Time{})
+	if err != nil || !queued {
+		t.Fatalf("while running: queued %v, err %v", queued, err)
+	}
+}
+
+func TestStaleRunIsIgnored(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := t.Context()
+		st := store.NewMemory().Stores().Jobs
+		kinds := []string{string(greet)}
+		job, err := Enqueue(ctx, st, greet, greeting{Name: "Ada"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the first run outlives its lease and the job is claimed again
+		if _, err := st.Claim(ctx, kinds, 1, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(2 * time.Minute)
+		claimed, err := st.Claim(ctx, kinds, 1, time.Minute)
+		if err != nil || len(claimed) != 1 || claimed[0].Attempts != 2 {
+			t.Fatalf("reclaimed %+v, err %v", claimed, err)
+		}
+
+		// the first run finishing neither ends nor fails the second
+		if err := st.Complete(ctx, job.ID, 1); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.Fail(ctx, job.ID, 1, "mailbox full", nil); err != nil {
+			t.Fatal(err)
+		}
+		if dead, _ := st.ListDead(ctx, 10); len(dead) != 0 {
+			t.Fatalf("stale failure recorded: %+v", dead)
+		}
+		if err := st.Fail(ctx, job.ID, 2, "mailbox full", nil); err != nil {
+			t.Fatal(err)
+		}
+		if dead, _ := st.ListDead(ctx, 10); len(dead) != 1 || dead[0].Attempts != 2 {
+			t.Fatalf("dead letters %+v, want the second run's failure", dead)
+		}
+	})
+}
+
+func TestExponential(t *testing.T) {
+	backoff := Exponential(10*time.Second, time.Hour)
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{4, 80 * time.Second},
+		{10, time.Hour},
+		{50, time.Hour},
+	}
+	for _, tt := range tests {
+		for range 20 {
+			if got := backoff(tt.attempts); got < tt.want || got > tt.want+tt.want/10 {
+				t.Fatalf("after %d attempts: %v, want %v plus up to 10%%", tt.attempts, got, tt.want)
+			}
+		}
+	}
+}
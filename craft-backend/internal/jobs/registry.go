@@ -0,0 +1,172 @@
+// Package jobs runs background work queued in a store.JobStore. Each kind of
+// job has a typed payload and a handler registered with a Registry; a Pool of
+// workers claims due jobs and runs them, retrying failures with backoff until
+// they run out of attempts and become dead letters.
+package jobs
+
+import (
+	"context"
+	"craft/internal/model"
+	"craft/internal/store"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultMaxAttempts = 5
+	DefaultTimeout     = time.Minute
+)
+
+// Kind names a kind of job whose payload is a T.
+type Kind[T any] string
+
+// Job is one attempt at running a job, as seen by its handler.
+type Job[T any] struct {
+	ID          uuid.UUID
+	Attempt     int // 1 on the first attempt
+	MaxAttempts int
+	Payload     T
+	// RetryAt is when the job runs again if this attempt fails, nil on the
+	// last attempt.
+	RetryAt *time.Time
+}
+
+// Options tune how a kind of job is run. Zero fields take the defaults.
+type Options struct {
+	MaxAttempts int
+	// Timeout bounds each attempt.
+	Timeout time.Duration
+	// Backoff returns the delay before retrying a job that has failed
+	// attempts times. Defaults to ExponentialBackoff.
+	Backoff func(attempts int) time.Duration
+}
+
+type handler struct {
+	run func(ctx context.Context, job model.Job, retryAt *time.Time) error
+	Options
+}
+
+// Registry maps job kinds to their handlers.
+type Registry struct {
+	handlers map[string]*handler
+}
+
+func NewRegistry() *Registry {
+	return &Registry{handlers: make(map[string]*handler)}
+}
+
+// Handle registers fn to run jobs of kind. It panics if kind is already
+// registered.
+func Handle[T any](r *Registry, kind Kind[T], fn func(context.Context, Job[T]) error, opts Options) {
+	if _, ok := r.handlers[string(kind)]; ok {
+		panic(fmt.Sprintf("jobs: kind %q registered twice", kind))
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Backoff == nil {
+		opts.Backoff = ExponentialBackoff
+	}
+
+	r.handlers[string(kind)] = &handler{
+		Options: opts,
+		run: func(ctx context.Context, job model.Job, retryAt *time.Time) error {
+			var payload T
+			if err := json.Unmarshal(job.Payload, &payload); err != nil {
+				return Permanent(fmt.Errorf("decoding payload: %w", err))
+			}
+			return fn(ctx, Job[T]{
+				ID:          job.ID,
+				Attempt:     job.Attempts,
+				MaxAttempts: opts.MaxAttempts,
+				Payload:     payload,
+				RetryAt:     retryAt,
+			})
+		},
+	}
+}
+
+// Kinds lists the registered kinds.
+func (r *Registry) Kinds() []string {
+	kinds := make([]string, 0, len(r.handlers))
+	for kind := range r.handlers {
+		kinds = append(kinds, kind)
+	}
+	return kinds
+}
+
+// Enqueue queues a job of kind to run as soon as a worker is free.
+func Enqueue[T any](ctx context.Context, q store.JobStore, kind Kind[T], payload T) (*model.Job, error) {
+	return EnqueueAt(ctx, q, kind, payload, time.Time{})
+}
+
+// EnqueueAt queues a job of kind to run no earlier than runAt.
+func EnqueueAt[T any](ctx context.Context, q store.JobStore, kind Kind[T], payload T, runAt time.Time) (*model.Job, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	job := &model.Job{Kind: string(kind), Payload: raw, RunAt: runAt}
+	if err := q.Enqueue(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// EnqueueOnce queues a job of kind to run no earlier than runAt unless a job
+// with the same key is still pending, and reports whether it queued one.
+// Work that piles up while the job waits is expected to be picked up by it.
+func EnqueueOnce[T any](ctx context.Context, q store.JobStore, kind Kind[T], key string, payload T, runAt time.Time) (bool, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+	job := &model.Job{Kind: string(kind), UniqueKey: &key, Payload: raw, RunAt: runAt}
+	if err := q.Enqueue(ctx, job); err != nil {
+		return false, err
+	}
+	return job.ID != uuid.Nil, nil
+}
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying: the job becomes a dead letter
+// straight away.
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+func isPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+// ExponentialBackoff waits 10s after the first failure, doubling per attempt
+// up to an hour, plus up to 10% jitter.
+func ExponentialBackoff(attempts int) time.Duration {
+	return Exponential(10*time.Second, time.Hour)(attempts)
+}
+
+// Exponential returns a backoff of base doubling per attempt up to limit,
+// plus up to 10% jitter.
+func Exponential(base, limit time.Duration) func(attempts int) time.Duration {
+	return func(attempts int) time.Duration {
+		delay := base
+		for i := 1; i < attempts && delay < limit; i++ {
+			delay *= 2
+		}
+		delay = min(delay, limit)
+		return delay + rand.N(delay/10+1)
+	}
+}
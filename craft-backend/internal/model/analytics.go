@@ -0,0 +1,139 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type FormAnalytics struct {
+	FormID    uuid.UUID           `json:"form_id"`
+	Responses int                 `json:"responses"`
+	Questions []QuestionAnalytics `json:"questions"`
+}
+
+// QuestionAnalytics aggregates the answers to one question. Which of Options,
+// Numeric and Text is set depends on the question type and the answers given.
+type QuestionAnalytics struct {
+	QuestionID uuid.UUID `json:"question_id"`
+	Title      string    `json:"title"`
+	Type       string    `json:"type"`
+	Required   bool      `json:"required"`
+	// Shown counts the responses whose form had the question, so answers
+	// from before it was added do not count as skipped.
+	Shown    int     `json:"shown"`
+	Answered int     `json:"answered"`
+	Skipped  int     `json:"skipped"`
+	SkipRate float64 `json:"skip_rate"` // share of responses shown the question that left it empty, 0..1
+
+	Options []OptionCount `json:"options,omitempty"`
+	Numeric *NumericStats `json:"numeric,omitempty"`
+	Text    *TextStats    `json:"text,omitempty"`
+}
+
+type OptionCount struct {
+	OptionID uuid.UUID `json:"option_id"`
+	Label    string    `json:"label"`
+	Count    int       `json:"count"`
+	// Percentage of respondents who answered the question and picked this
+	// option; multi-select percentages can add up to more than 100.
+	Percentage float64 `json:"percentage"`
+}
+
+type NumericStats struct {
+	Count        int          `json:"count"`
+	Mean         float64      `json:"mean"`
+	Median       float64      `json:"median"`
+	Min          float64      `json:"min"`
+	Max          float64      `json:"max"`
+	Distribution []ValueCount `json:"distribution"`
+}
+
+type ValueCount struct {
+	Value float64 `json:"value"`
+	Count int     `json:"count"`
+}
+
+type TextStats struct {
+	TotalWords   int         `json:"total_words"`
+	AverageWords float64     `json:"average_words"`
+	TopTerms     []TermCount `json:"top_terms"`
+}
+
+type TermCount struct {
+	Term  string `json:"term"`
+	Count int    `json:"count"`
+}
+
+const (
+	SeriesIntervalHour = "hour"
+	SeriesIntervalDay  = "day"
+	SeriesIntervalWeek = "week"
+)
+
+// ResponseSeries counts submissions per time bucket over [From, To) and
+// compares the total with the period of equal length right before it.
+type ResponseSeries struct {
+	Interval string        `json:"interval"`
+	Timezone string        `json:"timezone"`
+	From     time.Time     `json:"from"`
+	To       time.Time     `json:"to"`
+	Points   []SeriesPoint `json:"points"`
+
+	Total         int `json:"total"`
+	PreviousTotal int `json:"previous_total"`
+	Delta         int `json:"delta"`
+	// DeltaPercent is nil when the previous period had no responses.
+	DeltaPercent *float64 `json:"delta_percent"`
+}
+
+// SeriesPoint is one bucket; Start is its beginning in the series' timezone.
+type SeriesPoint struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+}
+
+const (
+	FormEventView     = "view"
+	FormEventStart    = "start"
+	FormEventProgress = "progress"
+)
+
+// FormEvent records a respondent's progress through a public form.
+type FormEvent struct {
+	ID         uuid.UUID  `json:"id"`
+	FormID     uuid.UUID  `json:"form_id"`
+	SessionID  string     `json:"-"` // the respondent's device cookie
+	Kind       string     `json:"kind"`
+	QuestionID *uuid.UUID `json:"question_id,omitempty"` // the question reached, for progress events
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// FormFunnel counts distinct respondent sessions at each step of a form.
+// Rates are 0..1 and zero when their base is zero.
+type FormFunnel struct {
+	FormID      uuid.UUID `json:"form_id"`
+	Views       int       `json:"views"`
+	Starts      int       `json:"starts"`
+	Completions int       `json:"completions"` // sessions whose device went on to submit
+
+	StartRate      float64 `json:"start_rate"`      // starts / views
+	CompletionRate float64 `json:"completion_rate"` // completions / starts
+	ConversionRate float64 `json:"conversion_rate"` // completions / views
+
+	Questions []QuestionDropOff `json:"questions"`
+	// DropOff is the question most respondents abandoned the form at, nil
+	// when nobody did.
+	DropOff *QuestionDropOff `json:"drop_off"`
+}
+
+type QuestionDropOff struct {
+	QuestionID uuid.UUID `json:"question_id"`
+	Title      string    `json:"title"`
+	Position   int       `json:"position"`
+	Reached    int       `json:"reached"`
+	// DroppedOff counts sessions that got no further than this question and
+	// did not submit.
+	DroppedOff  int     `json:"dropped_off"`
+	DropOffRate float64 `json:"drop_off_rate"` // dropped_off / reached
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Autoresponder emails respondents a receipt of their submission, to the
+// address they gave in an email question. Subject and Body are templates
+// in which {{Question title}} or {{question ID}} stands for that question's
+// answer and {{form.title}} for the form's title.
+type Autoresponder struct {
+	FormID     uuid.UUID  `json:"form_id"`
+	Enabled    bool       `json:"enabled"`
+	QuestionID *uuid.UUID `json:"question_id"` // cleared when the question is deleted
+	Subject    string     `json:"subject"`
+	Body       string     `json:"body"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// DigestPreference is how often a user is emailed a summary of the new
+// submissions to their forms.
+type DigestPreference struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Frequency string    `json:"frequency"` // off, daily, weekly
+	Timezone  string    `json:"timezone"`  // IANA name; digests go out at 08:00 local time
+	// SentThrough ends the period the last digest covered. It is reset to
+	// the time digests are turned on, so the first one only covers what
+	// came after.
+	SentThrough *time.Time `json:"sent_through"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
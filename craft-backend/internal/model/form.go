@@ -1,11 +1,18 @@
 package model
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const (
+	FormStatusDraft     = "draft"
+	FormStatusPublished = "published"
+	FormStatusClosed    = "closed"
+)
+
 type Form struct {
 	ID                       uuid.UUID  `json:"id"`
 	OwnerID                  uuid.UUID  `json:"owner_id"`
@@ -17,27 +24,176 @@ type Form struct {
 	CloseDate                *time.Time `json:"close_date"`
 	ThankYouMessage          *string    `json:"thank_you_message"`
 	RedirectURL              *string    `json:"redirect_url"`
+	NotifyOwner              bool       `json:"notify_owner"` // email the owner about new submissions
 	CreatedAt                time.Time  `json:"created_at"`
 	UpdatedAt                time.Time  `json:"updated_at"`
+	Version                  int        `json:"version"` // incremented on every write, exposed as the ETag
 	Responses                int        `json:"responses"`
 	Questions                []Question `json:"questions,omitempty"`
+	Sections                 []Section  `json:"sections,omitempty"` // pages; a form without any is a single page
 }
 
+// PublicForm is what respondents see of a published form: its definition
+// and the settings that shape answering it, but nothing about its owner,
+// notifications or editing.
+type PublicForm struct {
+	ID                       uuid.UUID  `json:"id"`
+	Title                    string     `json:"title"`
+	Description              *string    `json:"description"`
+	AllowMultipleSubmissions bool       `json:"allow_multiple_submissions"`
+	CloseDate                *time.Time `json:"close_date"`
+	ThankYouMessage          *string    `json:"thank_you_message"`
+	RedirectURL              *string    `json:"redirect_url"`
+	Questions                []Question `json:"questions"`
+	Sections                 []Section  `json:"sections,omitempty"`
+}
+
+// Public returns the respondents' view of f.
+func (f *Form) Public() *PublicForm {
+	questions := f.Questions
+	if questions == nil {
+		questions = []Question{}
+	}
+	return &PublicForm{
+		ID:                       f.ID,
+		Title:                    f.Title,
+		Description:              f.Description,
+		AllowMultipleSubmissions: f.AllowMultipleSubmissions,
+		CloseDate:                f.CloseDate,
+		ThankYouMessage:          f.ThankYouMessage,
+		RedirectURL:              f.RedirectURL,
+		Questions:                questions,
+		Sections:                 f.Sections,
+	}
+}
+
+const (
+	QuestionTypeShortText    = "short-text"
+	QuestionTypeLongText     = "long-text"
+	QuestionTypeSingleSelect = "single-select"
+	QuestionTypeMultiSelect  = "multi-select"
+	QuestionTypeDropdown     = "dropdown"
+	QuestionTypeEmail        = "email"
+	QuestionTypeNumber       = "number"
+	QuestionTypeRating       = "rating"
+	QuestionTypeFileUpload   = "file_upload"
+)
+
 type Question struct {
-	ID          uuid.UUID `json:"id"`
-	FormID      uuid.UUID `json:"form_id"`
-	Type        string    `json:"type"`
-	Title       string    `json:"title"`
-	Description *string   `json:"description"`
-	Emoji       *string   `json:"emoji"`
-	Position    int       `json:"position"`
-	Required    bool      `json:"required"`
-	Options     []Option  `json:"options,omitempty"`
+	ID          uuid.UUID  `json:"id"`
+	FormID      uuid.UUID  `json:"form_id"`
+	Type        string     `json:"type"`
+	Title       string     `json:"title"`
+	Description *string    `json:"description"`
+	Emoji       *string    `json:"emoji"`
+	Position    int        `json:"position"`
+	Required    bool       `json:"required"`
+	ArchivedAt  *time.Time `json:"archived_at,omitempty"` // set once the question is removed from the form; its answers are kept
+	Options     []Option   `json:"options,omitempty"`
+	SectionID   *uuid.UUID `json:"section_id,omitempty"` // page the question is on, when the form has pages
+	// Number bounds the answers of number questions.
+	Number *NumberSettings `json:"number,omitempty"`
+	// Rating sets the scale of rating questions.
+	Rating *RatingSettings `json:"rating,omitempty"`
+	// FileUpload limits the files of file_upload questions.
+	FileUpload *FileUploadSettings `json:"file_upload,omitempty"`
+	// Visibility hides the question unless earlier answers meet its rule.
+	Visibility *VisibilityRule `json:"visibility,omitempty"`
+}
+
+// NumberSettings bounds the answers a number question accepts. Nil bounds
+// do not apply.
+type NumberSettings struct {
+	Min     *float64 `json:"min,omitempty"`
+	Max     *float64 `json:"max,omitempty"`
+	Integer bool     `json:"integer,omitempty"` // whole numbers only
+}
+
+const DefaultRatingScale = 5
+
+// RatingSettings sets the points of a rating question, which is answered
+// with a whole number from 1 to Scale. Zero takes the default.
+type RatingSettings struct {
+	Scale int `json:"scale,omitempty"`
+}
+
+// RatingScale returns the highest rating q accepts.
+func (q Question) RatingScale() int {
+	if q.Rating == nil || q.Rating.Scale <= 0 {
+		return DefaultRatingScale
+	}
+	return q.Rating.Scale
+}
+
+const (
+	DefaultMaxFiles    = 1
+	DefaultMaxFileSize = 10 << 20
+)
+
+// FileUploadSettings limits the files a file_upload question accepts. Zero
+// values take the defaults.
+type FileUploadSettings struct {
+	MaxFiles int   `json:"max_files,omitempty"`
+	MaxSize  int64 `json:"max_size,omitempty"` // bytes, per file
+	// AllowedTypes lists MIME types such as "application/pdf" or "image/*".
+	// Any type is allowed when empty.
+	AllowedTypes []string `json:"allowed_types,omitempty"`
+}
+
+// FileLimits returns the file settings of q with the defaults filled in.
+func (q Question) FileLimits() FileUploadSettings {
+	var s FileUploadSettings
+	if q.FileUpload != nil {
+		s = *q.FileUpload
+	}
+	if s.MaxFiles <= 0 {
+		s.MaxFiles = DefaultMaxFiles
+	}
+	if s.MaxSize <= 0 {
+		s.MaxSize = DefaultMaxFileSize
+	}
+	return s
+}
+
+// Allows reports whether contentType, a bare MIME type, is in AllowedTypes.
+func (s FileUploadSettings) Allows(contentType string) bool {
+	if len(s.AllowedTypes) == 0 {
+		return true
+	}
+	major, _, _ := strings.Cut(contentType, "/")
+	for _, allowed := range s.AllowedTypes {
+		if strings.EqualFold(allowed, contentType) || strings.EqualFold(allowed, major+"/*") {
+			return true
+		}
+	}
+	return false
 }
 
 type Option struct {
-	ID         uuid.UUID `json:"id"`
-	QuestionID uuid.UUID `json:"question_id"`
-	Label      string    `json:"label"`
-	Position   int       `json:"position"`
+	ID         uuid.UUID  `json:"id"`
+	QuestionID uuid.UUID  `json:"question_id"`
+	Label      string     `json:"label"`
+	Position   int        `json:"position"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+}
+
+const (
+	RevisionReasonSave    = "save"
+	RevisionReasonPublish = "publish"
+	RevisionReasonRestore = "restore"
+)
+
+// FormRevision is an immutable snapshot of a form's definition, taken on
+// every save, publish and restore.
+type FormRevision struct {
+	ID          uuid.UUID  `json:"id"`
+	FormID      uuid.UUID  `json:"form_id"`
+	Revision    int        `json:"revision"`
+	Reason      string     `json:"reason"`
+	Title       string     `json:"title"`
+	Description *string    `json:"description"`
+	Questions   []Question `json:"questions,omitempty"`
+	Sections    []Section  `json:"sections,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Responses   int        `json:"responses"`
 }
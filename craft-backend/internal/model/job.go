@@ -0,0 +1,33 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	JobStatusPending = "pending"
+	JobStatusRunning = "running"
+	// JobStatusDead marks a job that failed its last attempt and is kept for
+	// inspection until retried by hand.
+	JobStatusDead = "dead"
+)
+
+type Job struct {
+	ID      uuid.UUID       `json:"id"`
+	Kind    string          `json:"kind"`
+	Payload json.RawMessage `json:"payload"`
+	// UniqueKey, while the job is pending, keeps other jobs with the same key
+	// from being queued.
+	UniqueKey *string   `json:"unique_key,omitempty"`
+	Status    string    `json:"status"`
+	Attempts  int       `json:"attempts"` // attempts started, including the current one
+	RunAt     time.Time `json:"run_at"`
+	// LockedUntil is when a running job's lease runs out.
+	LockedUntil *time.Time `json:"locked_until"`
+	LastError   *string    `json:"last_error"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
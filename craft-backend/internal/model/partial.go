@@ -0,0 +1,28 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PartialResponse holds the answers of a respondent who saved a form to
+// finish later, possibly on another device. It is found by the hash of its
+// resume token and expires unless saved again.
+type PartialResponse struct {
+	ID              uuid.UUID       `json:"id"`
+	FormID          uuid.UUID       `json:"form_id"`
+	TokenHash       string          `json:"-"`
+	Answers         []PartialAnswer `json:"answers"`
+	RespondentEmail *string         `json:"respondent_email"`
+	SectionID       *uuid.UUID      `json:"section_id"` // page to resume at
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	ExpiresAt       time.Time       `json:"expires_at"`
+}
+
+type PartialAnswer struct {
+	QuestionID uuid.UUID       `json:"question_id"`
+	Value      json.RawMessage `json:"value"`
+}
@@ -0,0 +1,10 @@
+package payload
+
+import "github.com/google/uuid"
+
+type PutAutoresponderRequest struct {
+	Enabled    bool      `json:"enabled"`
+	QuestionID uuid.UUID `json:"question_id"`
+	Subject    string    `json:"subject"`
+	Body       string    `json:"body"`
+}
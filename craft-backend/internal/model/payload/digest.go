@@ -0,0 +1,6 @@
+package payload
+
+type UpdateDigestPreferenceRequest struct {
+	Frequency string `json:"frequency" validate:"required,oneof=off daily weekly"`
+	Timezone  string `json:"timezone" validate:"omitempty,max=64"`
+}
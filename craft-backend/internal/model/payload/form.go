@@ -0,0 +1,14 @@
+package payload
+
+import "time"
+
+// UpdateFormSettingsRequest is a partial update: omitted fields are left
+// unchanged and null clears the optional ones.
+type UpdateFormSettingsRequest struct {
+	IsPublic                 *bool               `json:"is_public"`
+	AllowMultipleSubmissions *bool               `json:"allow_multiple_submissions"`
+	CloseDate                Nullable[time.Time] `json:"close_date"`
+	ThankYouMessage          Nullable[string]    `json:"thank_you_message"`
+	RedirectURL              Nullable[string]    `json:"redirect_url"`
+	NotifyOwner              *bool               `json:"notify_owner"`
+}
@@ -0,0 +1,29 @@
+package payload
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Nullable distinguishes a JSON field that was omitted (Set is false) from
+// one explicitly sent as null (Set is true, Value is nil), which PATCH
+// requests need to clear optional columns.
+type Nullable[T any] struct {
+	Set   bool
+	Value *T
+}
+
+func (n *Nullable[T]) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		n.Value = nil
+		return nil
+	}
+
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	n.Value = &v
+	return nil
+}
@@ -0,0 +1,13 @@
+package payload
+
+import "github.com/google/uuid"
+
+// SavePartialRequest stores a respondent's progress. It replaces the answers
+// saved before; the email and page are kept when left out.
+type SavePartialRequest struct {
+	Answers         []AnswerInput `json:"answers"`
+	RespondentEmail *string       `json:"respondent_email" validate:"omitempty,email,max=254"`
+	SectionID       *uuid.UUID    `json:"section_id"`
+	// SendLink emails the resume link to RespondentEmail.
+	SendLink bool `json:"send_link"`
+}
@@ -0,0 +1,26 @@
+package payload
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+type AnswerInput struct {
+	QuestionID uuid.UUID       `json:"question_id"`
+	Value      json.RawMessage `json:"value"`
+}
+
+type SubmitFormRequest struct {
+	RespondentEmail *string       `json:"respondent_email" validate:"omitempty,email"`
+	Answers         []AnswerInput `json:"answers"`
+	// ResumeToken finishes a saved partial response: its answers are
+	// submitted along with Answers, which take precedence, and it is deleted.
+	ResumeToken string `json:"resume_token"`
+}
+
+// FormEventRequest reports a respondent reaching a step of a public form.
+type FormEventRequest struct {
+	Kind       string     `json:"kind" validate:"required,oneof=view start progress"`
+	QuestionID *uuid.UUID `json:"question_id" validate:"required_if=Kind progress"`
+}
@@ -0,0 +1,12 @@
+package payload
+
+import "github.com/google/uuid"
+
+// PresignUploadRequest describes a file the client will upload directly to
+// storage.
+type PresignUploadRequest struct {
+	QuestionID  uuid.UUID `json:"question_id" validate:"required"`
+	Filename    string    `json:"filename" validate:"required,max=255"`
+	ContentType string    `json:"content_type" validate:"required,max=255"`
+	Size        int64     `json:"size" validate:"required,gt=0"`
+}
@@ -0,0 +1,15 @@
+package payload
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,http_url,max=2048"`
+	Events []string `json:"events" validate:"omitempty,dive,oneof=submission.created"`
+}
+
+// UpdateWebhookRequest is a partial update; omitted fields are unchanged.
+type UpdateWebhookRequest struct {
+	URL    *string  `json:"url" validate:"omitempty,http_url,max=2048"`
+	Events []string `json:"events" validate:"omitempty,min=1,dive,oneof=submission.created"`
+	Active *bool    `json:"active"`
+	// RotateSecret issues a new signing secret, returned in the response.
+	RotateSecret bool `json:"rotate_secret"`
+}
@@ -0,0 +1,50 @@
+package model
+
+import "github.com/google/uuid"
+
+// Section is a page of a form. Its questions are those whose SectionID names
+// it, and they come after the questions of earlier pages. IDs are chosen by
+// the client, so questions and jumps can refer to pages not saved yet.
+type Section struct {
+	ID          uuid.UUID  `json:"id"`
+	FormID      uuid.UUID  `json:"form_id"`
+	Title       string     `json:"title"`
+	Description *string    `json:"description"`
+	Position    int        `json:"position"`
+	Jumps       []PageJump `json:"jumps,omitempty"`
+}
+
+// PageJump sends respondents whose answers meet When to a later page once
+// they finish this one, skipping the pages in between. The first jump that
+// applies wins; without one, respondents go on to the next page.
+type PageJump struct {
+	When Condition `json:"when"`
+	// To is the page to go to; nil ends the form.
+	To *uuid.UUID `json:"to"`
+}
+
+// Remap returns a copy of s that has the ID it is mapped to and refers to
+// questions, options and pages by their mapped IDs, as when a form is
+// duplicated. IDs missing from ids are kept.
+func (s Section) Remap(ids map[uuid.UUID]uuid.UUID) Section {
+	if id, ok := ids[s.ID]; ok {
+		s.ID = id
+	}
+	if s.Jumps == nil {
+		return s
+	}
+	jumps := make([]PageJump, len(s.Jumps))
+	for i, j := range s.Jumps {
+		j.When = j.When.Remap(ids)
+		if j.To != nil {
+			to := *j.To
+			if id, ok := ids[to]; ok {
+				to = id
+			}
+			j.To = &to
+		}
+		jumps[i] = j
+	}
+	s.Jumps = jumps
+	return s
+}
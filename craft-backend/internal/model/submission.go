@@ -10,10 +10,13 @@ import (
 type Submission struct {
 	ID               uuid.UUID  `json:"id"`
 	FormID           uuid.UUID  `json:"form_id"`
+	RevisionID       *uuid.UUID `json:"revision_id"` // form revision the answers were given against
 	RespondentEmail  *string    `json:"respondent_email"`
 	RespondentUserID *uuid.UUID `json:"respondent_user_id"`
 	IPAddress        *string    `json:"ip_address"`
 	UserAgent        *string    `json:"user_agent"`
+	DeviceID         *string    `json:"-"` // value of the respondent's device cookie
+	Fingerprint      *string    `json:"-"` // hash of IP and user agent, used when no cookie is sent
 	CreatedAt        time.Time  `json:"created_at"`
 }
 
@@ -23,6 +26,14 @@ type Answer struct {
 	QuestionID   uuid.UUID       `json:"question_id"`
 	Value        json.RawMessage `json:"value"` // JSONB to support both single and multi-select answers
 	CreatedAt    time.Time       `json:"created_at"`
+
+	// Filled in when listing submissions so answers to questions that were
+	// since edited or archived stay readable.
+	QuestionTitle    string `json:"question_title,omitempty"`
+	QuestionType     string `json:"question_type,omitempty"`
+	QuestionArchived bool   `json:"question_archived,omitempty"`
+	// Files are the uploads a file_upload answer names, with download links.
+	Files []Upload `json:"files,omitempty"`
 }
 
 type SubmissionWithAnswers struct {
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Upload is a file a respondent attached to a file_upload question. It is
+// stored before the submission that names it and belongs to no submission
+// until then.
+type Upload struct {
+	ID           uuid.UUID  `json:"id"`
+	FormID       uuid.UUID  `json:"form_id"`
+	QuestionID   uuid.UUID  `json:"question_id"`
+	SubmissionID *uuid.UUID `json:"submission_id,omitempty"`
+	Filename     string     `json:"filename"`
+	ContentType  string     `json:"content_type"`
+	Size         int64      `json:"size"`
+	BlobKey      string     `json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+	// URL is a signed download link, set when listing submissions.
+	URL string `json:"url,omitempty"`
+}
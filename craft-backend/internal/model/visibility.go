@@ -0,0 +1,80 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	VisibilityMatchAll = "all"
+	VisibilityMatchAny = "any"
+)
+
+const (
+	ConditionEquals         = "equals"
+	ConditionNotEquals      = "not_equals"
+	ConditionContains       = "contains"
+	ConditionNotContains    = "not_contains"
+	ConditionGreater        = "gt"
+	ConditionGreaterOrEqual = "gte"
+	ConditionLess           = "lt"
+	ConditionLessOrEqual    = "lte"
+	ConditionAnswered       = "answered"
+	ConditionNotAnswered    = "not_answered"
+)
+
+// VisibilityRule shows a question only while the answers to earlier
+// questions meet all of its conditions, or any of them when Match is "any".
+// Hidden questions are not required and their answers are dropped.
+type VisibilityRule struct {
+	Match      string      `json:"match,omitempty"` // all (default) or any
+	Conditions []Condition `json:"conditions"`
+}
+
+// Condition tests the answer to an earlier question. Value is a string for
+// equals and contains (an option label or ID for choice questions), a
+// number for gt, gte, lt and lte, and absent for answered and not_answered.
+type Condition struct {
+	QuestionID uuid.UUID       `json:"question_id"`
+	Operator   string          `json:"operator"`
+	Value      json.RawMessage `json:"value,omitempty"`
+}
+
+// MatchAny reports whether one met condition is enough to show the question.
+func (r *VisibilityRule) MatchAny() bool {
+	return r.Match == VisibilityMatchAny
+}
+
+// Remap returns a copy of r that refers to questions and options by the IDs
+// they are mapped to, as when a form is duplicated. IDs missing from ids are
+// kept.
+func (r *VisibilityRule) Remap(ids map[uuid.UUID]uuid.UUID) *VisibilityRule {
+	if r == nil {
+		return nil
+	}
+	out := &VisibilityRule{Match: r.Match, Conditions: make([]Condition, len(r.Conditions))}
+	for i, c := range r.Conditions {
+		out.Conditions[i] = c.Remap(ids)
+	}
+	return out
+}
+
+// Remap returns a copy of c that refers to the question, and the option in
+// its value, by the IDs they are mapped to.
+func (c Condition) Remap(ids map[uuid.UUID]uuid.UUID) Condition {
+	if id, ok := ids[c.QuestionID]; ok {
+		c.QuestionID = id
+	}
+	var value string
+	if json.Unmarshal(c.Value, &value) == nil {
+		if optionID, err := uuid.Parse(strings.TrimSpace(value)); err == nil {
+			if id, ok := ids[optionID]; ok {
+				c.Value, _ = json.Marshal(id.String())
+			}
+		}
+	}
+	c.Value = append(json.RawMessage(nil), c.Value...)
+	return c
+}
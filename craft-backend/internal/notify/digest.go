@@ -0,0 +1,299 @@
+package notify
+
+import (
+	"context"
+	"craft/internal/jobs"
+	"craft/internal/model"
+	"craft/internal/store"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DigestHour is the local hour digests are sent at: daily, or on
+	// Mondays for weekly digests.
+	DigestHour = 8
+	// DigestTopAnswers caps the options listed per choice question.
+	DigestTopAnswers = 3
+)
+
+// DigestSweepKind queues a DigestKind job for every user due a digest and
+// schedules the next sweep, at the top of the following hour.
+const DigestSweepKind jobs.Kind[DigestSweepPayload] = "notify.digest_sweep"
+
+type DigestSweepPayload struct{}
+
+// DigestKind emails one user the digest of the period ending at Through.
+const DigestKind jobs.Kind[DigestPayload] = "notify.digest"
+
+type DigestPayload struct {
+	UserID  uuid.UUID `json:"user_id"`
+	Through time.Time `json:"through"`
+}
+
+const digestSweepKey = "digest-sweep"
+
+func digestKey(userID uuid.UUID) string {
+	return "digest:" + userID.String()
+}
+
+// ScheduleDigests queues a sweep now, unless one is already waiting. Call
+// it at startup; every sweep schedules the next one.
+func ScheduleDigests(ctx context.Context, q store.JobStore) error {
+	_, err := jobs.EnqueueOnce(ctx, q, DigestSweepKind, digestSweepKey, DigestSweepPayload{}, time.Now())
+	return err
+}
+
+// DigestPeriodEnd returns the end of the latest digest period at or before
+// now: DigestHour in loc that day, or that week's Monday for weekly digests.
+func DigestPeriodEnd(frequency string, loc *time.Location, now time.Time) time.Time {
+	now = now.In(loc)
+	end := time.Date(now.Year(), now.Month(), now.Day(), DigestHour, 0, 0, 0, loc)
+	days := 1
+	if frequency == model.DigestWeekly {
+		daysSinceMonday := (int(end.Weekday()) + 6) % 7
+		end = end.AddDate(0, 0, -daysSinceMonday)
+		days = 7
+	}
+	if end.After(now) {
+		end = end.AddDate(0, 0, -days)
+	}
+	return end
+}
+
+func digestLocation(p *model.DigestPreference) *time.Location {
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// DigestMailer runs DigestSweepKind and DigestKind jobs.
+type DigestMailer struct {
+	Digests     store.DigestStore
+	Users       store.UserStore
+	Forms       store.FormStore
+	Submissions store.SubmissionStore
+	Jobs        store.JobStore
+	Notifier    Notifier
+	// AppURL is the frontend's base URL, for links to the responses.
+	AppURL string
+}
+
+func (m *DigestMailer) Register(r *jobs.Registry) {
+	jobs.Handle(r, DigestSweepKind, m.sweep, jobs.Options{})
+	jobs.Handle(r, DigestKind, m.sendDigest, jobs.Options{})
+}
+
+func (m *DigestMailer) sweep(ctx context.Context, job jobs.Job[DigestSweepPayload]) error {
+	prefs, err := m.Digests.ListEnabled(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, p := range prefs {
+		through := DigestPeriodEnd(p.Frequency, digestLocation(&p), now)
+		if p.SentThrough != nil && !p.SentThrough.Before(through) {
+			continue
+		}
+		_, err := jobs.EnqueueOnce(ctx, m.Jobs, DigestKind, digestKey(p.UserID),
+			DigestPayload{UserID: p.UserID, Through: through}, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	next := now.Truncate(time.Hour).Add(time.Hour)
+	_, err = jobs.EnqueueOnce(ctx, m.Jobs, DigestSweepKind, digestSweepKey, DigestSweepPayload{}, next)
+	return err
+}
+
+// formDigest summarizes one form's submissions over a digest period.
+type formDigest struct {
+	Form      *model.Form
+	Responses int
+	// choices counts the options picked per choice question, by option ID
+	// or label as submitted.
+	choices map[uuid.UUID]map[string]int
+}
+
+func (m *DigestMailer) sendDigest(ctx context.Context, job jobs.Job[DigestPayload]) error {
+	p, err := m.Digests.Preference(ctx, job.Payload.UserID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	through := job.Payload.Through
+	if p.Frequency == model.DigestOff || (p.SentThrough != nil && !p.SentThrough.Before(through)) {
+		return nil
+	}
+	from := through.AddDate(0, 0, -1)
+	if p.Frequency == model.DigestWeekly {
+		from = through.AddDate(0, 0, -7)
+	}
+	if p.SentThrough != nil {
+		from = *p.SentThrough
+	}
+
+	user, err := m.Users.Get(ctx, p.UserID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	forms, err := m.Forms.ListByOwner(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	var digests []formDigest
+	total := 0
+	for _, f := range forms {
+		if f.Responses == 0 {
+			continue
+		}
+		d, err := m.summarize(ctx, f.ID, from, through)
+		if err != nil {
+			return err
+		}
+		if d != nil {
+			digests = append(digests, *d)
+			total += d.Responses
+		}
+	}
+
+	if total > 0 {
+		slices.SortStableFunc(digests, func(a, b formDigest) int { return b.Responses - a.Responses })
+		msg := Message{
+			To:      user.Email,
+			Subject: digestSubject(p.Frequency, total),
+			Text:    m.digestText(user, p, digests, from, total),
+		}
+		if err := m.Notifier.Send(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return m.Digests.MarkSent(ctx, user.ID, through)
+}
+
+// summarize counts the submissions made to a form in [from, to), or
+// returns nil if there were none.
+func (m *DigestMailer) summarize(ctx context.Context, formID uuid.UUID, from, to time.Time) (*formDigest, error) {
+	f, err := m.Forms.Get(ctx, formID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	d := &formDigest{Form: f, choices: make(map[uuid.UUID]map[string]int)}
+	err = m.Submissions.Each(ctx, f.ID, store.SubmissionQuery{CreatedFrom: &from, CreatedTo: &to}, func(sub *model.SubmissionWithAnswers) error {
+		d.Responses++
+		for _, a := range sub.Answers {
+			for _, choice := range choiceValues(a.Value) {
+				if d.choices[a.QuestionID] == nil {
+					d.choices[a.QuestionID] = make(map[string]int)
+				}
+				d.choices[a.QuestionID][choice]++
+			}
+		}
+		return nil
+	})
+	if err != nil || d.Responses == 0 {
+		return nil, err
+	}
+	return d, nil
+}
+
+// choiceValues decodes the options of a choice answer, which is a single
+// option or a list of them.
+func choiceValues(raw json.RawMessage) []string {
+	var one string
+	if json.Unmarshal(raw, &one) == nil {
+		return []string{one}
+	}
+	var many []string
+	if json.Unmarshal(raw, &many) == nil {
+		return many
+	}
+	return nil
+}
+
+func digestSubject(frequency string, total int) string {
+	responses := "new responses"
+	if total == 1 {
+		responses = "new response"
+	}
+	return fmt.Sprintf("Your %s digest: %d %s", frequency, total, responses)
+}
+
+func (m *DigestMailer) digestText(user *model.User, p *model.DigestPreference, digests []formDigest, from time.Time, total int) string {
+	var b strings.Builder
+	if user.FirstName != "" {
+		fmt.Fprintf(&b, "Hi %s,\n\n", user.FirstName)
+	}
+	fmt.Fprintf(&b, "Your forms received %s since %s.\n", plural(total, "new response"),
+		from.In(digestLocation(p)).Format("Mon 2 Jan 15:04 MST"))
+
+	for _, d := range digests {
+		fmt.Fprintf(&b, "\n%q: %s\n", d.Form.Title, plural(d.Responses, "new response"))
+		for _, q := range d.Form.Questions {
+			if line := topAnswers(q, d.choices[q.ID]); line != "" {
+				fmt.Fprintf(&b, "  %s: %s\n", q.Title, line)
+			}
+		}
+		fmt.Fprintf(&b, "  See all responses: %s\n", ResponsesURL(m.AppURL, d.Form.ID))
+	}
+
+	fmt.Fprintf(&b, "\nYou get this digest %s. You can change how often in your account settings.\n", p.Frequency)
+	return b.String()
+}
+
+// topAnswers lists the most picked options of a choice question, most
+// picked first, as "Label (count)".
+func topAnswers(q model.Question, counts map[string]int) string {
+	if len(q.Options) == 0 || len(counts) == 0 {
+		return ""
+	}
+
+	type optionCount struct {
+		label string
+		count int
+	}
+	var top []optionCount
+	for _, opt := range q.Options {
+		n := counts[opt.ID.String()]
+		if opt.Label != opt.ID.String() {
+			n += counts[opt.Label]
+		}
+		if n > 0 {
+			top = append(top, optionCount{opt.Label, n})
+		}
+	}
+	slices.SortStableFunc(top, func(a, b optionCount) int { return b.count - a.count })
+
+	parts := make([]string, 0, DigestTopAnswers)
+	for _, oc := range top[:min(len(top), DigestTopAnswers)] {
+		parts = append(parts, fmt.Sprintf("%s (%d)", oc.label, oc.count))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
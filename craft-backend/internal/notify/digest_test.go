@@ -0,0 +1,143 @@
+package notify
+
+import (
+	"context"
+	"craft/internal/jobs"
+	"craft/internal/model"
+	"craft/internal/store"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestDigestPeriodEnd(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	auckland, err := time.LoadLocation("Pacific/Auckland")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(loc *time.Location, s string) time.Time {
+		t.Helper()
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	// 2026-03-09 is a Monday; New York moves its clocks forward on the 8th
+	tests := []struct {
+		name      string
+		frequency string
+		loc       *time.Location
+		now       time.Time
+		want      time.Time
+	}{
+		{"daily after the hour", model.DigestDaily, time.UTC, at(time.UTC, "2026-03-10 09:30"), at(time.UTC, "2026-03-10 08:00")},
+		{"daily at the hour", model.DigestDaily, time.UTC, at(time.UTC, "2026-03-10 08:00"), at(time.UTC, "2026-03-10 08:00")},
+		{"daily before the hour", model.DigestDaily, time.UTC, at(time.UTC, "2026-03-10 07:59"), at(time.UTC, "2026-03-09 08:00")},
+		{"weekly on Monday", model.DigestWeekly, time.UTC, at(time.UTC, "2026-03-09 08:00"), at(time.UTC, "2026-03-09 08:00")},
+		{"weekly before Monday's hour", model.DigestWeekly, time.UTC, at(time.UTC, "2026-03-09 07:59"), at(time.UTC, "2026-03-02 08:00")},
+		{"weekly on Sunday", model.DigestWeekly, time.UTC, at(time.UTC, "2026-03-15 23:59"), at(time.UTC, "2026-03-09 08:00")},
+		{"daily across the DST change", model.DigestDaily, newYork, at(newYork, "2026-03-08 07:00"), at(newYork, "2026-03-07 08:00")},
+		{"daily after the DST change", model.DigestDaily, newYork, at(newYork, "2026-03-08 09:00"), at(newYork, "2026-03-08 08:00")},
+		{"weekly across the DST change", model.DigestWeekly, newYork, at(newYork, "2026-03-09 07:00"), at(newYork, "2026-03-02 08:00")},
+		{"already Monday in loc", model.DigestWeekly, auckland, at(time.UTC, "2026-03-08 20:00"), at(auckland, "2026-03-09 08:00")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DigestPeriodEnd(tt.frequency, tt.loc, tt.now)
+			if !got.Equal(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			if local := got.In(tt.loc); local.Hour() != DigestHour || local.Minute() != 0 {
+				t.Fatalf("%v is not %d:00 local time", local, DigestHour)
+			}
+		})
+	}
+}
+
+// outbox is a Notifier that keeps what it is given.
+type outbox struct{ sent []Message }
+
+func (o *outbox) Send(ctx context.Context, m Message) error {
+	o.sent = append(o.sent, m)
+	return nil
+}
+
+func TestDigestIsSentOncePerPeriod(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := t.Context()
+		m := store.NewMemory()
+		st := m.Stores()
+		owner := uuid.New()
+		m.PutUser(model.User{ID: owner, FirstName: "Ada", Email: "ada@example.com"})
+		if err := st.Digests.SetPreference(ctx, &model.DigestPreference{UserID: owner, Frequency: model.DigestDaily, Timezone: "UTC"}); err != nil {
+			t.Fatal(err)
+		}
+		f, err := st.Forms.Create(ctx, owner, "Team lunch", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		submit := func() {
+			t.Helper()
+			if err := st.Submissions.Create(ctx, &model.Submission{FormID: f.ID}, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		out := &outbox{}
+		mailer := &DigestMailer{
+			Digests:     st.Digests,
+			Users:       st.Users,
+			Forms:       st.Forms,
+			Submissions: st.Submissions,
+			Jobs:        st.Jobs,
+			Notifier:    out,
+			AppURL:      "https://craft.test",
+		}
+		digest := func() {
+			t.Helper()
+			through := DigestPeriodEnd(model.DigestDaily, time.UTC, time.Now())
+			job := jobs.Job[DigestPayload]{Payload: DigestPayload{UserID: owner, Through: through}}
+			if err := mailer.sendDigest(ctx, job); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		time.Sleep(time.Hour)
+		submit()
+		time.Sleep(9 * time.Hour)
+
+		// a job run twice, as after a lost lease, sends one email
+		digest()
+		digest()
+		if len(out.sent) != 1 || !strings.Contains(out.sent[0].Subject, "1 new response") {
+			t.Fatalf("sent %+v, want one digest of one response", out.sent)
+		}
+
+		// nor does the next sweep queue the period again
+		if err := mailer.sweep(ctx, jobs.Job[DigestSweepPayload]{}); err != nil {
+			t.Fatal(err)
+		}
+		if claimed, _ := st.Jobs.Claim(ctx, []string{string(DigestKind)}, 10, time.Minute); len(claimed) != 0 {
+			t.Fatalf("queued %+v for a period already sent", claimed)
+		}
+
+		// the next day's digest starts where this one ended
+		time.Sleep(time.Hour)
+		submit()
+		submit()
+		time.Sleep(24 * time.Hour)
+		digest()
+		if len(out.sent) != 2 || !strings.Contains(out.sent[1].Subject, "2 new responses") {
+			t.Fatalf("sent %+v, want a second digest of two responses", out.sent)
+		}
+	})
+}
@@ -0,0 +1,27 @@
+// Package notify sends emails about form activity.
+package notify
+
+import (
+	"context"
+	"log"
+)
+
+// Message is a plain text email to one recipient.
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+}
+
+type Notifier interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// LogNotifier writes messages to the log instead of sending them, for
+// development setups without a mail server.
+type LogNotifier struct{}
+
+func (LogNotifier) Send(ctx context.Context, m Message) error {
+	log.Printf("notify: mail to %s: %s\n%s", m.To, m.Subject, m.Text)
+	return nil
+}
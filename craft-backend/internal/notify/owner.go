@@ -0,0 +1,215 @@
+package notify
+
+import (
+	"context"
+	"craft/internal/export"
+	"craft/internal/jobs"
+	"craft/internal/model"
+	"craft/internal/store"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// BatchWindow is how long a new submission waits for others to share
+	// its owner email.
+	BatchWindow = 2 * time.Minute
+	// MaxSummarized caps the submissions spelled out in one email; the rest
+	// are only counted.
+	MaxSummarized = 10
+)
+
+// NewSubmissionsKind emails a form's owner about the submissions made since
+// their last email.
+const NewSubmissionsKind jobs.Kind[NewSubmissionsPayload] = "notify.new_submissions"
+
+type NewSubmissionsPayload struct {
+	FormID uuid.UUID `json:"form_id"`
+	// From is when the first submission of the batch was made; earlier
+	// ones were made while notifications were off or are already reported.
+	From time.Time `json:"from"`
+}
+
+func ownerWatermark(formID uuid.UUID) string {
+	return "owner-submissions:" + formID.String()
+}
+
+// QueueOwnerEmail schedules the owner email for sub when the form has
+// notifications on, joining the batch already waiting if there is one.
+func QueueOwnerEmail(ctx context.Context, q store.JobStore, f *model.Form, sub *model.Submission) error {
+	if !f.NotifyOwner {
+		return nil
+	}
+	_, err := jobs.EnqueueOnce(ctx, q, NewSubmissionsKind, ownerWatermark(f.ID),
+		NewSubmissionsPayload{FormID: f.ID, From: sub.CreatedAt}, sub.CreatedAt.Add(BatchWindow))
+	return err
+}
+
+// OwnerMailer runs NewSubmissionsKind jobs.
+type OwnerMailer struct {
+	Forms         store.FormStore
+	Users         store.UserStore
+	Submissions   store.SubmissionStore
+	Notifications store.NotificationStore
+	Jobs          store.JobStore
+	Notifier      Notifier
+	// AppURL is the frontend's base URL, for links to the responses.
+	AppURL string
+}
+
+func (m *OwnerMailer) Register(r *jobs.Registry) {
+	jobs.Handle(r, NewSubmissionsKind, m.sendNewSubmissions, jobs.Options{})
+}
+
+func (m *OwnerMailer) sendNewSubmissions(ctx context.Context, job jobs.Job[NewSubmissionsPayload]) error {
+	f, err := m.Forms.Get(ctx, job.Payload.FormID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !f.NotifyOwner {
+		return nil
+	}
+	owner, err := m.Users.Get(ctx, f.OwnerID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	key := ownerWatermark(f.ID)
+	since, err := m.Notifications.Watermark(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	// settled submissions only, so the watermark never passes one still
+	// being saved; those held back go in the next batch
+	var summarized []model.SubmissionWithAnswers
+	var last store.SubmissionCursor
+	total := 0
+	query := store.SubmissionQuery{Since: since, CreatedFrom: &job.Payload.From, Settled: true}
+	err = m.Submissions.Each(ctx, f.ID, query, func(sub *model.SubmissionWithAnswers) error {
+		total++
+		if len(summarized) < MaxSummarized {
+			summarized = append(summarized, *sub)
+		}
+		last = store.SubmissionCursor{CreatedAt: sub.CreatedAt, ID: sub.ID}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if total > 0 {
+		msg := Message{
+			To:      owner.Email,
+			Subject: newSubmissionsSubject(f, total),
+			Text:    m.newSubmissionsText(f, owner, summarized, total),
+		}
+		if err := m.Notifier.Send(ctx, msg); err != nil {
+			return err
+		}
+		if err := m.Notifications.SetWatermark(ctx, key, last); err != nil {
+			return err
+		}
+		since = &last
+	}
+	return m.queueHeldBack(ctx, f, since, job.Payload.From)
+}
+
+// queueHeldBack schedules another batch for the submissions after since that
+// were too recent to report. They joined the job just run while it was
+// pending, so nothing else would report them.
+func (m *OwnerMailer) queueHeldBack(ctx context.Context, f *model.Form, since *store.SubmissionCursor, from time.Time) error {
+	var first *time.Time
+	err := m.Submissions.Each(ctx, f.ID, store.SubmissionQuery{Since: since, CreatedFrom: &from}, func(sub *model.SubmissionWithAnswers) error {
+		if first == nil {
+			first = &sub.CreatedAt
+		}
+		return nil
+	})
+	if err != nil || first == nil {
+		return err
+	}
+	_, err = jobs.EnqueueOnce(ctx, m.Jobs, NewSubmissionsKind, ownerWatermark(f.ID),
+		NewSubmissionsPayload{FormID: f.ID, From: *first}, time.Now().Add(store.SubmissionSettleTime))
+	return err
+}
+
+func newSubmissionsSubject(f *model.Form, total int) string {
+	if total == 1 {
+		return fmt.Sprintf("New response to %q", f.Title)
+	}
+	return fmt.Sprintf("%d new responses to %q", total, f.Title)
+}
+
+func (m *OwnerMailer) newSubmissionsText(f *model.Form, owner *model.User, subs []model.SubmissionWithAnswers, total int) string {
+	var b strings.Builder
+	if owner.FirstName != "" {
+		fmt.Fprintf(&b, "Hi %s,\n\n", owner.FirstName)
+	}
+	if total == 1 {
+		fmt.Fprintf(&b, "%q received a new response.\n", f.Title)
+	} else {
+		fmt.Fprintf(&b, "%q received %d new responses.\n", f.Title, total)
+	}
+
+	for i, sub := range subs {
+		b.WriteString("\n")
+		writeSubmission(&b, f, &sub, i+1)
+	}
+	if more := total - len(subs); more > 0 {
+		fmt.Fprintf(&b, "\n...and %d more.\n", more)
+	}
+
+	fmt.Fprintf(&b, "\nSee all responses: %s\n", ResponsesURL(m.AppURL, f.ID))
+	b.WriteString("\nYou get these emails because notifications are on for this form. You can turn them off in its settings.\n")
+	return b.String()
+}
+
+// writeSubmission renders one submission as "Title: answer" lines, in the
+// form's current question order, followed by answers to removed questions.
+func writeSubmission(b *strings.Builder, f *model.Form, sub *model.SubmissionWithAnswers, n int) {
+	fmt.Fprintf(b, "Response %d, %s\n", n, sub.CreatedAt.UTC().Format("2 Jan 2006 15:04 MST"))
+	if sub.RespondentEmail != nil {
+		fmt.Fprintf(b, "  From: %s\n", *sub.RespondentEmail)
+	}
+
+	answers := make(map[uuid.UUID]model.Answer, len(sub.Answers))
+	for _, a := range sub.Answers {
+		answers[a.QuestionID] = a
+	}
+	for _, q := range export.Columns(f) {
+		if a, ok := answers[q.ID]; ok {
+			writeAnswer(b, q.Title, export.FormatAnswer(q, a.Value))
+			delete(answers, q.ID)
+		}
+	}
+	for _, a := range sub.Answers {
+		if _, ok := answers[a.QuestionID]; ok {
+			writeAnswer(b, a.QuestionTitle, export.FormatAnswer(model.Question{Type: a.QuestionType}, a.Value))
+		}
+	}
+}
+
+func writeAnswer(b *strings.Builder, title, value string) {
+	if value == "" {
+		value = "(no answer)"
+	}
+	// indent continuation lines of long-text answers under their title
+	value = strings.ReplaceAll(value, "\n", "\n    ")
+	fmt.Fprintf(b, "  %s: %s\n", title, value)
+}
+
+// ResponsesURL links to a form's responses in the frontend.
+func ResponsesURL(appURL string, formID uuid.UUID) string {
+	return strings.TrimRight(appURL, "/") + "/dashboard/forms/" + formID.String() + "/responses"
+}
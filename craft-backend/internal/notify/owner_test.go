@@ -0,0 +1,257 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"craft/internal/jobs"
+	"craft/internal/model"
+	"craft/internal/store"
+	"net"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// smtpSink is an SMTP server on loopback that accepts every message and
+// passes its data on mail, returning its port.
+func smtpSink(t *testing.T, mail chan<- string) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, mail)
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func serveSMTP(conn net.Conn, mail chan<- string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+	reply("220 sink")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 sink")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil || l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			mail <- data.String()
+			reply("250 ok")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+type ownerFixture struct {
+	st   store.Stores
+	form *model.Form
+}
+
+// newOwnerFixture stores a published form, with owner notifications as
+// given, and runs NewSubmissionsKind jobs that mail through the sink on
+// port until the test ends.
+func newOwnerFixture(t *testing.T, port int, notifyOwner bool) *ownerFixture {
+	t.Helper()
+	ctx := t.Context()
+	m := store.NewMemory()
+	st := m.Stores()
+	owner := uuid.New()
+	m.PutUser(model.User{ID: owner, FirstName: "Ada", Email: "ada@example.com"})
+
+	f, err := st.Forms.Create(ctx, owner, "Team lunch", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Questions = []model.Question{{Type: model.QuestionTypeShortText, Title: "Name"}}
+	if err := st.Forms.Update(ctx, owner, f); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Forms.SetStatus(ctx, f.ID, owner, model.FormStatusPublished, true, f.Version); err != nil {
+		t.Fatal(err)
+	}
+	if f, err = st.Forms.Get(ctx, f.ID); err != nil {
+		t.Fatal(err)
+	}
+	f.NotifyOwner = notifyOwner
+	f.AllowMultipleSubmissions = true
+	if err := st.Forms.UpdateSettings(ctx, owner, f); err != nil {
+		t.Fatal(err)
+	}
+
+	notifier, err := NewSMTPNotifier(SMTPConfig{Host: "127.0.0.1", Port: port, From: "Craft <forms@craft.test>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := jobs.NewRegistry()
+	(&OwnerMailer{
+		Forms:         st.Forms,
+		Users:         st.Users,
+		Submissions:   st.Submissions,
+		Notifications: st.Notifications,
+		Jobs:          st.Jobs,
+		Notifier:      notifier,
+		AppURL:        "https://craft.test",
+	}).Register(registry)
+	pool := jobs.NewPool(st.Jobs, registry)
+	pool.Workers = 1
+	pool.PollInterval = time.Second
+	pool.Start()
+	t.Cleanup(func() { pool.Shutdown(context.Background()) })
+
+	return &ownerFixture{st: st, form: f}
+}
+
+// submit saves a response and queues the owner email for it, as SubmitForm
+// does.
+func (fx *ownerFixture) submit(t *testing.T, name string) {
+	t.Helper()
+	sub := model.Submission{FormID: fx.form.ID}
+	answers := []model.Answer{{QuestionID: fx.form.Questions[0].ID, Value: []byte(`"` + name + `"`)}}
+	if err := fx.st.Submissions.Create(t.Context(), &sub, answers, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := QueueOwnerEmail(t.Context(), fx.st.Jobs, fx.form, &sub); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// received returns the messages the sink has been given so far.
+func received(mail <-chan string) []string {
+	var msgs []string
+	for {
+		select {
+		case msg := <-mail:
+			msgs = append(msgs, msg)
+		default:
+			return msgs
+		}
+	}
+}
+
+func TestOwnerEmailIsBatched(t *testing.T) {
+	mail := make(chan string, 10)
+	port := smtpSink(t, mail)
+
+	synctest.Test(t, func(t *testing.T) {
+		fx := newOwnerFixture(t, port, true)
+
+		// three responses within the window share one email
+		fx.submit(t, "Ada")
+		time.Sleep(30 * time.Second)
+		fx.submit(t, "Grace")
+		time.Sleep(30 * time.Second)
+		fx.submit(t, "Edsger")
+
+		time.Sleep(BatchWindow - time.Minute - time.Second)
+		synctest.Wait()
+		if msgs := received(mail); len(msgs) != 0 {
+			t.Fatalf("%d emails sent before the window closed", len(msgs))
+		}
+
+		time.Sleep(2 * time.Second)
+		synctest.Wait()
+		msgs := received(mail)
+		if len(msgs) != 1 {
+			t.Fatalf("%d emails sent for the first window, want 1", len(msgs))
+		}
+		for _, want := range []string{"To: <ada@example.com>", "3 new responses", "Name: Ada", "Name: Grace", "Name: Edsger"} {
+			if !strings.Contains(msgs[0], want) {
+				t.Errorf("email lacks %q:\n%s", want, msgs[0])
+			}
+		}
+
+		// a later response opens a new window, reported on its own
+		time.Sleep(time.Hour)
+		fx.submit(t, "Barbara")
+		time.Sleep(BatchWindow + time.Second)
+		synctest.Wait()
+		msgs = received(mail)
+		if len(msgs) != 1 {
+			t.Fatalf("%d emails sent for the second window, want 1", len(msgs))
+		}
+		if !strings.Contains(msgs[0], "New response to") || !strings.Contains(msgs[0], "Name: Barbara") || strings.Contains(msgs[0], "Name: Edsger") {
+			t.Errorf("second email:\n%s", msgs[0])
+		}
+
+		time.Sleep(time.Hour)
+		synctest.Wait()
+		if msgs := received(mail); len(msgs) != 0 {
+			t.Fatalf("%d more emails sent", len(msgs))
+		}
+	})
+}
+
+func TestOwnerEmailReportsHeldBackSubmissions(t *testing.T) {
+	mail := make(chan string, 10)
+	port := smtpSink(t, mail)
+
+	synctest.Test(t, func(t *testing.T) {
+		fx := newOwnerFixture(t, port, true)
+
+		// the second response comes too close to the batch to be reported
+		// with it, as it might still have been saving, so follows on its own
+		fx.submit(t, "Ada")
+		time.Sleep(BatchWindow - time.Second)
+		fx.submit(t, "Grace")
+		time.Sleep(2 * time.Second)
+		synctest.Wait()
+		msgs := received(mail)
+		if len(msgs) != 1 || !strings.Contains(msgs[0], "Name: Ada") || strings.Contains(msgs[0], "Name: Grace") {
+			t.Fatalf("first batch: %q", msgs)
+		}
+
+		time.Sleep(store.SubmissionSettleTime + 2*time.Second)
+		synctest.Wait()
+		msgs = received(mail)
+		if len(msgs) != 1 || !strings.Contains(msgs[0], "Name: Grace") || strings.Contains(msgs[0], "Name: Ada") {
+			t.Fatalf("held back response: %q", msgs)
+		}
+	})
+}
+
+func TestOwnerEmailNeedsNotifyOwner(t *testing.T) {
+	mail := make(chan string, 10)
+	port := smtpSink(t, mail)
+
+	synctest.Test(t, func(t *testing.T) {
+		fx := newOwnerFixture(t, port, false)
+		fx.submit(t, "Ada")
+		fx.submit(t, "Grace")
+
+		time.Sleep(BatchWindow + time.Hour)
+		synctest.Wait()
+		if msgs := received(mail); len(msgs) != 0 {
+			t.Fatalf("%d emails sent with notify_owner off", len(msgs))
+		}
+	})
+}
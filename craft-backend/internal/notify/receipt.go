@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"context"
+	"craft/internal/export"
+	"craft/internal/jobs"
+	"craft/internal/model"
+	"craft/internal/store"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReceiptKind sends a respondent the receipt rendered when they submitted,
+// so later edits to the form or its autoresponder do not change it.
+const ReceiptKind jobs.Kind[Message] = "notify.receipt"
+
+// QueueReceipt schedules the receipt of a submission made from ip when the
+// form's autoresponder is on and the respondent gave an address. It returns
+// ErrTooManyEmails, sending nothing, once the address or ip has had its share
+// of emails; see AllowRespondentEmail.
+func QueueReceipt(ctx context.Context, autoresponders store.AutoresponderStore, notifications store.NotificationStore, q store.JobStore, f *model.Form, answers []model.Answer, ip string) error {
+	ar, err := autoresponders.Get(ctx, f.ID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	msg, ok := RenderReceipt(f, ar, answers)
+	if !ok {
+		return nil
+	}
+	if err := AllowRespondentEmail(ctx, notifications, f.ID, msg.To, ip); err != nil {
+		return err
+	}
+	_, err = jobs.Enqueue(ctx, q, ReceiptKind, msg)
+	return err
+}
+
+// RenderReceipt fills in the autoresponder's templates with answers. It
+// reports false when there is nobody to send the receipt to: the
+// autoresponder is off, its question is gone or no longer an email
+// question, or was left unanswered.
+func RenderReceipt(f *model.Form, ar *model.Autoresponder, answers []model.Answer) (Message, bool) {
+	if !ar.Enabled || ar.QuestionID == nil {
+		return Message{}, false
+	}
+
+	questions := make(map[uuid.UUID]model.Question, len(f.Questions))
+	for _, q := range f.Questions {
+		if q.ArchivedAt == nil {
+			questions[q.ID] = q
+		}
+	}
+	if q, ok := questions[*ar.QuestionID]; !ok || q.Type != model.QuestionTypeEmail {
+		return Message{}, false
+	}
+
+	values := make(map[uuid.UUID]string, len(answers))
+	for _, a := range answers {
+		if q, ok := questions[a.QuestionID]; ok {
+			values[q.ID] = export.FormatAnswer(q, a.Value)
+		}
+	}
+	to := strings.TrimSpace(values[*ar.QuestionID])
+	if to == "" {
+		return Message{}, false
+	}
+
+	return Message{
+		To: to,
+		// answers may span lines, which a subject cannot
+		Subject: strings.Join(strings.Fields(renderTemplate(f, ar.Subject, values)), " "),
+		Text:    renderTemplate(f, ar.Body, values),
+	}, true
+}
+
+// ReceiptMailer runs ReceiptKind and ResumeLinkKind jobs.
+type ReceiptMailer struct {
+	Notifier Notifier
+}
+
+func (m *ReceiptMailer) Register(r *jobs.Registry) {
+	jobs.Handle(r, ReceiptKind, m.sendReceipt, jobs.Options{})
+	jobs.Handle(r, ResumeLinkKind, m.sendReceipt, jobs.Options{})
+}
+
+func (m *ReceiptMailer) sendReceipt(ctx context.Context, job jobs.Job[Message]) error {
+	return m.Notifier.Send(ctx, job.Payload)
+}
+
+// ResumeLinkKind sends a respondent the link that resumes the answers they
+// saved to a form.
+const ResumeLinkKind jobs.Kind[Message] = "notify.resume_link"
+
+// QueueResumeLink schedules the resume link of a partial response, which
+// stays valid for ttl after the last save.
+func QueueResumeLink(ctx context.Context, q store.JobStore, f *model.Form, to, link string, ttl time.Duration) error {
+	_, err := jobs.Enqueue(ctx, q, ResumeLinkKind, Message{
+		To:      to,
+		Subject: strings.Join(strings.Fields("Finish "+f.Title), " "),
+		Text: fmt.Sprintf("Your answers to %q are saved. Pick up where you left off, on any device:\n\n%s\n\n"+
+			"The link works for %s after your last save. Anyone with it can see and change your answers, so keep it to yourself.\n",
+			f.Title, link, plural(int(ttl/(24*time.Hour)), "day")),
+	})
+	return err
+}
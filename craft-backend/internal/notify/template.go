@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"craft/internal/model"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// FormTitlePlaceholder stands for the form's title in receipt templates.
+const FormTitlePlaceholder = "form.title"
+
+var placeholderPattern = regexp.MustCompile(`\{\{([^{}]*)\}\}`)
+
+// UnknownPlaceholders returns the placeholders of a receipt template that
+// name neither a live question of f nor the form's title.
+func UnknownPlaceholders(f *model.Form, text string) []string {
+	var unknown []string
+	for _, m := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+		name := strings.TrimSpace(m[1])
+		if name == FormTitlePlaceholder {
+			continue
+		}
+		if _, ok := placeholderQuestion(f, name); !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
+
+// placeholderQuestion resolves a placeholder to a live question of f, by ID
+// or else by title, ignoring case. Of several questions with that title the
+// first wins.
+func placeholderQuestion(f *model.Form, name string) (model.Question, bool) {
+	if id, err := uuid.Parse(name); err == nil {
+		for _, q := range f.Questions {
+			if q.ID == id && q.ArchivedAt == nil {
+				return q, true
+			}
+		}
+	}
+	for _, q := range f.Questions {
+		if q.ArchivedAt == nil && strings.EqualFold(strings.TrimSpace(q.Title), name) {
+			return q, true
+		}
+	}
+	return model.Question{}, false
+}
+
+// renderTemplate replaces each placeholder of text with the formatted
+// answer it names, keyed by question ID in answers. Questions left
+// unanswered, or removed since the template was saved, render empty.
+func renderTemplate(f *model.Form, text string, answers map[uuid.UUID]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(text, func(m string) string {
+		name := strings.TrimSpace(m[2 : len(m)-2])
+		if name == FormTitlePlaceholder {
+			return f.Title
+		}
+		q, ok := placeholderQuestion(f, name)
+		if !ok {
+			return ""
+		}
+		return answers[q.ID]
+	})
+}
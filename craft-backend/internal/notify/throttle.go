@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"context"
+	"craft/internal/store"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrTooManyEmails is returned when an email to a respondent is refused as
+// too many already went to the same address, or were asked for from the
+// same IP, so forms cannot be used to send mail to strangers in bulk.
+var ErrTooManyEmails = errors.New("notify: too many emails")
+
+const (
+	// ThrottleWindow is how long respondent emails are counted for.
+	ThrottleWindow = time.Hour
+	// MaxPerFormAddress caps the emails one form sends an address.
+	MaxPerFormAddress = 3
+	// MaxPerAddress caps the emails all forms together send an address.
+	MaxPerAddress = 10
+	// MaxPerIP caps the emails asked for from one IP.
+	MaxPerIP = 10
+)
+
+// AllowRespondentEmail counts an email a respondent asked formID for, from
+// ip, to be sent to to, and returns ErrTooManyEmails once one of the limits
+// is reached within ThrottleWindow.
+func AllowRespondentEmail(ctx context.Context, n store.NotificationStore, formID uuid.UUID, to, ip string) error {
+	to = strings.ToLower(strings.TrimSpace(to))
+	limits := []struct {
+		key string
+		max int
+	}{
+		{"respondent-ip:" + ip, MaxPerIP},
+		{"respondent-address:" + to, MaxPerAddress},
+		{"respondent-address:" + formID.String() + ":" + to, MaxPerFormAddress},
+	}
+	for _, l := range limits {
+		count, err := n.CountSend(ctx, l.key, ThrottleWindow)
+		if err != nil {
+			return err
+		}
+		if count > l.max {
+			return ErrTooManyEmails
+		}
+	}
+	return nil
+}
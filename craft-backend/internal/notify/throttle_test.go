@@ -0,0 +1,62 @@
+package notify
+
+import (
+	"craft/internal/store"
+	"errors"
+	"strconv"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestAllowRespondentEmail(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		n := store.NewMemory().Stores().Notifications
+		form, other := uuid.New(), uuid.New()
+		allow := func(formID uuid.UUID, to, ip string) error {
+			return AllowRespondentEmail(t.Context(), n, formID, to, ip)
+		}
+
+		// one form may only write to an address a few times, however
+		// the address is spelled
+		for i := range MaxPerFormAddress {
+			if err := allow(form, "ada@example.com", "203.0.113."+strconv.Itoa(i)); err != nil {
+				t.Fatalf("email %d: %v", i+1, err)
+			}
+		}
+		if err := allow(form, " Ada@Example.com", "203.0.113.50"); !errors.Is(err, ErrTooManyEmails) {
+			t.Fatalf("email past the form's limit: %v", err)
+		}
+
+		// nor may all forms together, refused emails counting too
+		for i := MaxPerFormAddress + 1; i < MaxPerAddress; i++ {
+			if err := allow(uuid.New(), "ada@example.com", "198.51.100."+strconv.Itoa(i)); err != nil {
+				t.Fatalf("email %d: %v", i+1, err)
+			}
+		}
+		if err := allow(other, "ada@example.com", "198.51.100.99"); !errors.Is(err, ErrTooManyEmails) {
+			t.Fatalf("email past the address's limit: %v", err)
+		}
+
+		// nor one IP, to however many addresses
+		for i := range MaxPerIP {
+			if err := allow(other, strconv.Itoa(i)+"@example.com", "192.0.2.1"); err != nil {
+				t.Fatalf("email %d from one IP: %v", i+1, err)
+			}
+		}
+		if err := allow(other, "grace@example.com", "192.0.2.1"); !errors.Is(err, ErrTooManyEmails) {
+			t.Fatalf("email past the IP's limit: %v", err)
+		}
+		if err := allow(other, "grace@example.com", "192.0.2.2"); err != nil {
+			t.Fatalf("email from another IP: %v", err)
+		}
+
+		// the limits lift once the window is over
+		time.Sleep(ThrottleWindow)
+		if err := allow(form, "ada@example.com", "192.0.2.1"); err != nil {
+			t.Fatalf("email after the window: %v", err)
+		}
+	})
+}
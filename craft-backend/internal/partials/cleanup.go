@@ -0,0 +1,52 @@
+package partials
+
+import (
+	"context"
+	"craft/internal/jobs"
+	"craft/internal/store"
+	"time"
+)
+
+const (
+	cleanupBatchSize = 500
+	cleanupKey       = "partials-cleanup"
+)
+
+// CleanupKind deletes expired partial responses and schedules the next
+// cleanup an hour later.
+const CleanupKind jobs.Kind[CleanupPayload] = "partials.cleanup"
+
+type CleanupPayload struct{}
+
+// ScheduleCleanup queues a cleanup now, unless one is already waiting. Call
+// it at startup; every cleanup schedules the next one.
+func ScheduleCleanup(ctx context.Context, q store.JobStore) error {
+	_, err := jobs.EnqueueOnce(ctx, q, CleanupKind, cleanupKey, CleanupPayload{}, time.Now())
+	return err
+}
+
+type Cleaner struct {
+	Partials store.PartialStore
+	Jobs     store.JobStore
+}
+
+func (c *Cleaner) Register(r *jobs.Registry) {
+	jobs.Handle(r, CleanupKind, c.cleanup, jobs.Options{})
+}
+
+func (c *Cleaner) cleanup(ctx context.Context, job jobs.Job[CleanupPayload]) error {
+	now := time.Now()
+	for {
+		n, err := c.Partials.DeleteExpired(ctx, now, cleanupBatchSize)
+		if err != nil {
+			return err
+		}
+		if n < cleanupBatchSize {
+			break
+		}
+	}
+
+	next := now.Truncate(time.Hour).Add(time.Hour)
+	_, err := jobs.EnqueueOnce(ctx, c.Jobs, CleanupKind, cleanupKey, CleanupPayload{}, next)
+	return err
+}
@@ -0,0 +1,76 @@
+// Package partials lets respondents save a form and finish it later.
+package partials
+
+import (
+	"craft/internal/model"
+	"craft/internal/model/payload"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TTL is how long a partial response is kept after it was last saved.
+const TTL = 30 * 24 * time.Hour
+
+// NewToken returns a new resume token and the hash it is stored under. Only
+// the respondent gets the token.
+func NewToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the hash a resume token is stored under.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ResumeURL links to the public page of a form, at appURL, with the resume
+// token that restores the saved answers. The page is addressed by the
+// owner's first name and the form title, as the store looks it up.
+func ResumeURL(appURL, ownerFirstName, formTitle, token string) string {
+	slug := func(s string) string {
+		return url.PathEscape(strings.ReplaceAll(strings.TrimSpace(s), " ", "_"))
+	}
+	return strings.TrimRight(appURL, "/") + "/" + slug(ownerFirstName) + "/" + slug(formTitle) +
+		"?" + url.Values{"resume": {token}}.Encode()
+}
+
+// Merge returns the saved answers with answers applied on top, question by
+// question, in the order they were first given.
+func Merge(saved []model.PartialAnswer, answers []payload.AnswerInput) []payload.AnswerInput {
+	merged := make([]payload.AnswerInput, 0, len(saved)+len(answers))
+	at := make(map[uuid.UUID]int, len(saved)+len(answers))
+	for _, a := range saved {
+		at[a.QuestionID] = len(merged)
+		merged = append(merged, payload.AnswerInput{QuestionID: a.QuestionID, Value: a.Value})
+	}
+	for _, a := range answers {
+		if i, ok := at[a.QuestionID]; ok {
+			merged[i] = a
+			continue
+		}
+		at[a.QuestionID] = len(merged)
+		merged = append(merged, a)
+	}
+	return merged
+}
+
+// Answers converts request answers to the form they are saved in.
+func Answers(answers []payload.AnswerInput) []model.PartialAnswer {
+	out := make([]model.PartialAnswer, len(answers))
+	for i, a := range answers {
+		out[i] = model.PartialAnswer{QuestionID: a.QuestionID, Value: a.Value}
+	}
+	return out
+}
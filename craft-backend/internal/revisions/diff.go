@@ -0,0 +1,161 @@
+package revisions
+
+import (
+	"craft/internal/model"
+	"reflect"
+
+	"github.com/google/uuid"
+)
+
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+type OptionChange struct {
+	OptionID uuid.UUID              `json:"option_id"`
+	Fields   map[string]FieldChange `json:"fields"`
+}
+
+type QuestionChange struct {
+	QuestionID     uuid.UUID              `json:"question_id"`
+	Title          string                 `json:"title"`
+	Fields         map[string]FieldChange `json:"fields,omitempty"`
+	OptionsAdded   []model.Option         `json:"options_added,omitempty"`
+	OptionsRemoved []model.Option         `json:"options_removed,omitempty"`
+	OptionsChanged []OptionChange         `json:"options_changed,omitempty"`
+}
+
+// Diff describes how to get from one revision of a form to another.
+// Questions and options are matched by ID, so a renamed question shows up as
+// a change rather than a removal plus an addition.
+type Diff struct {
+	From             int                    `json:"from"`
+	To               int                    `json:"to"`
+	Fields           map[string]FieldChange `json:"fields,omitempty"`
+	QuestionsAdded   []model.Question       `json:"questions_added"`
+	QuestionsRemoved []model.Question       `json:"questions_removed"`
+	QuestionsChanged []QuestionChange       `json:"questions_changed"`
+}
+
+func Compare(from, to *model.FormRevision) Diff {
+	d := Diff{
+		From:             from.Revision,
+		To:               to.Revision,
+		Fields:           map[string]FieldChange{},
+		QuestionsAdded:   []model.Question{},
+		QuestionsRemoved: []model.Question{},
+		QuestionsChanged: []QuestionChange{},
+	}
+
+	compareField(d.Fields, "title", from.Title, to.Title)
+	compareField(d.Fields, "description", deref(from.Description), deref(to.Description))
+	if (len(from.Sections) > 0 || len(to.Sections) > 0) && !reflect.DeepEqual(from.Sections, to.Sections) {
+		d.Fields["sections"] = FieldChange{From: from.Sections, To: to.Sections}
+	}
+
+	before := make(map[uuid.UUID]model.Question, len(from.Questions))
+	for _, q := range from.Questions {
+		before[q.ID] = q
+	}
+	after := make(map[uuid.UUID]bool, len(to.Questions))
+
+	for _, q := range to.Questions {
+		after[q.ID] = true
+		old, ok := before[q.ID]
+		if !ok {
+			d.QuestionsAdded = append(d.QuestionsAdded, q)
+			continue
+		}
+		if change, changed := compareQuestion(old, q); changed {
+			d.QuestionsChanged = append(d.QuestionsChanged, change)
+		}
+	}
+
+	for _, q := range from.Questions {
+		if !after[q.ID] {
+			d.QuestionsRemoved = append(d.QuestionsRemoved, q)
+		}
+	}
+
+	if len(d.Fields) == 0 {
+		d.Fields = nil
+	}
+	return d
+}
+
+func compareQuestion(from, to model.Question) (QuestionChange, bool) {
+	c := QuestionChange{
+		QuestionID: to.ID,
+		Title:      to.Title,
+		Fields:     map[string]FieldChange{},
+	}
+
+	compareField(c.Fields, "type", from.Type, to.Type)
+	compareField(c.Fields, "title", from.Title, to.Title)
+	compareField(c.Fields, "description", deref(from.Description), deref(to.Description))
+	compareField(c.Fields, "emoji", deref(from.Emoji), deref(to.Emoji))
+	compareField(c.Fields, "position", from.Position, to.Position)
+	compareField(c.Fields, "required", from.Required, to.Required)
+	if !reflect.DeepEqual(from.SectionID, to.SectionID) {
+		c.Fields["section_id"] = FieldChange{From: from.SectionID, To: to.SectionID}
+	}
+	if !reflect.DeepEqual(from.Number, to.Number) {
+		c.Fields["number"] = FieldChange{From: from.Number, To: to.Number}
+	}
+	if !reflect.DeepEqual(from.Rating, to.Rating) {
+		c.Fields["rating"] = FieldChange{From: from.Rating, To: to.Rating}
+	}
+	if !reflect.DeepEqual(from.FileUpload, to.FileUpload) {
+		c.Fields["file_upload"] = FieldChange{From: from.FileUpload, To: to.FileUpload}
+	}
+	if !reflect.DeepEqual(from.Visibility, to.Visibility) {
+		c.Fields["visibility"] = FieldChange{From: from.Visibility, To: to.Visibility}
+	}
+
+	before := make(map[uuid.UUID]model.Option, len(from.Options))
+	for _, opt := range from.Options {
+		before[opt.ID] = opt
+	}
+	after := make(map[uuid.UUID]bool, len(to.Options))
+
+	for _, opt := range to.Options {
+		after[opt.ID] = true
+		old, ok := before[opt.ID]
+		if !ok {
+			c.OptionsAdded = append(c.OptionsAdded, opt)
+			continue
+		}
+		fields := map[string]FieldChange{}
+		compareField(fields, "label", old.Label, opt.Label)
+		compareField(fields, "position", old.Position, opt.Position)
+		if len(fields) > 0 {
+			c.OptionsChanged = append(c.OptionsChanged, OptionChange{OptionID: opt.ID, Fields: fields})
+		}
+	}
+
+	for _, opt := range from.Options {
+		if !after[opt.ID] {
+			c.OptionsRemoved = append(c.OptionsRemoved, opt)
+		}
+	}
+
+	changed := len(c.Fields) > 0 || len(c.OptionsAdded) > 0 || len(c.OptionsRemoved) > 0 || len(c.OptionsChanged) > 0
+	if len(c.Fields) == 0 {
+		c.Fields = nil
+	}
+	return c, changed
+}
+
+func compareField[T comparable](fields map[string]FieldChange, name string, from, to T) {
+	if from != to {
+		fields[name] = FieldChange{From: from, To: to}
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,88 @@
+package revisions
+
+import (
+	"craft/internal/model"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCompare(t *testing.T) {
+	name, main, notes := uuid.New(), uuid.New(), uuid.New()
+	pasta, salad, soup := uuid.New(), uuid.New(), uuid.New()
+	from := &model.FormRevision{
+		Revision: 1,
+		Title:    "Team lunch",
+		Questions: []model.Question{
+			{ID: name, Type: model.QuestionTypeShortText, Title: "Name"},
+			{ID: main, Type: model.QuestionTypeSingleSelect, Title: "Main", Position: 1, Options: []model.Option{
+				{ID: pasta, Label: "Pasta"},
+				{ID: salad, Label: "Salad", Position: 1},
+			}},
+			{ID: notes, Type: model.QuestionTypeLongText, Title: "Notes", Position: 2},
+		},
+	}
+	email := uuid.New()
+	to := &model.FormRevision{
+		Revision: 3,
+		Title:    "Team dinner",
+		Questions: []model.Question{
+			{ID: name, Type: model.QuestionTypeShortText, Title: "Name"},
+			{ID: main, Type: model.QuestionTypeSingleSelect, Title: "Main course", Position: 1, Required: true, Options: []model.Option{
+				{ID: salad, Label: "Green salad"},
+				{ID: soup, Label: "Soup", Position: 1},
+			}},
+			{ID: email, Type: model.QuestionTypeShortText, Title: "Email", Position: 2},
+		},
+	}
+
+	d := Compare(from, to)
+	if d.From != 1 || d.To != 3 {
+		t.Fatalf("from %d to %d", d.From, d.To)
+	}
+	if got := d.Fields["title"]; got.From != "Team lunch" || got.To != "Team dinner" {
+		t.Fatalf("title change %+v", got)
+	}
+	if len(d.QuestionsAdded) != 1 || d.QuestionsAdded[0].ID != email {
+		t.Fatalf("added %+v", d.QuestionsAdded)
+	}
+	if len(d.QuestionsRemoved) != 1 || d.QuestionsRemoved[0].ID != notes {
+		t.Fatalf("removed %+v", d.QuestionsRemoved)
+	}
+
+	// the unchanged question is left out, the renamed one is a change
+	if len(d.QuestionsChanged) != 1 {
+		t.Fatalf("changed %+v", d.QuestionsChanged)
+	}
+	c := d.QuestionsChanged[0]
+	if c.QuestionID != main || c.Fields["title"].To != "Main course" || c.Fields["required"].To != true {
+		t.Fatalf("question change %+v", c)
+	}
+	if _, ok := c.Fields["type"]; ok {
+		t.Fatalf("type reported as changed: %+v", c.Fields)
+	}
+	if len(c.OptionsAdded) != 1 || c.OptionsAdded[0].ID != soup {
+		t.Fatalf("options added %+v", c.OptionsAdded)
+	}
+	if len(c.OptionsRemoved) != 1 || c.OptionsRemoved[0].ID != pasta {
+		t.Fatalf("options removed %+v", c.OptionsRemoved)
+	}
+	if len(c.OptionsChanged) != 1 || c.OptionsChanged[0].OptionID != salad {
+		t.Fatalf("options changed %+v", c.OptionsChanged)
+	}
+	if f := c.OptionsChanged[0].Fields; f["label"].To != "Green salad" || f["position"].To != 0 {
+		t.Fatalf("option change %+v", f)
+	}
+}
+
+func TestCompareSameRevision(t *testing.T) {
+	rev := &model.FormRevision{
+		Revision:  2,
+		Title:     "Team lunch",
+		Questions: []model.Question{{ID: uuid.New(), Type: model.QuestionTypeShortText, Title: "Name"}},
+	}
+	d := Compare(rev, rev)
+	if d.Fields != nil || len(d.QuestionsAdded)+len(d.QuestionsRemoved)+len(d.QuestionsChanged) != 0 {
+		t.Fatalf("diff of a revision with itself: %+v", d)
+	}
+}
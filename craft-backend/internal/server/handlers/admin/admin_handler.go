@@ -1,53 +1,40 @@
 package admin
 
 import (
-	"craft/internal/db"
 	"craft/internal/model"
+	"craft/internal/store"
+	"errors"
 
 	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
 	"github.com/supabase-community/supabase-go"
 )
 
 type AdminHandler struct {
 	supabase *supabase.Client
-	DB       *db.Database
+	Forms    store.FormStore
+	Users    store.UserStore
+	Jobs     store.JobStore
 }
 
-func NewAdminHandler(supabase *supabase.Client, DB *db.Database) *AdminHandler {
+func NewAdminHandler(supabase *supabase.Client, forms store.FormStore, users store.UserStore, jobs store.JobStore) *AdminHandler {
 	return &AdminHandler{
 		supabase: supabase,
-		DB:       DB,
+		Forms:    forms,
+		Users:    users,
+		Jobs:     jobs,
 	}
 }
 
 func (h *AdminHandler) GetAllUsers(c fiber.Ctx) error {
 	ctx := c.Context()
 
-	rows, err := h.DB.Pool.Query(ctx, `
-		SELECT id, first_name, last_name, email, role, is_verified, created_at, updated_at 
-		FROM public.users
-		ORDER BY created_at DESC
-	`)
+	users, err := h.Users.List(ctx)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to retrieve users",
 		})
 	}
-	defer rows.Close()
-
-	var users []model.User
-	for rows.Next() {
-		var u model.User
-		err := rows.Scan(
-			&u.ID, &u.FirstName, &u.LastName, &u.Email, &u.Role, &u.IsVerified, &u.CreatedAt, &u.UpdatedAt,
-		)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to scan user data",
-			})
-		}
-		users = append(users, u)
-	}
 
 	return c.JSON(fiber.Map{
 		"users": users,
@@ -57,31 +44,12 @@ func (h *AdminHandler) GetAllUsers(c fiber.Ctx) error {
 func (h *AdminHandler) GetAllForms(c fiber.Ctx) error {
 	ctx := c.Context()
 
-	rows, err := h.DB.Pool.Query(ctx, `
-		SELECT id, owner_id, title, description, status, created_at, updated_at 
-		FROM forms
-		ORDER BY created_at DESC
-	`)
+	forms, err := h.Forms.ListAll(ctx)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to retrieve forms",
 		})
 	}
-	defer rows.Close()
-
-	var forms []model.Form
-	for rows.Next() {
-		var f model.Form
-		err := rows.Scan(
-			&f.ID, &f.OwnerID, &f.Title, &f.Description, &f.Status, &f.CreatedAt, &f.UpdatedAt,
-		)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to scan form data",
-			})
-		}
-		forms = append(forms, f)
-	}
 
 	return c.JSON(fiber.Map{
 		"forms": forms,
@@ -98,32 +66,19 @@ func (h *AdminHandler) GetUserForms(c fiber.Ctx) error {
 		})
 	}
 
-	rows, err := h.DB.Pool.Query(ctx, `
-		SELECT id, owner_id, title, description, status, created_at, updated_at 
-		FROM forms
-		WHERE owner_id = $1
-		ORDER BY created_at DESC
-	`, userID)
+	ownerID, err := uuid.Parse(userID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	forms, err := h.Forms.ListByOwner(ctx, ownerID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to retrieve user forms",
 		})
 	}
-	defer rows.Close()
-
-	var forms []model.Form
-	for rows.Next() {
-		var f model.Form
-		err := rows.Scan(
-			&f.ID, &f.OwnerID, &f.Title, &f.Description, &f.Status, &f.CreatedAt, &f.UpdatedAt,
-		)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to scan form data",
-			})
-		}
-		forms = append(forms, f)
-	}
 
 	return c.JSON(fiber.Map{
 		"user_id": userID,
@@ -141,8 +96,14 @@ func (h *AdminHandler) DeleteUser(c fiber.Ctx) error {
 		})
 	}
 
-	_, err := h.DB.Pool.Exec(ctx, `DELETE FROM public.users WHERE id = $1`, userID)
+	id, err := uuid.Parse(userID)
 	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	if err := h.Users.Delete(ctx, id); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":  "Failed to delete user from database",
 			"detail": err.Error(),
@@ -158,68 +119,35 @@ func (h *AdminHandler) DeleteUser(c fiber.Ctx) error {
 func (h *AdminHandler) GetAllUsersWithForms(c fiber.Ctx) error {
 	ctx := c.Context()
 
-	rows, err := h.DB.Pool.Query(ctx, `
-		SELECT id, first_name, last_name, email, role, is_verified, created_at, updated_at 
-		FROM public.users
-		ORDER BY created_at DESC
-	`)
+	users, err := h.Users.List(ctx)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to retrieve users",
 		})
 	}
-	defer rows.Close()
-
-	var users []model.UserWithForms
-	for rows.Next() {
-		var u model.UserWithForms
-		err := rows.Scan(
-			&u.ID, &u.FirstName, &u.LastName, &u.Email, &u.Role, &u.IsVerified, &u.CreatedAt, &u.UpdatedAt,
-		)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to scan user data",
-			})
-		}
-		u.Forms = []model.Form{}
-		users = append(users, u)
-	}
 
-	formRows, err := h.DB.Pool.Query(ctx, `
-		SELECT id, owner_id, title, description, status, created_at, updated_at 
-		FROM forms
-		ORDER BY created_at DESC
-	`)
+	forms, err := h.Forms.ListAll(ctx)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to retrieve forms",
 		})
 	}
-	defer formRows.Close()
 
-	userMap := make(map[string]*model.UserWithForms)
+	result := make([]model.UserWithForms, len(users))
+	userMap := make(map[uuid.UUID]*model.UserWithForms)
 	for i := range users {
-		userMap[users[i].ID.String()] = &users[i]
+		result[i] = model.UserWithForms{User: users[i], Forms: []model.Form{}}
+		userMap[users[i].ID] = &result[i]
 	}
 
-	for formRows.Next() {
-		var f model.Form
-		err := formRows.Scan(
-			&f.ID, &f.OwnerID, &f.Title, &f.Description, &f.Status, &f.CreatedAt, &f.UpdatedAt,
-		)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to scan form data",
-			})
-		}
-
-		if u, ok := userMap[f.OwnerID.String()]; ok {
+	for _, f := range forms {
+		if u, ok := userMap[f.OwnerID]; ok {
 			u.Forms = append(u.Forms, f)
 		}
 	}
 
 	return c.JSON(fiber.Map{
-		"users": users,
+		"users": result,
 	})
 }
 
@@ -233,8 +161,14 @@ func (h *AdminHandler) DeleteForm(c fiber.Ctx) error {
 		})
 	}
 
-	_, err := h.DB.Pool.Exec(ctx, `DELETE FROM forms WHERE id = $1`, formID)
+	id, err := uuid.Parse(formID)
 	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid form ID",
+		})
+	}
+
+	if err := h.Forms.DeleteAny(ctx, id); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":  "Failed to delete form from database",
 			"detail": err.Error(),
@@ -250,13 +184,7 @@ func (h *AdminHandler) DeleteForm(c fiber.Ctx) error {
 func (h *AdminHandler) GetPublishedFormsCount(c fiber.Ctx) error {
 	ctx := c.Context()
 
-	var count int
-	err := h.DB.Pool.QueryRow(ctx, `
-		SELECT COUNT(*) 
-		FROM forms 
-		WHERE status = 'published'
-	`).Scan(&count)
-
+	count, err := h.Forms.CountPublished(ctx)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to count published forms",
@@ -267,3 +195,47 @@ func (h *AdminHandler) GetPublishedFormsCount(c fiber.Ctx) error {
 		"count": count,
 	})
 }
+
+const deadJobsLimit = 100
+
+// GetDeadJobs lists background jobs that failed their last attempt.
+func (h *AdminHandler) GetDeadJobs(c fiber.Ctx) error {
+	ctx := c.Context()
+
+	jobs, err := h.Jobs.ListDead(ctx, deadJobsLimit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve jobs",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"jobs": jobs,
+	})
+}
+
+// RetryJob queues a dead job again with a fresh set of attempts.
+func (h *AdminHandler) RetryJob(c fiber.Ctx) error {
+	ctx := c.Context()
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid job ID",
+		})
+	}
+
+	job, err := h.Jobs.Retry(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Dead job not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retry job",
+		})
+	}
+
+	return c.JSON(job)
+}
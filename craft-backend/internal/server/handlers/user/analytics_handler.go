@@ -0,0 +1,248 @@
+package user
+
+import (
+	"craft/internal/model"
+	"craft/internal/model/payload"
+	"craft/internal/store"
+	"craft/internal/validation"
+	"craft/pkg"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/supabase-community/supabase-go"
+)
+
+type AnalyticsHandler struct {
+	supabase  *supabase.Client
+	Forms     store.FormStore
+	Analytics store.AnalyticsStore
+}
+
+func NewAnalyticsHandler(supabase *supabase.Client, forms store.FormStore, analytics store.AnalyticsStore) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		supabase:  supabase,
+		Forms:     forms,
+		Analytics: analytics,
+	}
+}
+
+// GetFormAnalytics aggregates the answers to each question of the form
+// resolved by the FormAccess middleware.
+func (h *AnalyticsHandler) GetFormAnalytics(c fiber.Ctx) error {
+	ctx := c.Context()
+	f, ok := c.Locals("form").(*model.Form)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Form not resolved",
+		})
+	}
+
+	analytics, err := h.Analytics.FormAnalytics(ctx, f)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to compute analytics",
+			"detail": err.Error(),
+		})
+	}
+
+	return c.JSON(analytics)
+}
+
+// defaultSeriesSpan is how far back a response series reaches when the
+// request gives no start.
+var defaultSeriesSpan = map[string]time.Duration{
+	model.SeriesIntervalHour: 24 * time.Hour,
+	model.SeriesIntervalDay:  30 * 24 * time.Hour,
+	model.SeriesIntervalWeek: 12 * 7 * 24 * time.Hour,
+}
+
+// minBucketLength is the shortest a bucket can be, allowing for daylight
+// saving changes, which bounds the number of buckets in a range.
+var minBucketLength = map[string]time.Duration{
+	model.SeriesIntervalHour: time.Hour,
+	model.SeriesIntervalDay:  23 * time.Hour,
+	model.SeriesIntervalWeek: 7*24*time.Hour - time.Hour,
+}
+
+// seriesQuery reads ?interval=hour|day|week (default day), ?tz= (an IANA
+// zone, default UTC) and the ?from= and ?to= range of a response series.
+func seriesQuery(c fiber.Ctx) (store.SeriesQuery, error) {
+	q := store.SeriesQuery{Interval: c.Query("interval", model.SeriesIntervalDay)}
+	span, ok := defaultSeriesSpan[q.Interval]
+	if !ok {
+		return q, errors.New("Query parameter 'interval' must be hour, day or week")
+	}
+
+	loc, err := time.LoadLocation(c.Query("tz", "UTC"))
+	if err != nil || loc == time.Local {
+		return q, errors.New("Query parameter 'tz' must be an IANA time zone")
+	}
+	q.Location = loc
+
+	q.To = time.Now()
+	if raw := c.Query("to"); raw != "" {
+		if q.To, err = parseQueryTime(raw, loc); err != nil {
+			return q, errors.New("Query parameter 'to' must be an RFC 3339 timestamp or a date")
+		}
+	}
+	q.From = q.To.Add(-span)
+	if raw := c.Query("from"); raw != "" {
+		if q.From, err = parseQueryTime(raw, loc); err != nil {
+			return q, errors.New("Query parameter 'from' must be an RFC 3339 timestamp or a date")
+		}
+	}
+
+	if !q.From.Before(q.To) {
+		return q, errors.New("Query parameter 'from' must be before 'to'")
+	}
+	if q.To.Sub(q.From)/minBucketLength[q.Interval] > store.MaxSeriesPoints {
+		return q, fmt.Errorf("Range spans more than %d %ss", store.MaxSeriesPoints, q.Interval)
+	}
+	return q, nil
+}
+
+// GetResponseSeries counts the submissions to all of the caller's forms per
+// hour, day or week.
+func (h *AnalyticsHandler) GetResponseSeries(c fiber.Ctx) error {
+	ctx := c.Context()
+	userIDRaw := c.Locals("user_id")
+	if userIDRaw == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User not authenticated",
+		})
+	}
+
+	userID := userIDRaw.(uuid.UUID)
+	query, err := seriesQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	series, err := h.Analytics.ResponseSeries(ctx, userID, uuid.Nil, query)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to compute response series",
+			"detail": err.Error(),
+		})
+	}
+
+	return c.JSON(series)
+}
+
+// GetFormResponseSeries is GetResponseSeries for the form resolved by the
+// FormAccess middleware.
+func (h *AnalyticsHandler) GetFormResponseSeries(c fiber.Ctx) error {
+	ctx := c.Context()
+	f, ok := c.Locals("form").(*model.Form)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Form not resolved",
+		})
+	}
+
+	query, err := seriesQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	series, err := h.Analytics.ResponseSeries(ctx, f.OwnerID, f.ID, query)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to compute response series",
+			"detail": err.Error(),
+		})
+	}
+
+	return c.JSON(series)
+}
+
+// RecordFormEvent records a respondent viewing, starting or progressing
+// through a public form. Events for forms not accepting submissions are
+// accepted but not recorded, so tracking never disturbs the form itself.
+func (h *AnalyticsHandler) RecordFormEvent(c fiber.Ctx) error {
+	ctx := c.Context()
+	formID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid form ID",
+		})
+	}
+
+	var req payload.FormEventRequest
+	if err := c.Bind().JSON(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if err := pkg.Validator.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Event kind must be view, start or progress, with question_id for progress",
+		})
+	}
+
+	form, err := h.Forms.Get(ctx, formID)
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Form not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch form",
+		})
+	}
+	if validation.CheckAcceptingSubmissions(form, time.Now()) != nil {
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
+	event := model.FormEvent{FormID: formID, Kind: req.Kind}
+	if req.Kind == model.FormEventProgress {
+		if !slices.ContainsFunc(form.Questions, func(q model.Question) bool { return q.ID == *req.QuestionID }) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Question does not belong to this form",
+			})
+		}
+		event.QuestionID = req.QuestionID
+	}
+
+	// the device cookie ties events to the submission that completes them
+	event.SessionID, _, _ = respondentDevice(c, c.IP(), c.Get("User-Agent"))
+	if err := h.Analytics.RecordEvent(ctx, &event); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to record event",
+			"detail": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetFormFunnel reports view, start and completion counts and per-question
+// drop-off for the form resolved by the FormAccess middleware.
+func (h *AnalyticsHandler) GetFormFunnel(c fiber.Ctx) error {
+	ctx := c.Context()
+	f, ok := c.Locals("form").(*model.Form)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Form not resolved",
+		})
+	}
+
+	funnel, err := h.Analytics.Funnel(ctx, f)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to compute funnel",
+			"detail": err.Error(),
+		})
+	}
+
+	return c.JSON(funnel)
+}
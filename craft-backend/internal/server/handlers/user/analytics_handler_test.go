@@ -0,0 +1,275 @@
+package user
+
+import (
+	"craft/internal/authz"
+	"craft/internal/model"
+	"craft/internal/server/middlewares"
+	"craft/internal/store"
+	"encoding/json"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// formAnalytics fetches the analytics of f as its owner.
+func formAnalytics(t *testing.T, st store.Stores, owner uuid.UUID, f *model.Form) model.FormAnalytics {
+	t.Helper()
+	h := NewAnalyticsHandler(nil, st.Forms, st.Analytics)
+	app := fiber.New()
+	app.Get("/forms/:id/analytics", signedIn(owner, "user"), middlewares.FormAccess(authz.New(st.Forms)), h.GetFormAnalytics)
+	r := send(t, app, "GET", "/forms/"+f.ID.String()+"/analytics", "")
+	expectStatus(t, r, fiber.StatusOK)
+	var got model.FormAnalytics
+	r.decode(t, &got)
+	return got
+}
+
+func TestGetFormAnalytics(t *testing.T) {
+	st := store.NewMemory().Stores()
+	owner := uuid.New()
+	f := publishedForm(t, st, owner,
+		model.Question{Type: model.QuestionTypeShortText, Title: "Name"},
+		model.Question{Type: model.QuestionTypeMultiSelect, Title: "Diet", Options: []model.Option{{Label: "Vegan"}, {Label: "Halal"}}},
+		model.Question{Type: model.QuestionTypeNumber, Title: "Age"},
+	)
+	submitAnswers(t, st, f, map[int]string{0: `"Ada Lovelace"`, 1: `["Vegan","Halal"]`, 2: `30`})
+	submitAnswers(t, st, f, map[int]string{0: `"Grace"`, 1: `["Vegan"]`, 2: `41`})
+	submitAnswers(t, st, f, map[int]string{0: `"  "`, 1: `[]`, 2: `35`})
+
+	// two responses come in after a team question is added; answers from
+	// before it do not count as skipping it
+	f.Questions = append(f.Questions, model.Question{Type: model.QuestionTypeShortText, Title: "Team", Position: 3})
+	if err := st.Forms.Update(t.Context(), owner, f); err != nil {
+		t.Fatal(err)
+	}
+	f, err := st.Forms.Get(t.Context(), f.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	submitAnswers(t, st, f, map[int]string{0: `"Edsger"`, 3: `"Compilers"`})
+	submitAnswers(t, st, f, map[int]string{0: `"Barbara"`})
+
+	got := formAnalytics(t, st, owner, f)
+	if got.Responses != 5 || len(got.Questions) != 4 {
+		t.Fatalf("%d responses, %d questions; want 5, 4", got.Responses, len(got.Questions))
+	}
+	name, diet, age, team := got.Questions[0], got.Questions[1], got.Questions[2], got.Questions[3]
+
+	tests := []struct {
+		name                     string
+		q                        model.QuestionAnalytics
+		shown, answered, skipped int
+		skipRate                 float64
+	}{
+		{"blank text is skipped", name, 5, 4, 1, 0.2},
+		{"empty selection is skipped", diet, 5, 2, 3, 0.6},
+		{"unanswered number is skipped", age, 5, 3, 2, 0.4},
+		{"added question counts later responses only", team, 2, 1, 1, 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.q
+			if q.Shown != tt.shown || q.Answered != tt.answered || q.Skipped != tt.skipped || q.SkipRate != tt.skipRate {
+				t.Fatalf("%s: shown %d, answered %d, skipped %d, rate %v; want %d, %d, %d, %v",
+					q.Title, q.Shown, q.Answered, q.Skipped, q.SkipRate, tt.shown, tt.answered, tt.skipped, tt.skipRate)
+			}
+		})
+	}
+
+	// percentages are of the respondents who answered, so multi-select ones
+	// add up to more than 100
+	if want := []float64{100, 50}; len(diet.Options) != 2 || diet.Options[0].Percentage != want[0] || diet.Options[1].Percentage != want[1] {
+		t.Fatalf("diet options %+v, want percentages %v", diet.Options, want)
+	}
+	if n := age.Numeric; n == nil || n.Count != 3 || n.Min != 30 || n.Max != 41 || n.Median != 35 || n.Mean != 106.0/3 {
+		t.Fatalf("age stats %+v", age.Numeric)
+	}
+	if got := name.Text.AverageWords; got != 1.25 {
+		t.Fatalf("name average words %v, want 1.25", got)
+	}
+	terms := make([]string, len(name.Text.TopTerms))
+	for i, tc := range name.Text.TopTerms {
+		terms[i] = tc.Term
+	}
+	slices.Sort(terms)
+	if want := []string{"ada", "barbara", "edsger", "grace", "lovelace"}; !slices.Equal(terms, want) {
+		t.Fatalf("name terms %q, want %q", terms, want)
+	}
+}
+
+func TestGetFormAnalyticsHiddenQuestions(t *testing.T) {
+	st := store.NewMemory().Stores()
+	owner := uuid.New()
+	diet := uuid.New()
+	f := publishedForm(t, st, owner,
+		model.Question{ID: diet, Type: model.QuestionTypeSingleSelect, Title: "Diet", Options: []model.Option{{Label: "Vegan"}, {Label: "Other"}}},
+		model.Question{Type: model.QuestionTypeShortText, Title: "Which diet?", Visibility: &model.VisibilityRule{
+			Conditions: []model.Condition{{QuestionID: diet, Operator: model.ConditionEquals, Value: json.RawMessage(`"Other"`)}},
+		}},
+	)
+	submitAnswers(t, st, f, map[int]string{0: `"Vegan"`})
+	submitAnswers(t, st, f, map[int]string{0: `"Vegan"`})
+	submitAnswers(t, st, f, map[int]string{0: `"Other"`, 1: `"Pescatarian"`})
+	submitAnswers(t, st, f, map[int]string{0: `"Other"`})
+
+	// only the two who picked Other were asked which diet
+	which := formAnalytics(t, st, owner, f).Questions[1]
+	if which.Shown != 2 || which.Answered != 1 || which.Skipped != 1 || which.SkipRate != 0.5 {
+		t.Fatalf("shown %d, answered %d, skipped %d, rate %v; want 2, 1, 1, 0.5", which.Shown, which.Answered, which.Skipped, which.SkipRate)
+	}
+}
+
+func TestGetFormAnalyticsSkippedPages(t *testing.T) {
+	st := store.NewMemory().Stores()
+	owner := uuid.New()
+	attending, about, food := uuid.New(), uuid.New(), uuid.New()
+	f := publishedForm(t, st, owner,
+		model.Question{ID: attending, Type: model.QuestionTypeSingleSelect, Title: "Coming?", Options: []model.Option{{Label: "Yes"}, {Label: "No"}}},
+		model.Question{Type: model.QuestionTypeShortText, Title: "Diet"},
+	)
+	f.Questions[0].SectionID, f.Questions[1].SectionID = &about, &food
+	f.Sections = []model.Section{
+		{ID: about, Title: "About you", Jumps: []model.PageJump{
+			{When: model.Condition{QuestionID: attending, Operator: model.ConditionEquals, Value: json.RawMessage(`"No"`)}},
+		}},
+		{ID: food, Title: "Food"},
+	}
+	if err := st.Forms.Update(t.Context(), owner, f); err != nil {
+		t.Fatal(err)
+	}
+	submitAnswers(t, st, f, map[int]string{0: `"No"`})
+	submitAnswers(t, st, f, map[int]string{0: `"No"`})
+	submitAnswers(t, st, f, map[int]string{0: `"Yes"`, 1: `"Vegan"`})
+	submitAnswers(t, st, f, map[int]string{0: `"Yes"`})
+
+	// those not coming ended the form before the food page
+	diet := formAnalytics(t, st, owner, f).Questions[1]
+	if diet.Shown != 2 || diet.Answered != 1 || diet.Skipped != 1 || diet.SkipRate != 0.5 {
+		t.Fatalf("shown %d, answered %d, skipped %d, rate %v; want 2, 1, 1, 0.5", diet.Shown, diet.Answered, diet.Skipped, diet.SkipRate)
+	}
+}
+
+func TestGetFormFunnel(t *testing.T) {
+	st := store.NewMemory().Stores()
+	owner := uuid.New()
+	f := lunchForm(t, st, owner)
+	f.AllowMultipleSubmissions = true
+	if err := st.Forms.UpdateSettings(t.Context(), owner, f); err != nil {
+		t.Fatal(err)
+	}
+	h := NewAnalyticsHandler(nil, st.Forms, st.Analytics)
+
+	app := submitApp(st)
+	app.Post("/forms/:id/events", h.RecordFormEvent)
+	app.Get("/forms/:id/funnel", signedIn(owner, "user"), middlewares.FormAccess(authz.New(st.Forms)), h.GetFormFunnel)
+
+	base := "/forms/" + f.ID.String()
+	answers := `{"answers":[{"question_id":"` + f.Questions[0].ID.String() + `","value":"Ada"}]}`
+
+	// one respondent views, starts and submits; another only looks
+	r := send(t, app, "POST", base+"/events", `{"kind":"view"}`)
+	expectStatus(t, r, fiber.StatusNoContent)
+	ada := r.cookie(deviceCookie)
+	expectStatus(t, send(t, app, "POST", base+"/events", `{"kind":"start"}`, "Cookie", ada), fiber.StatusNoContent)
+	expectStatus(t, send(t, app, "POST", base+"/submit", answers, "Cookie", ada), fiber.StatusCreated)
+	expectStatus(t, send(t, app, "POST", base+"/events", `{"kind":"view"}`), fiber.StatusNoContent)
+
+	// submissions from devices that sent no events, as with tracking
+	// blocked, are not completions of any session
+	for range 2 {
+		expectStatus(t, send(t, app, "POST", base+"/submit", answers), fiber.StatusCreated)
+	}
+
+	r = send(t, app, "GET", base+"/funnel", "")
+	expectStatus(t, r, fiber.StatusOK)
+	var got model.FormFunnel
+	r.decode(t, &got)
+	if got.Views != 2 || got.Starts != 1 || got.Completions != 1 {
+		t.Fatalf("views %d, starts %d, completions %d; want 2, 1, 1", got.Views, got.Starts, got.Completions)
+	}
+	if got.CompletionRate != 1 || got.ConversionRate != 0.5 {
+		t.Fatalf("completion rate %v, conversion rate %v", got.CompletionRate, got.ConversionRate)
+	}
+}
+
+// seriesApp routes the response series endpoints for a caller signed in as
+// userID.
+func seriesApp(st store.Stores, userID uuid.UUID) *fiber.App {
+	h := NewAnalyticsHandler(nil, st.Forms, st.Analytics)
+	app := fiber.New()
+	app.Use(signedIn(userID, "user"))
+	app.Get("/dashboard/responses", h.GetResponseSeries)
+	app.Get("/forms/:id/analytics/responses", middlewares.FormAccess(authz.New(st.Forms)), h.GetFormResponseSeries)
+	return app
+}
+
+func TestGetResponseSeries(t *testing.T) {
+	// this first request also starts the server clock of fasthttp, which
+	// must run outside the bubble
+	st := store.NewMemory().Stores()
+	owner := uuid.New()
+	for _, query := range []string{"?interval=minute", "?tz=Mars/Olympus", "?from=2026-03-02&to=2026-03-01", "?interval=hour&from=2020-01-01"} {
+		r := send(t, seriesApp(st, owner), "GET", "/dashboard/responses"+query, "")
+		expectStatus(t, r, fiber.StatusBadRequest)
+	}
+
+	synctest.Test(t, func(t *testing.T) {
+		// the bubble's clock starts at midnight UTC on 2000-01-01
+		st := store.NewMemory().Stores()
+		owner := uuid.New()
+		lunch := publishedForm(t, st, owner, model.Question{Type: model.QuestionTypeShortText, Title: "Name"})
+		dinner := publishedForm(t, st, owner, model.Question{Type: model.QuestionTypeShortText, Title: "Name"})
+		other := publishedForm(t, st, uuid.New(), model.Question{Type: model.QuestionTypeShortText, Title: "Name"})
+
+		submitAnswers(t, st, lunch, map[int]string{0: `"Ada"`})
+		time.Sleep(2 * 24 * time.Hour)
+		submitAnswers(t, st, lunch, map[int]string{0: `"Grace"`})
+		submitAnswers(t, st, dinner, map[int]string{0: `"Edsger"`})
+		submitAnswers(t, st, other, map[int]string{0: `"Barbara"`})
+		time.Sleep(24 * time.Hour)
+		submitAnswers(t, st, lunch, map[int]string{0: `"Alan"`})
+
+		series := func(path string) model.ResponseSeries {
+			t.Helper()
+			r := send(t, seriesApp(st, owner), "GET", path, "")
+			expectStatus(t, r, fiber.StatusOK)
+			var got model.ResponseSeries
+			r.decode(t, &got)
+			return got
+		}
+		counts := func(s model.ResponseSeries) []int {
+			var n []int
+			for _, p := range s.Points {
+				n = append(n, p.Count)
+			}
+			return n
+		}
+
+		// every form of the caller's, empty days included
+		got := series("/dashboard/responses?from=2000-01-01&to=2000-01-04")
+		if want := []int{1, 0, 2}; !slices.Equal(counts(got), want) || got.Total != 3 {
+			t.Fatalf("dashboard counts %v total %d, want %v", counts(got), got.Total, want)
+		}
+
+		// days start at midnight in the requested zone, five hours after
+		// midnight UTC in Bogota
+		got = series("/forms/" + lunch.ID.String() + "/analytics/responses?tz=America/Bogota&from=1999-12-31&to=2000-01-04")
+		if want := []int{1, 0, 1, 1}; !slices.Equal(counts(got), want) || got.Timezone != "America/Bogota" {
+			t.Fatalf("lunch counts %v in %s, want %v", counts(got), got.Timezone, want)
+		}
+
+		// the previous period is the one of equal length right before
+		got = series("/forms/" + lunch.ID.String() + "/analytics/responses?from=2000-01-03&to=2000-01-05")
+		if got.Total != 2 || got.PreviousTotal != 1 || got.Delta != 1 || got.DeltaPercent == nil || *got.DeltaPercent != 100 {
+			t.Fatalf("total %d, previous %d, delta %d; want 2, 1, 1 (+100%%)", got.Total, got.PreviousTotal, got.Delta)
+		}
+		got = series("/forms/" + dinner.ID.String() + "/analytics/responses?from=2000-01-03&to=2000-01-05")
+		if got.Total != 1 || got.PreviousTotal != 0 || got.DeltaPercent != nil {
+			t.Fatalf("dinner total %d, previous %d, delta %v; want 1, 0 and no percentage", got.Total, got.PreviousTotal, got.DeltaPercent)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package user
+
+import (
+	"craft/internal/model"
+	"craft/internal/model/payload"
+	"craft/internal/notify"
+	"craft/internal/store"
+	"craft/internal/validation"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/supabase-community/supabase-go"
+)
+
+type AutoresponderHandler struct {
+	supabase       *supabase.Client
+	Autoresponders store.AutoresponderStore
+}
+
+func NewAutoresponderHandler(supabase *supabase.Client, autoresponders store.AutoresponderStore) *AutoresponderHandler {
+	return &AutoresponderHandler{
+		supabase:       supabase,
+		Autoresponders: autoresponders,
+	}
+}
+
+func (h *AutoresponderHandler) GetAutoresponder(c fiber.Ctx) error {
+	f, ok := c.Locals("form").(*model.Form)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Form not resolved",
+		})
+	}
+
+	ar, err := h.Autoresponders.Get(c.Context(), f.ID)
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Autoresponder not set up",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch autoresponder",
+		})
+	}
+
+	return c.JSON(ar)
+}
+
+// PutAutoresponder sets up or replaces the form's autoresponder. Its
+// templates may only reference the form's current questions.
+func (h *AutoresponderHandler) PutAutoresponder(c fiber.Ctx) error {
+	f, ok := c.Locals("form").(*model.Form)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Form not resolved",
+		})
+	}
+
+	var req payload.PutAutoresponderRequest
+	if err := c.Bind().JSON(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	fieldErrs := validation.ValidateAutoresponder(f, req)
+	for field, text := range map[string]string{"subject": req.Subject, "body": req.Body} {
+		if unknown := notify.UnknownPlaceholders(f, text); len(unknown) > 0 && fieldErrs[field] == "" {
+			if fieldErrs == nil {
+				fieldErrs = validation.FieldErrors{}
+			}
+			fieldErrs[field] = "unknown placeholders: " + strings.Join(unknown, ", ")
+		}
+	}
+	if fieldErrs != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":  "Invalid autoresponder",
+			"fields": fieldErrs,
+		})
+	}
+
+	ar := model.Autoresponder{
+		FormID:     f.ID,
+		Enabled:    req.Enabled,
+		QuestionID: &req.QuestionID,
+		Subject:    req.Subject,
+		Body:       req.Body,
+	}
+	err := h.Autoresponders.Put(c.Context(), &ar)
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Form not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to save autoresponder",
+			"detail": err.Error(),
+		})
+	}
+
+	return c.JSON(ar)
+}
+
+func (h *AutoresponderHandler) DeleteAutoresponder(c fiber.Ctx) error {
+	f, ok := c.Locals("form").(*model.Form)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Form not resolved",
+		})
+	}
+
+	err := h.Autoresponders.Delete(c.Context(), f.ID)
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Autoresponder not set up",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete autoresponder",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Autoresponder deleted successfully",
+	})
+}
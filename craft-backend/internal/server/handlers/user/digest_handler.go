@@ -0,0 +1,106 @@
+package user
+
+import (
+	"craft/internal/model"
+	"craft/internal/model/payload"
+	"craft/internal/store"
+	"craft/pkg"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/supabase-community/supabase-go"
+)
+
+type DigestHandler struct {
+	supabase *supabase.Client
+	Digests  store.DigestStore
+}
+
+func NewDigestHandler(supabase *supabase.Client, digests store.DigestStore) *DigestHandler {
+	return &DigestHandler{
+		supabase: supabase,
+		Digests:  digests,
+	}
+}
+
+// GetDigestPreference returns how often the caller gets a digest of new
+// responses. Users who never chose get none.
+func (h *DigestHandler) GetDigestPreference(c fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User not authenticated",
+		})
+	}
+
+	p, err := h.Digests.Preference(c.Context(), userID)
+	if errors.Is(err, store.ErrNotFound) {
+		p = &model.DigestPreference{UserID: userID, Frequency: model.DigestOff, Timezone: "UTC"}
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch digest preference",
+		})
+	}
+
+	return c.JSON(p)
+}
+
+// UpdateDigestPreference sets how often the caller gets a digest. The
+// timezone is kept when omitted.
+func (h *DigestHandler) UpdateDigestPreference(c fiber.Ctx) error {
+	ctx := c.Context()
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User not authenticated",
+		})
+	}
+
+	var req payload.UpdateDigestPreferenceRequest
+	if err := c.Bind().JSON(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if err := pkg.Validator.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Frequency must be off, daily or weekly",
+		})
+	}
+
+	p := model.DigestPreference{UserID: userID, Frequency: req.Frequency, Timezone: req.Timezone}
+	if p.Timezone == "" {
+		p.Timezone = "UTC"
+		current, err := h.Digests.Preference(ctx, userID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to fetch digest preference",
+			})
+		}
+		if current != nil {
+			p.Timezone = current.Timezone
+		}
+	}
+	if loc, err := time.LoadLocation(p.Timezone); err != nil || loc == time.Local {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Timezone must be an IANA time zone",
+		})
+	}
+
+	err := h.Digests.SetPreference(ctx, &p)
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to update digest preference",
+			"detail": err.Error(),
+		})
+	}
+
+	return c.JSON(p)
+}
@@ -0,0 +1,70 @@
+package user
+
+import (
+	"craft/internal/store"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// formETag formats a form version as a strong ETag.
+func formETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ifMatchVersion reads the form version from the If-Match header. present is
+// false when the header is missing; "*" yields version 0, which matches any.
+func ifMatchVersion(c fiber.Ctx) (version int, present bool, err error) {
+	raw := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if raw == "" {
+		return 0, false, nil
+	}
+	if raw == "*" {
+		return 0, true, nil
+	}
+
+	raw = strings.TrimPrefix(raw, "W/")
+	version, err = strconv.Atoi(strings.Trim(raw, `"`))
+	if err != nil || version < 1 {
+		return 0, true, errors.New("invalid If-Match header")
+	}
+	return version, true, nil
+}
+
+// requireIfMatch is ifMatchVersion for endpoints that refuse blind writes. It
+// writes the error response itself and reports ok=false when it did.
+func requireIfMatch(c fiber.Ctx) (version int, ok bool, err error) {
+	version, present, err := ifMatchVersion(c)
+	if err != nil {
+		return 0, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid If-Match header, expected the form ETag",
+		})
+	}
+	if !present {
+		return 0, false, c.Status(fiber.StatusPreconditionRequired).JSON(fiber.Map{
+			"error": "If-Match header with the form ETag is required",
+		})
+	}
+	return version, true, nil
+}
+
+// versionConflict answers a write that lost a race with 412 and the form as
+// it is now, so the client can merge its changes and retry.
+func versionConflict(c fiber.Ctx, forms store.FormStore, formID, userID uuid.UUID) error {
+	current, err := forms.GetByOwner(c.Context(), formID, userID)
+	if err != nil {
+		return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+			"error": "Form was modified by someone else",
+		})
+	}
+
+	c.Set(fiber.HeaderETag, formETag(current.Version))
+	return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+		"error":           "Form was modified by someone else",
+		"current_version": current.Version,
+		"form":            current,
+	})
+}
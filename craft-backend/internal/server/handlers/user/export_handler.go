@@ -0,0 +1,265 @@
+package user
+
+import (
+	"bufio"
+	"context"
+	"craft/internal/export"
+	"craft/internal/model"
+	"craft/internal/store"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/supabase-community/supabase-go"
+)
+
+type ExportHandler struct {
+	supabase    *supabase.Client
+	Forms       store.FormStore
+	Submissions store.SubmissionStore
+	Revisions   store.RevisionStore
+	Uploads     store.UploadStore
+}
+
+func NewExportHandler(supabase *supabase.Client, forms store.FormStore, submissions store.SubmissionStore, revisions store.RevisionStore, uploadStore store.UploadStore) *ExportHandler {
+	return &ExportHandler{
+		supabase:    supabase,
+		Forms:       forms,
+		Submissions: submissions,
+		Revisions:   revisions,
+		Uploads:     uploadStore,
+	}
+}
+
+// withArchived returns a copy of f that also has the questions removed from
+// it, whose answers are exported after the others.
+func (h *ExportHandler) withArchived(ctx context.Context, f *model.Form) (*model.Form, error) {
+	archived, err := h.Forms.ArchivedQuestions(ctx, f.ID)
+	if err != nil {
+		return nil, err
+	}
+	out := *f
+	out.Questions = append(slices.Clone(f.Questions), archived...)
+	return &out, nil
+}
+
+// withFiles attaches their files to the file_upload answers of each
+// submission before passing it to write, so exports can name the files.
+func (h *ExportHandler) withFiles(ctx context.Context, f *model.Form, write func(*model.SubmissionWithAnswers) error) func(*model.SubmissionWithAnswers) error {
+	if !slices.ContainsFunc(f.Questions, func(q model.Question) bool { return q.Type == model.QuestionTypeFileUpload }) {
+		return write
+	}
+	return func(sub *model.SubmissionWithAnswers) error {
+		subs := []model.SubmissionWithAnswers{*sub}
+		if err := attachFiles(ctx, h.Uploads, nil, subs); err != nil {
+			return err
+		}
+		return write(&subs[0])
+	}
+}
+
+// exportFilename turns the form title into a safe attachment name.
+func exportFilename(f *model.Form, ext string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_':
+			return unicode.ToLower(r)
+		case unicode.IsSpace(r):
+			return '_'
+		}
+		return -1
+	}, f.Title)
+	if name == "" {
+		name = "form"
+	}
+	return name + "_responses" + ext
+}
+
+// ExportCSV streams the submissions of the form resolved by the FormAccess
+// middleware as CSV. It accepts the filters of GetFormSubmissions.
+func (h *ExportHandler) ExportCSV(c fiber.Ctx) error {
+	f, ok := c.Locals("form").(*model.Form)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Form not resolved",
+		})
+	}
+
+	query, err := submissionQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	form, err := h.withArchived(c.Context(), f)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch form",
+		})
+	}
+
+	c.Attachment(exportFilename(f, ".csv"))
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		err := streamScan(w, func(ctx context.Context, w io.Writer) error {
+			cw := export.NewCSVWriter(w, form)
+			if err := cw.WriteHeader(); err != nil {
+				return err
+			}
+			if err := h.Submissions.Each(ctx, f.ID, query, h.withFiles(ctx, form, cw.Write)); err != nil {
+				return err
+			}
+			return cw.Flush()
+		})
+		if err != nil {
+			log.Printf("export: csv for form %s: %v", f.ID, err)
+		}
+	})
+}
+
+// streamScan runs scan, which writes to w, for a response streamed after the
+// handler returns, when the request context is no longer valid. The context
+// scan gets is cancelled instead once a write to w fails, as writes do when
+// the client has gone away, so the database scan stops with the download.
+func streamScan(w io.Writer, scan func(ctx context.Context, w io.Writer) error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	return scan(ctx, cancelOnError{w: w, cancel: cancel})
+}
+
+// cancelOnError is a writer that calls cancel when a write fails.
+type cancelOnError struct {
+	w      io.Writer
+	cancel context.CancelFunc
+}
+
+func (c cancelOnError) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	if err != nil {
+		c.cancel()
+	}
+	return n, err
+}
+
+// ExportNDJSON streams the submissions of the form resolved by the FormAccess
+// middleware as JSON lines, oldest first. Every line carries a cursor; passing
+// the last one as ?since= fetches only newer submissions. It accepts the
+// filters of GetFormSubmissions.
+//
+// Submissions from the last store.SubmissionSettleTime are left out until a
+// later poll: one still being saved can be stamped before another that is
+// already visible, and a cursor past the latter would otherwise skip it.
+// Pollers therefore see a submission a few seconds after it is made, but see
+// every one exactly once.
+func (h *ExportHandler) ExportNDJSON(c fiber.Ctx) error {
+	f, ok := c.Locals("form").(*model.Form)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Form not resolved",
+		})
+	}
+
+	query, err := submissionQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if raw := c.Query("since"); raw != "" {
+		since, err := store.ParseSubmissionCursor(raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid since cursor",
+			})
+		}
+		query.Since = &since
+	}
+	query.Settled = true
+
+	c.Set(fiber.HeaderContentType, "application/x-ndjson")
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		err := streamScan(w, func(ctx context.Context, w io.Writer) error {
+			return h.Submissions.Each(ctx, f.ID, query, export.NewNDJSONWriter(w).Write)
+		})
+		if err != nil {
+			log.Printf("export: ndjson for form %s: %v", f.ID, err)
+		}
+	})
+}
+
+// ExportXLSX returns the submissions of the form resolved by the FormAccess
+// middleware as an Excel workbook with a sheet per form revision. It accepts
+// the filters of GetFormSubmissions.
+func (h *ExportHandler) ExportXLSX(c fiber.Ctx) error {
+	ctx := c.Context()
+	f, ok := c.Locals("form").(*model.Form)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Form not resolved",
+		})
+	}
+
+	query, err := submissionQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	revs, err := h.Revisions.List(ctx, f.ID, f.OwnerID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch revisions",
+		})
+	}
+	// only revisions with responses get a sheet, so only those need questions
+	answered := make([]model.FormRevision, 0, len(revs))
+	for _, r := range revs {
+		if r.Responses == 0 {
+			continue
+		}
+		rev, err := h.Revisions.Get(ctx, f.ID, f.OwnerID, r.Revision)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to fetch revisions",
+			})
+		}
+		answered = append(answered, *rev)
+	}
+
+	form, err := h.withArchived(ctx, f)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch form",
+		})
+	}
+
+	// the workbook is assembled before responding so failures still get a
+	// proper status; excelize spills large sheets to temporary files
+	xw, err := export.NewXLSXWriter(form, answered)
+	if err == nil {
+		err = h.Submissions.Each(ctx, f.ID, query, h.withFiles(ctx, form, xw.Write))
+	}
+	if err != nil {
+		if xw != nil {
+			xw.Close()
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to export submissions",
+			"detail": err.Error(),
+		})
+	}
+
+	c.Attachment(exportFilename(f, ".xlsx"))
+	c.Set(fiber.HeaderContentType, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		defer xw.Close()
+		if _, err := xw.WriteTo(w); err != nil {
+			log.Printf("export: xlsx for form %s: %v", f.ID, err)
+		}
+	})
+}
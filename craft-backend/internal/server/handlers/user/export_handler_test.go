@@ -0,0 +1,205 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"craft/internal/authz"
+	"craft/internal/export"
+	"craft/internal/model"
+	"craft/internal/server/middlewares"
+	"craft/internal/store"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// exportApp routes the export endpoints for a caller signed in as userID.
+func exportApp(st store.Stores, userID uuid.UUID) *fiber.App {
+	h := NewExportHandler(nil, st.Forms, st.Submissions, st.Revisions, st.Uploads)
+	formAccess := middlewares.FormAccess(authz.New(st.Forms))
+
+	app := fiber.New()
+	app.Use(signedIn(userID, "user"))
+	app.Get("/forms/:id/submissions/export.csv", formAccess, h.ExportCSV)
+	app.Get("/forms/:id/submissions/export.xlsx", formAccess, h.ExportXLSX)
+	app.Get("/forms/:id/submissions/export.ndjson", formAccess, h.ExportNDJSON)
+	return app
+}
+
+// submitAnswers stores a submission of f with the given answers, keyed by
+// question index.
+func submitAnswers(t *testing.T, st store.Stores, f *model.Form, values map[int]string) model.Submission {
+	t.Helper()
+	sub := model.Submission{FormID: f.ID}
+	var answers []model.Answer
+	for i, v := range values {
+		answers = append(answers, model.Answer{QuestionID: f.Questions[i].ID, Value: json.RawMessage(v)})
+	}
+	if err := st.Submissions.Create(t.Context(), &sub, answers, nil); err != nil {
+		t.Fatal(err)
+	}
+	return sub
+}
+
+func TestExportCSV(t *testing.T) {
+	st := store.NewMemory().Stores()
+	owner := uuid.New()
+	f := publishedForm(t, st, owner,
+		model.Question{Type: model.QuestionTypeShortText, Title: "Name"},
+		model.Question{Type: model.QuestionTypeShortText, Title: "Nickname"},
+		model.Question{Type: model.QuestionTypeFileUpload, Title: "CV"},
+	)
+
+	upload := model.Upload{FormID: f.ID, QuestionID: f.Questions[2].ID, Filename: "cv.pdf", ContentType: "application/pdf"}
+	if err := st.Uploads.Create(t.Context(), &upload); err != nil {
+		t.Fatal(err)
+	}
+	sub := submitAnswers(t, st, f, map[int]string{0: `"Ada"`, 1: `"Countess"`, 2: `["` + upload.ID.String() + `"]`})
+	if _, err := st.Uploads.Attach(t.Context(), sub.ID, []uuid.UUID{upload.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the nickname is dropped after Ada answered it
+	f.Questions = slices.Delete(f.Questions, 1, 2)
+	if err := st.Forms.Update(t.Context(), owner, f); err != nil {
+		t.Fatal(err)
+	}
+	submitAnswers(t, st, f, map[int]string{0: `"Grace"`})
+
+	r := send(t, exportApp(st, owner), "GET", "/forms/"+f.ID.String()+"/submissions/export.csv", "")
+	expectStatus(t, r, fiber.StatusOK)
+	records, err := csv.NewReader(bytes.NewReader(r.Body)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("%d records: %q", len(records), records)
+	}
+	if want := []string{"Name", "CV", "Nickname (removed)"}; !slices.Equal(records[0][3:], want) {
+		t.Fatalf("header %q, want the question columns %q", records[0], want)
+	}
+	rows := map[string][]string{records[1][3]: records[1][4:], records[2][3]: records[2][4:]}
+	if got := rows["Ada"]; !slices.Equal(got, []string{"cv.pdf", "Countess"}) {
+		t.Fatalf("Ada's row %q", got)
+	}
+	if got := rows["Grace"]; !slices.Equal(got, []string{"", ""}) {
+		t.Fatalf("Grace's row %q", got)
+	}
+
+	r = send(t, exportApp(st, uuid.New()), "GET", "/forms/"+f.ID.String()+"/submissions/export.csv", "")
+	expectStatus(t, r, fiber.StatusNotFound)
+}
+
+// ndjsonRecords decodes an NDJSON export.
+func ndjsonRecords(t *testing.T, body []byte) []export.NDJSONRecord {
+	t.Helper()
+	var records []export.NDJSONRecord
+	dec := json.NewDecoder(bytes.NewReader(body))
+	for dec.More() {
+		var rec export.NDJSONRecord
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+func TestExportNDJSON(t *testing.T) {
+	// this first request also starts the server clock of fasthttp, which
+	// must run outside the bubble
+	st := store.NewMemory().Stores()
+	f := publishedForm(t, st, uuid.New(), model.Question{Type: model.QuestionTypeShortText, Title: "Name"})
+	r := send(t, exportApp(st, f.OwnerID), "GET", "/forms/"+f.ID.String()+"/submissions/export.ndjson?since=nope", "")
+	expectStatus(t, r, fiber.StatusBadRequest)
+
+	synctest.Test(t, func(t *testing.T) {
+		st := store.NewMemory().Stores()
+		owner := uuid.New()
+		f := publishedForm(t, st, owner, model.Question{Type: model.QuestionTypeShortText, Title: "Name"})
+		app := exportApp(st, owner)
+		path := "/forms/" + f.ID.String() + "/submissions/export.ndjson"
+
+		// poll fetches the records after cursor and returns the new cursor
+		var seen []string
+		poll := func(cursor string) string {
+			t.Helper()
+			url := path
+			if cursor != "" {
+				url += "?since=" + cursor
+			}
+			r := send(t, app, "GET", url, "")
+			expectStatus(t, r, fiber.StatusOK)
+			for _, rec := range ndjsonRecords(t, r.Body) {
+				var name string
+				json.Unmarshal(rec.Answers[f.Questions[0].ID.String()].Value, &name)
+				seen = append(seen, name)
+				cursor = rec.Cursor
+			}
+			return cursor
+		}
+
+		submitAnswers(t, st, f, map[int]string{0: `"Ada"`})
+		time.Sleep(time.Second)
+		submitAnswers(t, st, f, map[int]string{0: `"Grace"`})
+
+		// neither has settled yet
+		cursor := poll("")
+		if len(seen) != 0 {
+			t.Fatalf("unsettled submissions exported: %q", seen)
+		}
+
+		time.Sleep(store.SubmissionSettleTime)
+		cursor = poll(cursor)
+		if want := []string{"Ada"}; !slices.Equal(seen, want) {
+			t.Fatalf("exported %q, want %q", seen, want)
+		}
+
+		submitAnswers(t, st, f, map[int]string{0: `"Edsger"`})
+		time.Sleep(store.SubmissionSettleTime + time.Second)
+		cursor = poll(cursor)
+		poll(cursor)
+		if want := []string{"Ada", "Grace", "Edsger"}; !slices.Equal(seen, want) {
+			t.Fatalf("exported %q, want each submission once: %q", seen, want)
+		}
+	})
+
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("connection reset") }
+
+func TestStreamScanCancelsOnWriteFailure(t *testing.T) {
+	st := store.NewMemory().Stores()
+	f := publishedForm(t, st, uuid.New(), model.Question{Type: model.QuestionTypeShortText, Title: "Name"})
+	for range 3 {
+		submitAnswers(t, st, f, map[int]string{0: `"Ada"`})
+	}
+
+	// the write errors are ignored, as a buffered writer would hold them
+	// back, so only the cancelled context can stop the scan
+	written := 0
+	err := streamScan(failingWriter{}, func(ctx context.Context, w io.Writer) error {
+		return st.Submissions.Each(ctx, f.ID, store.SubmissionQuery{}, func(*model.SubmissionWithAnswers) error {
+			written++
+			w.Write([]byte("row\n"))
+			return nil
+		})
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want the scan cancelled", err)
+	}
+	if written != 1 {
+		t.Fatalf("%d rows written after the first failed write", written-1)
+	}
+}
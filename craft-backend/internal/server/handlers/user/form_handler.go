@@ -1,9 +1,14 @@
 package user
 
 import (
-	"craft/internal/db"
 	"craft/internal/model"
+	"craft/internal/model/payload"
+	"craft/internal/store"
+	"craft/internal/validation"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
@@ -12,13 +17,13 @@ import (
 
 type FormHandler struct {
 	supabase *supabase.Client
-	DB       *db.Database
+	Forms    store.FormStore
 }
 
-func NewFormHandler(supabase *supabase.Client, DB *db.Database) *FormHandler {
+func NewFormHandler(supabase *supabase.Client, forms store.FormStore) *FormHandler {
 	return &FormHandler{
 		supabase: supabase,
-		DB:       DB,
+		Forms:    forms,
 	}
 }
 
@@ -59,17 +64,7 @@ func (h *FormHandler) CreateForm(c fiber.Ctx) error {
 		req.Title = "Untitled Form"
 	}
 
-	var f model.Form
-	err := h.DB.Pool.QueryRow(ctx, `
-		INSERT INTO forms (owner_id, title, description, status, is_public, allow_multiple_submissions)
-		VALUES ($1, $2, $3, 'draft', false, false)
-		RETURNING id, owner_id, title, description, status, is_public, allow_multiple_submissions, close_date, thank_you_message, redirect_url, created_at, updated_at
-	`, userID, req.Title, req.Description).Scan(
-		&f.ID, &f.OwnerID, &f.Title, &f.Description, &f.Status,
-		&f.IsPublic, &f.AllowMultipleSubmissions, &f.CloseDate,
-		&f.ThankYouMessage, &f.RedirectURL, &f.CreatedAt, &f.UpdatedAt,
-	)
-
+	f, err := h.Forms.Create(ctx, userID, req.Title, req.Description)
 	if err != nil {
 		fmt.Printf("ERROR: Failed to create form for user %s: %v\n", userID, err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -81,26 +76,31 @@ func (h *FormHandler) CreateForm(c fiber.Ctx) error {
 	return c.Status(fiber.StatusCreated).JSON(f)
 }
 
+// GetForm returns the form resolved by the FormAccess middleware, so admins
+// can read any form.
 func (h *FormHandler) GetForm(c fiber.Ctx) error {
+	f, ok := c.Locals("form").(*model.Form)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Form not resolved",
+		})
+	}
+
+	c.Set(fiber.HeaderETag, formETag(f.Version))
+	return c.JSON(f)
+}
+
+// UpdateForm replaces the definition of a form and returns it as saved.
+func (h *FormHandler) UpdateForm(c fiber.Ctx) error {
 	ctx := c.Context()
 	userIDRaw := c.Locals("user_id")
-	var userID uuid.UUID
-	switch v := userIDRaw.(type) {
-	case uuid.UUID:
-		userID = v
-	case string:
-		var err error
-		userID, err = uuid.Parse(v)
-		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid user ID format",
-			})
-		}
-	default:
+	if userIDRaw == nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "User ID not found in context",
+			"error": "User not authenticated",
 		})
 	}
+
+	userID := userIDRaw.(uuid.UUID)
 	formIDStr := c.Params("id")
 	formID, err := uuid.Parse(formIDStr)
 	if err != nil {
@@ -109,79 +109,56 @@ func (h *FormHandler) GetForm(c fiber.Ctx) error {
 		})
 	}
 
-	var f model.Form
-	err = h.DB.Pool.QueryRow(ctx, `
-		SELECT id, owner_id, title, description, status, is_public, allow_multiple_submissions, close_date, thank_you_message, redirect_url, created_at, updated_at
-		FROM forms
-		WHERE id = $1 AND owner_id = $2
-	`, formID, userID).Scan(
-		&f.ID, &f.OwnerID, &f.Title, &f.Description, &f.Status,
-		&f.IsPublic, &f.AllowMultipleSubmissions, &f.CloseDate,
-		&f.ThankYouMessage, &f.RedirectURL, &f.CreatedAt, &f.UpdatedAt,
-	)
+	var req model.Form
+	if err := c.Bind().JSON(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
 
-	if err != nil {
+	if fieldErrs := validation.ValidateQuestions(req.Questions); fieldErrs != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":  "Invalid question settings",
+			"fields": fieldErrs,
+		})
+	}
+	if fieldErrs := validation.ValidateSections(req.Sections, req.Questions); fieldErrs != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":  "Invalid pages",
+			"fields": fieldErrs,
+		})
+	}
+
+	version, ok, err := requireIfMatch(c)
+	if !ok {
+		return err
+	}
+
+	req.ID = formID
+	req.Version = version
+	err = h.Forms.Update(ctx, userID, &req)
+	if errors.Is(err, store.ErrNotFound) {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Form not found",
 		})
 	}
-
-	rows, err := h.DB.Pool.Query(ctx, `
-		SELECT id, form_id, type, title, description, emoji, position, required
-		FROM questions
-		WHERE form_id = $1
-		ORDER BY position ASC
-	`, formID)
+	if errors.Is(err, store.ErrVersionConflict) {
+		return versionConflict(c, h.Forms, formID, userID)
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to fetch questions",
+			"error":  "Failed to update form",
+			"detail": err.Error(),
 		})
 	}
-	defer rows.Close()
 
-	var questions []model.Question
-	questionIDs := []uuid.UUID{}
-	for rows.Next() {
-		var q model.Question
-		if err := rows.Scan(&q.ID, &q.FormID, &q.Type, &q.Title, &q.Description, &q.Emoji, &q.Position, &q.Required); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to scan question",
-			})
-		}
-		questions = append(questions, q)
-		questionIDs = append(questionIDs, q.ID)
-	}
-
-	if len(questionIDs) > 0 {
-		optRows, err := h.DB.Pool.Query(ctx, `
-			SELECT id, question_id, label, position
-			FROM question_options
-			WHERE question_id = ANY($1)
-			ORDER BY question_id, position ASC
-		`, questionIDs)
-		if err == nil {
-			defer optRows.Close()
-			optionsMap := make(map[uuid.UUID][]model.Option)
-			for optRows.Next() {
-				var opt model.Option
-				if err := optRows.Scan(&opt.ID, &opt.QuestionID, &opt.Label, &opt.Position); err == nil {
-					optionsMap[opt.QuestionID] = append(optionsMap[opt.QuestionID], opt)
-				}
-			}
-
-			for i := range questions {
-				if opts, ok := optionsMap[questions[i].ID]; ok {
-					questions[i].Options = opts
-				}
-			}
-		}
-	}
-
-	f.Questions = questions
-	return c.JSON(f)
+	// new questions, options and pages got their IDs on save; the client
+	// must send them back next time or they are archived and added again
+	c.Set(fiber.HeaderETag, formETag(req.Version))
+	return c.JSON(req)
 }
 
-func (h *FormHandler) UpdateForm(c fiber.Ctx) error {
+func (h *FormHandler) UpdateFormSettings(c fiber.Ctx) error {
 	ctx := c.Context()
 	userIDRaw := c.Locals("user_id")
 	if userIDRaw == nil {
@@ -199,79 +176,90 @@ func (h *FormHandler) UpdateForm(c fiber.Ctx) error {
 		})
 	}
 
-	var req model.Form
+	version, _, err := ifMatchVersion(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid If-Match header, expected the form ETag",
+		})
+	}
+
+	var req payload.UpdateFormSettingsRequest
 	if err := c.Bind().JSON(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid request body",
 		})
 	}
 
-	tx, err := h.DB.Pool.Begin(ctx)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to start transaction",
-		})
+	// blank strings clear the setting like null does
+	for _, field := range []*payload.Nullable[string]{&req.ThankYouMessage, &req.RedirectURL} {
+		if field.Value != nil && strings.TrimSpace(*field.Value) == "" {
+			field.Value = nil
+		}
 	}
-	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx, `
-		UPDATE forms 
-		SET title = $1, description = $2, updated_at = NOW()
-		WHERE id = $3 AND owner_id = $4
-	`, req.Title, req.Description, formID, userID)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update form metadata",
+	if fieldErrs := validation.ValidateFormSettings(req, time.Now()); fieldErrs != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":  "Invalid form settings",
+			"fields": fieldErrs,
 		})
 	}
 
-	_, err = tx.Exec(ctx, `DELETE FROM questions WHERE form_id = $1`, formID)
+	f, err := h.Forms.GetByOwner(ctx, formID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Form not found",
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to clear existing questions",
+			"error": "Failed to fetch form",
 		})
 	}
 
-	for i, q := range req.Questions {
-		if q.ID == uuid.Nil {
-			q.ID = uuid.New()
-		}
-
-		_, err = tx.Exec(ctx, `
-			INSERT INTO questions (id, form_id, type, title, description, emoji, position, required)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		`, q.ID, formID, q.Type, q.Title, q.Description, q.Emoji, i, q.Required)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":  "Failed to insert question",
-				"detail": err.Error(),
-			})
-		}
+	if req.IsPublic != nil {
+		f.IsPublic = *req.IsPublic
+	}
+	if req.AllowMultipleSubmissions != nil {
+		f.AllowMultipleSubmissions = *req.AllowMultipleSubmissions
+	}
+	if req.CloseDate.Set {
+		f.CloseDate = req.CloseDate.Value
+	}
+	if req.ThankYouMessage.Set {
+		f.ThankYouMessage = req.ThankYouMessage.Value
+	}
+	if req.RedirectURL.Set {
+		f.RedirectURL = req.RedirectURL.Value
+	}
+	if req.NotifyOwner != nil {
+		f.NotifyOwner = *req.NotifyOwner
+	}
 
-		for j, opt := range q.Options {
-			if opt.ID == uuid.Nil {
-				opt.ID = uuid.New()
-			}
-			_, err = tx.Exec(ctx, `
-				INSERT INTO question_options (id, question_id, label, position)
-				VALUES ($1, $2, $3, $4)
-			`, opt.ID, q.ID, opt.Label, j)
-			if err != nil {
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-					"error":  "Failed to insert option",
-					"detail": err.Error(),
-				})
-			}
-		}
+	// f.Version from the read above already catches a write racing this one;
+	// If-Match additionally pins it to the copy the client edited
+	if version != 0 {
+		f.Version = version
 	}
 
-	if err := tx.Commit(ctx); err != nil {
+	err = h.Forms.UpdateSettings(ctx, userID, f)
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Form not found",
+		})
+	}
+	if errors.Is(err, store.ErrVersionConflict) {
+		return versionConflict(c, h.Forms, formID, userID)
+	}
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to commit transaction",
+			"error":  "Failed to update form settings",
+			"detail": err.Error(),
 		})
 	}
 
-	return c.SendStatus(fiber.StatusNoContent)
+	f.Questions = nil
+	c.Set(fiber.HeaderETag, formETag(f.Version))
+	return c.JSON(f)
 }
 
 func (h *FormHandler) DuplicateForm(c fiber.Ctx) error {
@@ -292,97 +280,15 @@ func (h *FormHandler) DuplicateForm(c fiber.Ctx) error {
 		})
 	}
 
-	// Start transaction
-	tx, err := h.DB.Pool.Begin(ctx)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to start transaction",
-		})
-	}
-	defer tx.Rollback(ctx)
-
-	var originalForm model.Form
-	err = tx.QueryRow(ctx, `
-		SELECT title, description
-		FROM forms
-		WHERE id = $1 AND owner_id = $2
-	`, formID, userID).Scan(&originalForm.Title, &originalForm.Description)
-
-	if err != nil {
+	newForm, err := h.Forms.Duplicate(ctx, formID, userID)
+	if errors.Is(err, store.ErrNotFound) {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Form not found",
 		})
 	}
-
-	var newForm model.Form
-	err = tx.QueryRow(ctx, `
-		INSERT INTO forms (owner_id, title, description, status, is_public, allow_multiple_submissions)
-		VALUES ($1, $2, $3, 'draft', false, false)
-		RETURNING id, owner_id, title, description, status, is_public, allow_multiple_submissions, close_date, thank_you_message, redirect_url, created_at, updated_at
-	`, userID, "Copy of "+originalForm.Title, originalForm.Description).Scan(
-		&newForm.ID, &newForm.OwnerID, &newForm.Title, &newForm.Description, &newForm.Status,
-		&newForm.IsPublic, &newForm.AllowMultipleSubmissions, &newForm.CloseDate,
-		&newForm.ThankYouMessage, &newForm.RedirectURL, &newForm.CreatedAt, &newForm.UpdatedAt,
-	)
-
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create new form",
-		})
-	}
-
-	rows, err := tx.Query(ctx, `
-		SELECT id, type, title, description, emoji, position, required
-		FROM questions
-		WHERE form_id = $1
-	`, formID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to fetch questions",
-		})
-	}
-	defer rows.Close()
-
-	var questions []model.Question
-	for rows.Next() {
-		var q model.Question
-		if err := rows.Scan(&q.ID, &q.Type, &q.Title, &q.Description, &q.Emoji, &q.Position, &q.Required); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to scan question",
-			})
-		}
-		questions = append(questions, q)
-	}
-	rows.Close()
-
-	for _, q := range questions {
-		newQuestionID := uuid.New()
-		_, err = tx.Exec(ctx, `
-			INSERT INTO questions (id, form_id, type, title, description, emoji, position, required)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		`, newQuestionID, newForm.ID, q.Type, q.Title, q.Description, q.Emoji, q.Position, q.Required)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to copy question",
-			})
-		}
-
-		_, err = tx.Exec(ctx, `
-			INSERT INTO question_options (id, question_id, label, position)
-			SELECT gen_random_uuid(), $1, label, position
-			FROM question_options
-			WHERE question_id = $2
-		`, newQuestionID, q.ID)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to copy options",
-			})
-		}
-	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to commit transaction",
+			"error": "Failed to duplicate form",
 		})
 	}
 
@@ -407,20 +313,30 @@ func (h *FormHandler) PublishForm(c fiber.Ctx) error {
 		})
 	}
 
-	_, err = h.DB.Pool.Exec(ctx, `
-		UPDATE forms
-		SET status = 'published', is_public = true, updated_at = NOW()
-		WHERE id = $1 AND owner_id = $2
-	`, formID, userID)
+	version, ok, err := requireIfMatch(c)
+	if !ok {
+		return err
+	}
 
+	version, err = h.Forms.SetStatus(ctx, formID, userID, "published", true, version)
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Form not found",
+		})
+	}
+	if errors.Is(err, store.ErrVersionConflict) {
+		return versionConflict(c, h.Forms, formID, userID)
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to publish form",
 		})
 	}
 
+	c.Set(fiber.HeaderETag, formETag(version))
 	return c.JSON(fiber.Map{
 		"message": "Form published successfully",
+		"version": version,
 	})
 }
 
@@ -442,20 +358,30 @@ func (h *FormHandler) UnpublishForm(c fiber.Ctx) error {
 		})
 	}
 
-	_, err = h.DB.Pool.Exec(ctx, `
-		UPDATE forms
-		SET status = 'draft', is_public = false, updated_at = NOW()
-		WHERE id = $1 AND owner_id = $2
-	`, formID, userID)
+	version, ok, err := requireIfMatch(c)
+	if !ok {
+		return err
+	}
 
+	version, err = h.Forms.SetStatus(ctx, formID, userID, "draft", false, version)
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Form not found",
+		})
+	}
+	if errors.Is(err, store.ErrVersionConflict) {
+		return versionConflict(c, h.Forms, formID, userID)
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to unpublish form",
 		})
 	}
 
+	c.Set(fiber.HeaderETag, formETag(version))
 	return c.JSON(fiber.Map{
 		"message": "Form unpublished successfully",
+		"version": version,
 	})
 }
 
@@ -463,83 +389,19 @@ func (h *FormHandler) GetPublicForm(c fiber.Ctx) error {
 	ctx := c.Context()
 	username := c.Params("username")
 	slug := c.Params("slug") //title of form
-	var f model.Form
-
-	// replace spaces with underscores
-	err := h.DB.Pool.QueryRow(ctx, `
-		SELECT f.id, f.owner_id, f.title, f.description, f.status, f.is_public, 
-		       f.allow_multiple_submissions, f.close_date, f.thank_you_message, f.redirect_url, 
-		       f.created_at, f.updated_at
-		FROM forms f
-		JOIN users u ON f.owner_id = u.id
-		WHERE LOWER(REPLACE(TRIM(u.first_name), ' ', '_')) = LOWER($1) 
-		  AND LOWER(REPLACE(TRIM(f.title), ' ', '_')) = LOWER($2)
-		  AND f.status = 'published' AND f.is_public = true
-		LIMIT 1
-	`, username, slug).Scan(
-		&f.ID, &f.OwnerID, &f.Title, &f.Description, &f.Status,
-		&f.IsPublic, &f.AllowMultipleSubmissions, &f.CloseDate,
-		&f.ThankYouMessage, &f.RedirectURL, &f.CreatedAt, &f.UpdatedAt,
-	)
 
-	if err != nil {
+	f, err := h.Forms.GetPublished(ctx, username, slug)
+	if errors.Is(err, store.ErrNotFound) {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Form not found or not public",
 		})
 	}
-
-	rows, err := h.DB.Pool.Query(ctx, `
-		SELECT id, form_id, type, title, description, emoji, position, required
-		FROM questions
-		WHERE form_id = $1
-		ORDER BY position ASC
-	`, f.ID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to fetch questions",
+			"error": "Failed to fetch form",
 		})
 	}
-	defer rows.Close()
 
-	var questions []model.Question
-	questionIDs := []uuid.UUID{}
-	for rows.Next() {
-		var q model.Question
-		if err := rows.Scan(&q.ID, &q.FormID, &q.Type, &q.Title, &q.Description, &q.Emoji, &q.Position, &q.Required); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to scan question",
-			})
-		}
-		questions = append(questions, q)
-		questionIDs = append(questionIDs, q.ID)
-	}
-
-	if len(questionIDs) > 0 {
-		optRows, err := h.DB.Pool.Query(ctx, `
-			SELECT id, question_id, label, position
-			FROM question_options
-			WHERE question_id = ANY($1)
-			ORDER BY question_id, position ASC
-		`, questionIDs)
-		if err == nil {
-			defer optRows.Close()
-			optionsMap := make(map[uuid.UUID][]model.Option)
-			for optRows.Next() {
-				var opt model.Option
-				if err := optRows.Scan(&opt.ID, &opt.QuestionID, &opt.Label, &opt.Position); err == nil {
-					optionsMap[opt.QuestionID] = append(optionsMap[opt.QuestionID], opt)
-				}
-			}
-
-			for i := range questions {
-				if opts, ok := optionsMap[questions[i].ID]; ok {
-					questions[i].Options = opts
-				}
-			}
-		}
-	}
-
-	f.Questions = questions
 	return c.JSON(f)
 }
 
@@ -561,11 +423,12 @@ func (h *FormHandler) DeleteForm(c fiber.Ctx) error {
 		})
 	}
 
-	res, err := h.DB.Pool.Exec(ctx, `
-		DELETE FROM forms 
-		WHERE id = $1 AND owner_id = $2
-	`, formID, userID)
-
+	err = h.Forms.Delete(ctx, formID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Form not found or you don't have permission to delete it",
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":  "Failed to delete form",
@@ -573,11 +436,5 @@ func (h *FormHandler) DeleteForm(c fiber.Ctx) error {
 		})
 	}
 
-	if res.RowsAffected() == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Form not found or you don't have permission to delete it",
-		})
-	}
-
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -0,0 +1,242 @@
+package user
+
+import (
+	"craft/internal/authz"
+	"craft/internal/model"
+	"craft/internal/server/middlewares"
+	"craft/internal/store"
+	"encoding/json"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// formApp routes the form endpoints for a caller signed in as userID.
+func formApp(st store.Stores, userID uuid.UUID) *fiber.App {
+	h := NewFormHandler(nil, st.Forms)
+	formAccess := middlewares.FormAccess(authz.New(st.Forms))
+
+	app := fiber.New()
+	app.Use(signedIn(userID, "user"))
+	app.Post("/forms", h.CreateForm)
+	app.Get("/forms/:id", formAccess, h.GetForm)
+	app.Put("/forms/:id", h.UpdateForm)
+	app.Patch("/forms/:id/settings", h.UpdateFormSettings)
+	app.Put("/forms/:id/publish", h.PublishForm)
+	app.Delete("/forms/:id", h.DeleteForm)
+	return app
+}
+
+func TestCreateForm(t *testing.T) {
+	st := store.NewMemory().Stores()
+	owner := uuid.New()
+	app := formApp(st, owner)
+
+	r := send(t, app, "POST", "/forms", `{"title":"Team lunch"}`)
+	expectStatus(t, r, fiber.StatusCreated)
+	var created model.Form
+	r.decode(t, &created)
+	if created.Title != "Team lunch" || created.OwnerID != owner || created.Status != model.FormStatusDraft {
+		t.Fatalf("created %+v", created)
+	}
+
+	r = send(t, app, "POST", "/forms", `{}`)
+	expectStatus(t, r, fiber.StatusCreated)
+	var untitled model.Form
+	r.decode(t, &untitled)
+	if untitled.Title != "Untitled Form" {
+		t.Fatalf("title %q, want the default", untitled.Title)
+	}
+
+	forms, err := st.Forms.ListByOwner(t.Context(), owner)
+	if err != nil || len(forms) != 2 {
+		t.Fatalf("owner has %d forms, err %v", len(forms), err)
+	}
+}
+
+func TestGetForm(t *testing.T) {
+	st := store.NewMemory().Stores()
+	owner := uuid.New()
+	f := publishedForm(t, st, owner, model.Question{Type: model.QuestionTypeShortText, Title: "Name"})
+
+	r := send(t, formApp(st, owner), "GET", "/forms/"+f.ID.String(), "")
+	expectStatus(t, r, fiber.StatusOK)
+	if got, want := r.Header.Get(fiber.HeaderETag), formETag(f.Version); got != want {
+		t.Fatalf("ETag %s, want %s", got, want)
+	}
+	var got model.Form
+	r.decode(t, &got)
+	if len(got.Questions) != 1 || got.Questions[0].ID != f.Questions[0].ID {
+		t.Fatalf("questions %+v", got.Questions)
+	}
+
+	r = send(t, formApp(st, uuid.New()), "GET", "/forms/"+f.ID.String(), "")
+	expectStatus(t, r, fiber.StatusNotFound)
+	r = send(t, formApp(st, owner), "GET", "/forms/"+uuid.NewString(), "")
+	expectStatus(t, r, fiber.StatusNotFound)
+}
+
+func TestUpdateForm(t *testing.T) {
+	st := store.NewMemory().Stores()
+	owner := uuid.New()
+	f, err := st.Forms.Create(t.Context(), owner, "Team lunch", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := formApp(st, owner)
+	path := "/forms/" + f.ID.String()
+	body := `{"title":"Team dinner","questions":[{"type":"short-text","title":"Name","required":true}]}`
+
+	r := send(t, app, "PUT", path, body)
+	expectStatus(t, r, fiber.StatusPreconditionRequired)
+
+	for _, invalid := range []string{
+		`{"type":"slider","title":"Hunger"}`,
+		`{"type":"rating","title":"Hunger","rating":{"scale":100}}`,
+		`{"type":"number","title":"Guests","number":{"min":10,"max":2}}`,
+		`{"type":"short-text","title":"Name","file_upload":{"max_files":1}}`,
+	} {
+		r = send(t, app, "PUT", path, `{"title":"Team dinner","questions":[`+invalid+`]}`, fiber.HeaderIfMatch, formETag(f.Version))
+		expectStatus(t, r, fiber.StatusUnprocessableEntity)
+	}
+
+	r = send(t, app, "PUT", path, body, fiber.HeaderIfMatch, formETag(f.Version))
+	expectStatus(t, r, fiber.StatusOK)
+	if got, want := r.Header.Get(fiber.HeaderETag), formETag(f.Version+1); got != want {
+		t.Fatalf("ETag %s, want %s", got, want)
+	}
+	var returned model.Form
+	r.decode(t, &returned)
+	if returned.Version != f.Version+1 || len(returned.Questions) != 1 || returned.Questions[0].ID == uuid.Nil {
+		t.Fatalf("returned %s", r.Body)
+	}
+
+	saved, err := st.Forms.Get(t.Context(), f.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Title != "Team dinner" || len(saved.Questions) != 1 || saved.Questions[0].ID != returned.Questions[0].ID {
+		t.Fatalf("saved %+v", saved)
+	}
+
+	// saving the returned form again keeps its question rather than
+	// archiving it and adding a copy
+	returned.Questions[0].Title = "Full name"
+	again, err := json.Marshal(returned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = send(t, app, "PUT", path, string(again), fiber.HeaderIfMatch, r.Header.Get(fiber.HeaderETag))
+	expectStatus(t, r, fiber.StatusOK)
+	var resaved model.Form
+	r.decode(t, &resaved)
+	if len(resaved.Questions) != 1 || resaved.Questions[0].ID != returned.Questions[0].ID || resaved.Questions[0].Title != "Full name" {
+		t.Fatalf("resaved %s", r.Body)
+	}
+
+	// a write based on the old version loses
+	r = send(t, app, "PUT", path, body, fiber.HeaderIfMatch, formETag(f.Version))
+	expectStatus(t, r, fiber.StatusPreconditionFailed)
+
+	r = send(t, formApp(st, uuid.New()), "PUT", path, body, fiber.HeaderIfMatch, "*")
+	expectStatus(t, r, fiber.StatusNotFound)
+}
+
+func TestUpdateFormSettings(t *testing.T) {
+	st := store.NewMemory().Stores()
+	owner := uuid.New()
+	f := publishedForm(t, st, owner)
+	app := formApp(st, owner)
+	path := "/forms/" + f.ID.String() + "/settings"
+
+	r := send(t, app, "PATCH", path, `{"allow_multiple_submissions":true,"thank_you_message":"Thanks!"}`)
+	expectStatus(t, r, fiber.StatusOK)
+	var got model.Form
+	r.decode(t, &got)
+	if !got.AllowMultipleSubmissions || got.ThankYouMessage == nil || *got.ThankYouMessage != "Thanks!" {
+		t.Fatalf("settings %+v", got)
+	}
+
+	r = send(t, app, "PATCH", path, `{"redirect_url":"javascript:alert(1)"}`)
+	expectStatus(t, r, fiber.StatusUnprocessableEntity)
+
+	r = send(t, formApp(st, uuid.New()), "PATCH", path, `{"allow_multiple_submissions":false}`)
+	expectStatus(t, r, fiber.StatusNotFound)
+}
+
+func TestPublishForm(t *testing.T) {
+	st := store.NewMemory().Stores()
+	owner := uuid.New()
+	f, err := st.Forms.Create(t.Context(), owner, "Team lunch", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := "/forms/" + f.ID.String() + "/publish"
+
+	r := send(t, formApp(st, uuid.New()), "PUT", path, "", fiber.HeaderIfMatch, "*")
+	expectStatus(t, r, fiber.StatusNotFound)
+
+	r = send(t, formApp(st, owner), "PUT", path, "", fiber.HeaderIfMatch, formETag(f.Version))
+	expectStatus(t, r, fiber.StatusOK)
+	published, err := st.Forms.Get(t.Context(), f.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published.Status != model.FormStatusPublished || !published.IsPublic {
+		t.Fatalf("status %q, public %v", published.Status, published.IsPublic)
+	}
+}
+
+func TestDeleteForm(t *testing.T) {
+	st := store.NewMemory().Stores()
+	owner := uuid.New()
+	f := publishedForm(t, st, owner)
+	path := "/forms/" + f.ID.String()
+
+	r := send(t, formApp(st, uuid.New()), "DELETE", path, "")
+	expectStatus(t, r, fiber.StatusNotFound)
+
+	r = send(t, formApp(st, owner), "DELETE", path, "")
+	expectStatus(t, r, fiber.StatusNoContent)
+	if _, err := st.Forms.Get(t.Context(), f.ID); err != store.ErrNotFound {
+		t.Fatalf("form still there: %v", err)
+	}
+
+	r = send(t, formApp(st, owner), "DELETE", path, "")
+	expectStatus(t, r, fiber.StatusNotFound)
+}
+
+func TestGetPublicForm(t *testing.T) {
+	m := store.NewMemory()
+	st := m.Stores()
+	owner := uuid.New()
+	m.PutUser(model.User{ID: owner, FirstName: "Ada", Email: "ada@example.com"})
+	f := publishedForm(t, st, owner, model.Question{Type: model.QuestionTypeShortText, Title: "Name"})
+	f.NotifyOwner = true
+	if err := st.Forms.UpdateSettings(t.Context(), owner, f); err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Get("/public/forms/:username/:slug", NewFormHandler(nil, st.Forms).GetPublicForm)
+
+	r := send(t, app, "GET", "/public/forms/ada/team_lunch", "")
+	expectStatus(t, r, fiber.StatusOK)
+	var got map[string]any
+	r.decode(t, &got)
+	if got["id"] != f.ID.String() || got["title"] != "Team lunch" {
+		t.Fatalf("form %s", r.Body)
+	}
+	if questions, _ := got["questions"].([]any); len(questions) != 1 {
+		t.Fatalf("questions %v", got["questions"])
+	}
+	for _, field := range []string{"owner_id", "notify_owner", "status", "version", "responses", "created_at", "updated_at"} {
+		if _, ok := got[field]; ok {
+			t.Errorf("public form exposes %s", field)
+		}
+	}
+
+	r = send(t, app, "GET", "/public/forms/bob/team_lunch", "")
+	expectStatus(t, r, fiber.StatusNotFound)
+}
@@ -0,0 +1,105 @@
+package user
+
+import (
+	"craft/internal/model"
+	"craft/internal/store"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// signedIn stands in for AuthMiddleware, setting the locals it would.
+func signedIn(userID uuid.UUID, role string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		c.Locals("user_id", userID)
+		c.Locals("user_role", role)
+		return c.Next()
+	}
+}
+
+type response struct {
+	*http.Response
+	Body   []byte
+	method string
+	path   string
+}
+
+// decode unmarshals the response body into v, failing the test if it is not
+// valid JSON.
+func (r response) decode(t *testing.T, v any) {
+	t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		t.Fatalf("decoding %s: %v", r.Body, err)
+	}
+}
+
+// send makes a request to app with a JSON body, if not empty, and the given
+// header pairs.
+func send(t *testing.T, app *fiber.App, method, path, body string, header ...string) response {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response{resp, b, method, path}
+}
+
+// cookie returns the named cookie set by r as a Cookie header value, or "".
+func (r response) cookie(name string) string {
+	for _, c := range r.Cookies() {
+		if c.Name == name {
+			return c.Name + "=" + c.Value
+		}
+	}
+	return ""
+}
+
+func expectStatus(t *testing.T, r response, want int) {
+	t.Helper()
+	if r.StatusCode != want {
+		t.Fatalf("%s %s: status %d, want %d: %s", r.method, r.path, r.StatusCode, want, r.Body)
+	}
+}
+
+// publishedForm creates a public form owned by ownerID with the given
+// questions and returns it as stored, with question IDs assigned.
+func publishedForm(t *testing.T, st store.Stores, ownerID uuid.UUID, questions ...model.Question) *model.Form {
+	t.Helper()
+	ctx := t.Context()
+	f, err := st.Forms.Create(ctx, ownerID, "Team lunch", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range questions {
+		questions[i].Position = i
+	}
+	f.Questions = questions
+	if err := st.Forms.Update(ctx, ownerID, f); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Forms.SetStatus(ctx, f.ID, ownerID, model.FormStatusPublished, true, f.Version); err != nil {
+		t.Fatal(err)
+	}
+	f, err = st.Forms.Get(ctx, f.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
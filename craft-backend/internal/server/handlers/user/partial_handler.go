@@ -0,0 +1,295 @@
+package user
+
+import (
+	"context"
+	"craft/internal/model"
+	"craft/internal/model/payload"
+	"craft/internal/notify"
+	"craft/internal/partials"
+	"craft/internal/store"
+	"craft/internal/validation"
+	"craft/pkg"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/supabase-community/supabase-go"
+)
+
+type PartialHandler struct {
+	supabase      *supabase.Client
+	Forms         store.FormStore
+	Partials      store.PartialStore
+	Users         store.UserStore
+	Jobs          store.JobStore
+	Notifications store.NotificationStore
+	// AppURL is the frontend's base URL, for resume links.
+	AppURL string
+}
+
+func NewPartialHandler(supabase *supabase.Client, forms store.FormStore, partialStore store.PartialStore, users store.UserStore, jobs store.JobStore, notifications store.NotificationStore, appURL string) *PartialHandler {
+	return &PartialHandler{
+		supabase:      supabase,
+		Forms:         forms,
+		Partials:      partialStore,
+		Users:         users,
+		Jobs:          jobs,
+		Notifications: notifications,
+		AppURL:        appURL,
+	}
+}
+
+// CreatePartial saves a respondent's progress on a form and returns the
+// token that resumes it, alone or in a link to the form.
+func (h *PartialHandler) CreatePartial(c fiber.Ctx) error {
+	form, err := acceptingForm(c, h.Forms)
+	if form == nil {
+		return err
+	}
+
+	var req payload.SavePartialRequest
+	if err := c.Bind().JSON(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+			"code":  "invalid_request",
+		})
+	}
+	token, hash, err := partials.NewToken()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to create resume token",
+			"detail": err.Error(),
+		})
+	}
+	p := &model.PartialResponse{FormID: form.ID, TokenHash: hash}
+	if ok, err := checkPartial(c, form, p, &req); !ok {
+		return err
+	}
+
+	link, err := h.resumeURL(c.Context(), form, token)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to build resume link",
+			"detail": err.Error(),
+		})
+	}
+	if ok, err := h.allowResumeLink(c, form, p, &req); !ok {
+		return err
+	}
+
+	if err := h.Partials.Create(c.Context(), p); errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Form not found",
+			"code":  "form_not_found",
+		})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to save answers",
+			"detail": err.Error(),
+		})
+	}
+
+	if req.SendLink {
+		if err := notify.QueueResumeLink(c.Context(), h.Jobs, form, *p.RespondentEmail, link, partials.TTL); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":  "Failed to send resume link",
+				"detail": err.Error(),
+			})
+		}
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"token":      token,
+		"resume_url": link,
+		"partial":    p,
+	})
+}
+
+// GetPartial returns the saved progress of the token in the path.
+func (h *PartialHandler) GetPartial(c fiber.Ctx) error {
+	form, err := acceptingForm(c, h.Forms)
+	if form == nil {
+		return err
+	}
+	p, err := h.partial(c, form)
+	if p == nil {
+		return err
+	}
+	return c.JSON(fiber.Map{"partial": p})
+}
+
+// UpdatePartial replaces the saved answers of the token in the path and
+// keeps them for another partials.TTL.
+func (h *PartialHandler) UpdatePartial(c fiber.Ctx) error {
+	form, err := acceptingForm(c, h.Forms)
+	if form == nil {
+		return err
+	}
+
+	var req payload.SavePartialRequest
+	if err := c.Bind().JSON(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+			"code":  "invalid_request",
+		})
+	}
+	p, err := h.partial(c, form)
+	if p == nil {
+		return err
+	}
+	if ok, err := checkPartial(c, form, p, &req); !ok {
+		return err
+	}
+	if ok, err := h.allowResumeLink(c, form, p, &req); !ok {
+		return err
+	}
+
+	if err := h.Partials.Update(c.Context(), p); errors.Is(err, store.ErrNotFound) {
+		return partialNotFound(c)
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to save answers",
+			"detail": err.Error(),
+		})
+	}
+
+	if req.SendLink {
+		link, err := h.resumeURL(c.Context(), form, c.Params("token"))
+		if err == nil {
+			err = notify.QueueResumeLink(c.Context(), h.Jobs, form, *p.RespondentEmail, link, partials.TTL)
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":  "Failed to send resume link",
+				"detail": err.Error(),
+			})
+		}
+	}
+
+	return c.JSON(fiber.Map{"partial": p})
+}
+
+// DeletePartial discards the saved progress of the token in the path.
+func (h *PartialHandler) DeletePartial(c fiber.Ctx) error {
+	form, err := acceptingForm(c, h.Forms)
+	if form == nil {
+		return err
+	}
+	p, err := h.partial(c, form)
+	if p == nil {
+		return err
+	}
+
+	if err := h.Partials.Delete(c.Context(), p.ID); errors.Is(err, store.ErrNotFound) {
+		return partialNotFound(c)
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete saved answers",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Saved answers deleted successfully",
+	})
+}
+
+// partial loads the unexpired partial response of the token in the path.
+// When there is none it answers the request itself and returns nil.
+func (h *PartialHandler) partial(c fiber.Ctx, form *model.Form) (*model.PartialResponse, error) {
+	p, err := h.Partials.GetByToken(c.Context(), form.ID, partials.HashToken(c.Params("token")))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, partialNotFound(c)
+	}
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch saved answers",
+		})
+	}
+	return p, nil
+}
+
+func partialNotFound(c fiber.Ctx) error {
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+		"error": "Saved answers not found or expired",
+		"code":  "partial_not_found",
+	})
+}
+
+// checkPartial validates req and applies it to p, renewing its expiry.
+// When req is invalid it answers the request itself and reports false.
+func checkPartial(c fiber.Ctx, form *model.Form, p *model.PartialResponse, req *payload.SavePartialRequest) (bool, error) {
+	if err := pkg.Validator.Struct(req); err != nil {
+		return false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid respondent email",
+			"code":  "invalid_request",
+		})
+	}
+
+	fieldErrs := validation.ValidatePartialAnswers(form.Questions, req.Answers)
+	if fieldErrs == nil {
+		fieldErrs = validation.FieldErrors{}
+	}
+	if req.SectionID != nil && !hasSection(form, *req.SectionID) {
+		fieldErrs["section_id"] = "page does not belong to this form"
+	}
+	if req.RespondentEmail != nil {
+		p.RespondentEmail = req.RespondentEmail
+	}
+	if req.SendLink && p.RespondentEmail == nil {
+		fieldErrs["respondent_email"] = "an email address is needed to send the resume link"
+	}
+	if len(fieldErrs) > 0 {
+		return false, c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":  "Some answers are invalid",
+			"code":   "invalid_answers",
+			"fields": fieldErrs,
+		})
+	}
+
+	p.Answers = partials.Answers(req.Answers)
+	if req.SectionID != nil {
+		p.SectionID = req.SectionID
+	}
+	p.ExpiresAt = time.Now().Add(partials.TTL)
+	return true, nil
+}
+
+// allowResumeLink counts the resume link req asks to be sent to p's address,
+// before anything is saved. When the address or caller has had too many
+// emails it answers the request itself and reports false.
+func (h *PartialHandler) allowResumeLink(c fiber.Ctx, form *model.Form, p *model.PartialResponse, req *payload.SavePartialRequest) (bool, error) {
+	if !req.SendLink {
+		return true, nil
+	}
+	err := notify.AllowRespondentEmail(c.Context(), h.Notifications, form.ID, *p.RespondentEmail, c.IP())
+	if errors.Is(err, notify.ErrTooManyEmails) {
+		return false, c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error": "Too many emails sent to this address, try again later",
+			"code":  "too_many_emails",
+		})
+	}
+	if err != nil {
+		return false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to send resume link",
+			"detail": err.Error(),
+		})
+	}
+	return true, nil
+}
+
+func hasSection(form *model.Form, id uuid.UUID) bool {
+	for _, sec := range form.Sections {
+		if sec.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *PartialHandler) resumeURL(ctx context.Context, form *model.Form, token string) (string, error) {
+	owner, err := h.Users.Get(ctx, form.OwnerID)
+	if err != nil {
+		return "", err
+	}
+	return partials.ResumeURL(h.AppURL, owner.FirstName, form.Title, token), nil
+}
@@ -0,0 +1,96 @@
+package user
+
+import (
+	"craft/internal/model"
+	"craft/internal/notify"
+	"craft/internal/partials"
+	"craft/internal/store"
+	"errors"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// partialApp routes the public partial and submit endpoints, as an
+// anonymous caller.
+func partialApp(st store.Stores) *fiber.App {
+	h := NewPartialHandler(nil, st.Forms, st.Partials, st.Users, st.Jobs, st.Notifications, "https://craft.test")
+	submissions := NewSubmissionHandler(nil, st.Forms, st.Submissions, st.Webhooks, st.Autoresponders, st.Jobs, st.Uploads, nil, nil, st.Partials, st.Notifications)
+	app := fiber.New()
+	app.Post("/forms/:id/partials", h.CreatePartial)
+	app.Put("/forms/:id/partials/:token", h.UpdatePartial)
+	app.Post("/forms/:id/submit", submissions.SubmitForm)
+	return app
+}
+
+func partialForm(t *testing.T) (store.Stores, *model.Form) {
+	t.Helper()
+	m := store.NewMemory()
+	st := m.Stores()
+	owner := uuid.New()
+	m.PutUser(model.User{ID: owner, FirstName: "Ada", Email: "ada@example.com"})
+	return st, lunchForm(t, st, owner)
+}
+
+func TestPartialResumeLinksAreThrottled(t *testing.T) {
+	st, f := partialForm(t)
+	app := partialApp(st)
+	path := "/forms/" + f.ID.String() + "/partials"
+	body := `{"respondent_email":"grace@example.com","send_link":true,"answers":[]}`
+
+	var token string
+	for range notify.MaxPerFormAddress {
+		r := send(t, app, "POST", path, body)
+		expectStatus(t, r, fiber.StatusCreated)
+		var created struct {
+			Token string `json:"token"`
+		}
+		r.decode(t, &created)
+		token = created.Token
+	}
+
+	r := send(t, app, "POST", path, body)
+	expectStatus(t, r, fiber.StatusTooManyRequests)
+	r = send(t, app, "PUT", path+"/"+token, body)
+	expectStatus(t, r, fiber.StatusTooManyRequests)
+
+	// saving without a link still works
+	r = send(t, app, "PUT", path+"/"+token, `{"answers":[]}`)
+	expectStatus(t, r, fiber.StatusOK)
+}
+
+func TestSubmitPartialOnlyOnce(t *testing.T) {
+	st, f := partialForm(t)
+	app := partialApp(st)
+	name := f.Questions[0].ID.String()
+
+	r := send(t, app, "POST", "/forms/"+f.ID.String()+"/partials", `{"answers":[{"question_id":"`+name+`","value":"Ada"}]}`)
+	expectStatus(t, r, fiber.StatusCreated)
+	var created struct {
+		Token string `json:"token"`
+	}
+	r.decode(t, &created)
+	p, err := st.Partials.GetByToken(t.Context(), f.ID, partials.HashToken(created.Token))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	submit := `{"resume_token":"` + created.Token + `","answers":[]}`
+	r = send(t, app, "POST", "/forms/"+f.ID.String()+"/submit", submit)
+	expectStatus(t, r, fiber.StatusCreated)
+	r = send(t, app, "POST", "/forms/"+f.ID.String()+"/submit", submit)
+	expectStatus(t, r, fiber.StatusNotFound)
+
+	// a submission racing the first one finds the partial gone when saving,
+	// and nothing is saved for it
+	sub := model.Submission{FormID: f.ID}
+	answers := []model.Answer{{QuestionID: f.Questions[0].ID, Value: []byte(`"Ada"`)}}
+	if err := st.Submissions.Create(t.Context(), &sub, answers, &p.ID); !errors.Is(err, store.ErrPartialGone) {
+		t.Fatalf("submitting the partial again: %v", err)
+	}
+	page, err := st.Submissions.ListByForm(t.Context(), f.ID, store.SubmissionQuery{})
+	if err != nil || page.Total != 1 {
+		t.Fatalf("%d submissions, err %v", page.Total, err)
+	}
+}
@@ -0,0 +1,204 @@
+package user
+
+import (
+	"craft/internal/model"
+	"craft/internal/revisions"
+	"craft/internal/store"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/supabase-community/supabase-go"
+)
+
+type RevisionHandler struct {
+	supabase  *supabase.Client
+	Forms     store.FormStore
+	Revisions store.RevisionStore
+}
+
+func NewRevisionHandler(supabase *supabase.Client, forms store.FormStore, revisions store.RevisionStore) *RevisionHandler {
+	return &RevisionHandler{
+		supabase:  supabase,
+		Forms:     forms,
+		Revisions: revisions,
+	}
+}
+
+// ListRevisions, GetRevision and DiffRevisions read the form resolved by the
+// FormAccess middleware and query as its owner, so admins can read any form.
+func (h *RevisionHandler) ListRevisions(c fiber.Ctx) error {
+	ctx := c.Context()
+	f, ok := c.Locals("form").(*model.Form)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Form not resolved",
+		})
+	}
+
+	revs, err := h.Revisions.List(ctx, f.ID, f.OwnerID)
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Form not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch revisions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"revisions": revs,
+	})
+}
+
+func (h *RevisionHandler) GetRevision(c fiber.Ctx) error {
+	ctx := c.Context()
+	f, ok := c.Locals("form").(*model.Form)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Form not resolved",
+		})
+	}
+
+	revision, err := strconv.Atoi(c.Params("revision"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid revision number",
+		})
+	}
+
+	rev, err := h.Revisions.Get(ctx, f.ID, f.OwnerID, revision)
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Revision not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch revision",
+		})
+	}
+
+	return c.JSON(rev)
+}
+
+// DiffRevisions compares ?from=N against ?to=M. to defaults to the latest revision.
+func (h *RevisionHandler) DiffRevisions(c fiber.Ctx) error {
+	ctx := c.Context()
+	f, ok := c.Locals("form").(*model.Form)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Form not resolved",
+		})
+	}
+
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Query parameter 'from' must be a revision number",
+		})
+	}
+
+	to := 0
+	if raw := c.Query("to"); raw != "" {
+		to, err = strconv.Atoi(raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Query parameter 'to' must be a revision number",
+			})
+		}
+	} else {
+		revs, err := h.Revisions.List(ctx, f.ID, f.OwnerID)
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Form not found",
+			})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to fetch revisions",
+			})
+		}
+		if len(revs) > 0 {
+			to = revs[0].Revision
+		}
+	}
+
+	fromRev, err := h.Revisions.Get(ctx, f.ID, f.OwnerID, from)
+	if err != nil {
+		return revisionLookupError(c, err)
+	}
+	toRev, err := h.Revisions.Get(ctx, f.ID, f.OwnerID, to)
+	if err != nil {
+		return revisionLookupError(c, err)
+	}
+
+	return c.JSON(revisions.Compare(fromRev, toRev))
+}
+
+func revisionLookupError(c fiber.Ctx, err error) error {
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Revision not found",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to fetch revision",
+	})
+}
+
+func (h *RevisionHandler) RestoreRevision(c fiber.Ctx) error {
+	ctx := c.Context()
+	userIDRaw := c.Locals("user_id")
+	if userIDRaw == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User not authenticated",
+		})
+	}
+
+	userID := userIDRaw.(uuid.UUID)
+	formID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid form ID",
+		})
+	}
+
+	revision, err := strconv.Atoi(c.Params("revision"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid revision number",
+		})
+	}
+
+	version, _, err := ifMatchVersion(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid If-Match header, expected the form ETag",
+		})
+	}
+
+	restored, err := h.Revisions.Restore(ctx, formID, userID, revision, version)
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Revision not found",
+		})
+	}
+	if errors.Is(err, store.ErrVersionConflict) {
+		return versionConflict(c, h.Forms, formID, userID)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to restore revision",
+			"detail": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":  "Revision restored successfully",
+		"revision": restored,
+	})
+}
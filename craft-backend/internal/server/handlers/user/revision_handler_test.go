@@ -0,0 +1,110 @@
+package user
+
+import (
+	"craft/internal/authz"
+	"craft/internal/model"
+	"craft/internal/revisions"
+	"craft/internal/server/middlewares"
+	"craft/internal/store"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// revisionApp routes the revision endpoints for a caller signed in as userID.
+func revisionApp(st store.Stores, userID uuid.UUID) *fiber.App {
+	h := NewRevisionHandler(nil, st.Forms, st.Revisions)
+	formAccess := middlewares.FormAccess(authz.New(st.Forms))
+
+	app := fiber.New()
+	app.Use(signedIn(userID, "user"))
+	app.Get("/forms/:id/revisions", formAccess, h.ListRevisions)
+	app.Get("/forms/:id/revisions/diff", formAccess, h.DiffRevisions)
+	app.Post("/forms/:id/revisions/:revision/restore", h.RestoreRevision)
+	return app
+}
+
+// rename saves f under a new title, recording a revision.
+func rename(t *testing.T, st store.Stores, f *model.Form, title string) {
+	t.Helper()
+	f.Title = title
+	if err := st.Forms.Update(t.Context(), f.OwnerID, f); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiffRevisions(t *testing.T) {
+	st := store.NewMemory().Stores()
+	owner := uuid.New()
+	f := publishedForm(t, st, owner, model.Question{Type: model.QuestionTypeShortText, Title: "Name"})
+	rename(t, st, f, "Team dinner")
+
+	r := send(t, revisionApp(st, owner), "GET", "/forms/"+f.ID.String()+"/revisions/diff?from=1", "")
+	expectStatus(t, r, fiber.StatusOK)
+	var d revisions.Diff
+	r.decode(t, &d)
+	if d.From != 1 || d.To != 3 || d.Fields["title"].To != "Team dinner" {
+		t.Fatalf("diff %s", r.Body)
+	}
+
+	r = send(t, revisionApp(st, owner), "GET", "/forms/"+f.ID.String()+"/revisions/diff?from=1&to=9", "")
+	expectStatus(t, r, fiber.StatusNotFound)
+}
+
+func TestRestoreRevision(t *testing.T) {
+	st := store.NewMemory().Stores()
+	owner := uuid.New()
+	f := publishedForm(t, st, owner, model.Question{Type: model.QuestionTypeShortText, Title: "Name"})
+	first := f.Questions[0].ID
+	f.Questions = []model.Question{{Type: model.QuestionTypeShortText, Title: "Email"}}
+	rename(t, st, f, "Team dinner")
+	version := f.Version
+
+	app := revisionApp(st, owner)
+	path := "/forms/" + f.ID.String() + "/revisions/1/restore"
+	r := send(t, app, "POST", path, "", fiber.HeaderIfMatch, formETag(version-1))
+	expectStatus(t, r, fiber.StatusPreconditionFailed)
+
+	r = send(t, app, "POST", path, "", fiber.HeaderIfMatch, formETag(version))
+	expectStatus(t, r, fiber.StatusOK)
+	var restored struct {
+		Revision model.FormRevision `json:"revision"`
+	}
+	r.decode(t, &restored)
+	if restored.Revision.Revision != 4 || restored.Revision.Reason != model.RevisionReasonRestore {
+		t.Fatalf("restored %s", r.Body)
+	}
+
+	// the form is as it was, its question back under the same ID, with its
+	// version bumped; history is kept rather than rewound
+	got, err := st.Forms.Get(t.Context(), f.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "Team lunch" || got.Version != version+1 || len(got.Questions) != 1 || got.Questions[0].ID != first {
+		t.Fatalf("form after restore %+v", got)
+	}
+	revs, err := st.Revisions.List(t.Context(), f.ID, owner)
+	if err != nil || len(revs) != 4 {
+		t.Fatalf("%d revisions, err %v", len(revs), err)
+	}
+}
+
+func TestRestoreRevisionOfAnotherForm(t *testing.T) {
+	st := store.NewMemory().Stores()
+	owner := uuid.New()
+	f := publishedForm(t, st, owner)
+	other := publishedForm(t, st, owner)
+	for i := range 3 {
+		rename(t, st, other, "Team dinner "+strconv.Itoa(i))
+	}
+
+	// revision 5 exists, but only for the other form
+	r := send(t, revisionApp(st, owner), "POST", "/forms/"+f.ID.String()+"/revisions/5/restore", "", fiber.HeaderIfMatch, "*")
+	expectStatus(t, r, fiber.StatusNotFound)
+
+	r = send(t, revisionApp(st, uuid.New()), "POST", "/forms/"+other.ID.String()+"/revisions/1/restore", "", fiber.HeaderIfMatch, "*")
+	expectStatus(t, r, fiber.StatusNotFound)
+}
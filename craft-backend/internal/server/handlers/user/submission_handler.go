@@ -1,9 +1,24 @@
 package user
 
 import (
-	"craft/internal/db"
+	"context"
+	"craft/internal/model"
+	"craft/internal/model/payload"
+	"craft/internal/notify"
+	"craft/internal/partials"
+	"craft/internal/store"
+	"craft/internal/uploads"
+	"craft/internal/validation"
+	"craft/internal/webhooks"
+	"craft/pkg"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
@@ -12,17 +27,72 @@ import (
 )
 
 type SubmissionHandler struct {
-	supabase *supabase.Client
-	DB       *db.Database
+	supabase       *supabase.Client
+	Forms          store.FormStore
+	Submissions    store.SubmissionStore
+	Webhooks       store.WebhookStore
+	Autoresponders store.AutoresponderStore
+	Jobs           store.JobStore
+	Uploads        store.UploadStore
+	Blobs          uploads.BlobStore
+	Links          *uploads.Links
+	Partials       store.PartialStore
+	Notifications  store.NotificationStore
 }
 
-func NewSubmissionHandler(supabase *supabase.Client, DB *db.Database) *SubmissionHandler {
+func NewSubmissionHandler(supabase *supabase.Client, forms store.FormStore, submissions store.SubmissionStore, webhooks store.WebhookStore, autoresponders store.AutoresponderStore, jobs store.JobStore, uploadStore store.UploadStore, blobs uploads.BlobStore, links *uploads.Links, partialStore store.PartialStore, notifications store.NotificationStore) *SubmissionHandler {
 	return &SubmissionHandler{
-		supabase: supabase,
-		DB:       DB,
+		supabase:       supabase,
+		Forms:          forms,
+		Submissions:    submissions,
+		Webhooks:       webhooks,
+		Autoresponders: autoresponders,
+		Jobs:           jobs,
+		Uploads:        uploadStore,
+		Blobs:          blobs,
+		Links:          links,
+		Partials:       partialStore,
+		Notifications:  notifications,
 	}
 }
 
+// deviceCookie identifies a browser across submissions so forms that allow a
+// single response can recognise anonymous respondents.
+const deviceCookie = "craft_device_id"
+
+func lifecycleStatus(err *validation.LifecycleError) int {
+	switch err {
+	case validation.ErrFormClosed, validation.ErrFormPastCloseDate:
+		return fiber.StatusGone
+	case validation.ErrAlreadySubmitted:
+		return fiber.StatusConflict
+	default:
+		return fiber.StatusForbidden
+	}
+}
+
+// respondentDevice returns the caller's device ID, issuing a new cookie when
+// none was sent (known is then false), and a fingerprint of IP and user agent.
+func respondentDevice(c fiber.Ctx, ip, ua string) (deviceID string, known bool, fingerprint string) {
+	sum := sha256.Sum256([]byte(ip + "|" + ua))
+	fingerprint = hex.EncodeToString(sum[:])
+
+	if id := c.Cookies(deviceCookie); id != "" {
+		return id, true, fingerprint
+	}
+
+	deviceID = uuid.NewString()
+	c.Cookie(&fiber.Cookie{
+		Name:     deviceCookie,
+		Value:    deviceID,
+		Path:     "/",
+		MaxAge:   int((365 * 24 * time.Hour).Seconds()),
+		HTTPOnly: true,
+		SameSite: "Lax",
+	})
+	return deviceID, false, fingerprint
+}
+
 func (h *SubmissionHandler) SubmitForm(c fiber.Ctx) error {
 	ctx := c.Context()
 	formIDStr := c.Params("id")
@@ -30,185 +100,336 @@ func (h *SubmissionHandler) SubmitForm(c fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid form ID",
+			"code":  "invalid_request",
 		})
 	}
 
-	var req struct {
-		Answers []struct {
-			QuestionID uuid.UUID   `json:"question_id"`
-			Value      interface{} `json:"value"`
-		} `json:"answers"`
-	}
-
+	var req payload.SubmitFormRequest
 	if err := c.Bind().JSON(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid request body",
+			"code":  "invalid_request",
 		})
 	}
 
-	tx, err := h.DB.Pool.Begin(ctx)
+	if err := pkg.Validator.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid respondent email",
+			"code":  "invalid_request",
+		})
+	}
+
+	form, err := h.Forms.Get(ctx, formID)
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Form not found",
+			"code":  "form_not_found",
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to start transaction",
+			"error": "Failed to fetch form",
 		})
 	}
-	defer tx.Rollback(ctx)
 
-	submissionID := uuid.New()
-	ip := c.IP()
-	ua := c.Get("User-Agent")
+	var lifecycleErr *validation.LifecycleError
+	if errors.As(validation.CheckAcceptingSubmissions(form, time.Now()), &lifecycleErr) {
+		return c.Status(lifecycleStatus(lifecycleErr)).JSON(fiber.Map{
+			"error": lifecycleErr.Message,
+			"code":  lifecycleErr.Code,
+		})
+	}
 
-	_, err = tx.Exec(ctx, `
-		INSERT INTO submissions (id, form_id, ip_address, user_agent)
-		VALUES ($1, $2, $3, $4)
-	`, submissionID, formID, ip, ua)
+	// answers saved to finish later are submitted along with the request's,
+	// which take precedence
+	var partial *model.PartialResponse
+	if req.ResumeToken != "" {
+		partial, err = h.Partials.GetByToken(ctx, form.ID, partials.HashToken(req.ResumeToken))
+		if errors.Is(err, store.ErrNotFound) {
+			return partialNotFound(c)
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to fetch saved answers",
+			})
+		}
+		req.Answers = partials.Merge(partial.Answers, req.Answers)
+		if req.RespondentEmail == nil {
+			req.RespondentEmail = partial.RespondentEmail
+		}
+	}
+
+	// questions on skipped pages or hidden by earlier answers are not
+	// required, and answers to them are dropped rather than saved
+	questions, visibleAnswers := validation.VisibleQuestions(form, req.Answers)
+	req.Answers = visibleAnswers
+
+	if fieldErrs := validation.ValidateAnswers(questions, req.Answers); fieldErrs != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":  "Some answers are invalid",
+			"code":   "invalid_answers",
+			"fields": fieldErrs,
+		})
+	}
+
+	uploadIDs, fieldErrs, err := uploads.CheckAnswers(ctx, h.Uploads, h.Blobs, form, req.Answers)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":  "Failed to create submission",
+			"error":  "Failed to check uploaded files",
 			"detail": err.Error(),
 		})
 	}
+	if fieldErrs != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":  "Some answers are invalid",
+			"code":   "invalid_answers",
+			"fields": fieldErrs,
+		})
+	}
 
-	for _, ans := range req.Answers {
-		answerID := uuid.New()
-		valJSON, err := json.Marshal(ans.Value)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":  "Failed to marshal answer value",
-				"detail": err.Error(),
-			})
+	ip := c.IP()
+	ua := c.Get("User-Agent")
+	deviceID, knownDevice, fingerprint := respondentDevice(c, ip, ua)
+	submission := model.Submission{
+		FormID:          formID,
+		RespondentEmail: req.RespondentEmail,
+		IPAddress:       &ip,
+		UserAgent:       &ua,
+		DeviceID:        &deviceID,
+		Fingerprint:     &fingerprint,
+	}
+	if userID, ok := c.Locals("user_id").(uuid.UUID); ok {
+		submission.RespondentUserID = &userID
+		if email, ok := c.Locals("user_email").(string); ok && submission.RespondentEmail == nil && email != "" {
+			submission.RespondentEmail = &email
 		}
+	}
 
-		_, err = tx.Exec(ctx, `
-			INSERT INTO answers (id, submission_id, question_id, value)
-			VALUES ($1, $2, $3, $4)
-		`, answerID, submissionID, ans.QuestionID, valJSON)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":  "Failed to save answer",
-				"detail": err.Error(),
-			})
+	answers := make([]model.Answer, 0, len(req.Answers))
+	for _, ans := range req.Answers {
+		value := ans.Value
+		if len(value) == 0 {
+			value = json.RawMessage("null")
 		}
+		answers = append(answers, model.Answer{
+			QuestionID: ans.QuestionID,
+			Value:      value,
+		})
 	}
 
-	if err := tx.Commit(ctx); err != nil {
+	var partialID *uuid.UUID
+	if partial != nil {
+		partialID = &partial.ID
+	}
+	if form.AllowMultipleSubmissions {
+		err = h.Submissions.Create(ctx, &submission, answers, partialID)
+	} else {
+		// a fresh cookie cannot match anything yet, so fall back to the
+		// fingerprint for first-time or cookie-less clients
+		match := store.RespondentMatch{
+			UserID: submission.RespondentUserID,
+			Email:  submission.RespondentEmail,
+		}
+		if knownDevice {
+			match.DeviceID = &deviceID
+		} else {
+			match.Fingerprint = &fingerprint
+		}
+		err = h.Submissions.CreateUnique(ctx, &submission, answers, partialID, match)
+	}
+	if errors.Is(err, store.ErrAlreadySubmitted) {
+		return c.Status(lifecycleStatus(validation.ErrAlreadySubmitted)).JSON(fiber.Map{
+			"error": validation.ErrAlreadySubmitted.Message,
+			"code":  validation.ErrAlreadySubmitted.Code,
+		})
+	}
+	if errors.Is(err, store.ErrPartialGone) {
+		return partialNotFound(c)
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Form not found",
+			"code":  "form_not_found",
+		})
+	}
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":  "Failed to commit transaction",
+			"error":  "Failed to save submission",
 			"detail": err.Error(),
 		})
 	}
 
+	// the submission is saved either way; a lost event is logged, not reported
+	if len(uploadIDs) > 0 {
+		if n, err := h.Uploads.Attach(ctx, submission.ID, uploadIDs); err != nil {
+			log.Printf("uploads: attaching files to submission %s: %v", submission.ID, err)
+		} else if n < len(uploadIDs) {
+			log.Printf("uploads: %d of %d files of submission %s were already attached", len(uploadIDs)-n, len(uploadIDs), submission.ID)
+		}
+	}
+	if body, err := webhooks.SubmissionCreated(form, submission, answers); err != nil {
+		log.Printf("webhooks: building payload for submission %s: %v", submission.ID, err)
+	} else if err := webhooks.Publish(ctx, h.Webhooks, h.Jobs, form.ID, model.WebhookEventSubmissionCreated, body); err != nil {
+		log.Printf("webhooks: queueing deliveries for submission %s: %v", submission.ID, err)
+	}
+	if err := notify.QueueOwnerEmail(ctx, h.Jobs, form, &submission); err != nil {
+		log.Printf("notify: queueing owner email for submission %s: %v", submission.ID, err)
+	}
+	if err := notify.QueueReceipt(ctx, h.Autoresponders, h.Notifications, h.Jobs, form, answers, ip); err != nil {
+		log.Printf("notify: queueing receipt for submission %s: %v", submission.ID, err)
+	}
+
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"message": "Submission received successfully",
-		"id":      submissionID,
+		"message":           "Submission received successfully",
+		"id":                submission.ID,
+		"thank_you_message": form.ThankYouMessage,
+		"redirect_url":      form.RedirectURL,
 	})
 }
 
+// GetFormSubmissions lists the submissions of the form resolved by the
+// FormAccess middleware.
 func (h *SubmissionHandler) GetFormSubmissions(c fiber.Ctx) error {
 	ctx := c.Context()
-	formIDStr := c.Params("id")
-	formID, err := uuid.Parse(formIDStr)
+	f, ok := c.Locals("form").(*model.Form)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Form not resolved",
+		})
+	}
+
+	query, err := submissionQuery(c)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid form ID",
+			"error": err.Error(),
 		})
 	}
 
-	fmt.Printf("DEBUG: Fetching submissions for formID: %s\n", formID)
-
-	subRows, err := h.DB.Pool.Query(ctx, `
-		SELECT id, form_id, respondent_email, respondent_user_id, ip_address::text, user_agent, created_at
-		FROM submissions
-		WHERE form_id = $1
-		ORDER BY created_at DESC
-	`, formID)
+	page, err := h.Submissions.ListByForm(ctx, f.ID, query)
 	if err != nil {
-		fmt.Printf("DEBUG: Error fetching submissions: %v\n", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch submissions",
 		})
 	}
-	defer subRows.Close()
 
-	var result []map[string]interface{}
-	var submissionIDs []uuid.UUID
+	if err := attachFiles(ctx, h.Uploads, h.Links, page.Submissions); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch uploaded files",
+		})
+	}
+
+	c.Set(headerTotalCount, strconv.Itoa(page.Total))
+	if page.Next != nil {
+		c.Set(headerNextCursor, page.Next.String())
+	}
+	return c.JSON(page.Submissions)
+}
+
+// attachFiles fills in the files of file_upload answers, with download links
+// unless links is nil.
+func attachFiles(ctx context.Context, uploadStore store.UploadStore, links *uploads.Links, subs []model.SubmissionWithAnswers) error {
+	ids := make([]uuid.UUID, len(subs))
+	for i, sub := range subs {
+		ids[i] = sub.ID
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	files, err := uploadStore.ListBySubmissions(ctx, ids)
+	if err != nil || len(files) == 0 {
+		return err
+	}
 
-	for subRows.Next() {
-		var s struct {
-			ID               uuid.UUID  `json:"id"`
-			FormID           uuid.UUID  `json:"form_id"`
-			RespondentEmail  *string    `json:"respondent_email"`
-			RespondentUserID *uuid.UUID `json:"respondent_user_id"`
-			IPAddress        *string    `json:"ip_address"`
-			UserAgent        *string    `json:"user_agent"`
-			CreatedAt        time.Time  `json:"created_at"`
+	type answerKey struct{ submissionID, questionID uuid.UUID }
+	byAnswer := make(map[answerKey][]model.Upload)
+	expires := time.Now().Add(uploads.LinkTTL)
+	for _, u := range files {
+		if links != nil {
+			u.URL = links.URL(u.ID, expires)
 		}
-		if err := subRows.Scan(&s.ID, &s.FormID, &s.RespondentEmail, &s.RespondentUserID, &s.IPAddress, &s.UserAgent, &s.CreatedAt); err != nil {
-			fmt.Printf("DEBUG: Error scanning submission: %v\n", err)
-			continue
+		key := answerKey{*u.SubmissionID, u.QuestionID}
+		byAnswer[key] = append(byAnswer[key], u)
+	}
+	for i := range subs {
+		for j := range subs[i].Answers {
+			a := &subs[i].Answers[j]
+			a.Files = byAnswer[answerKey{subs[i].ID, a.QuestionID}]
 		}
-		submissionIDs = append(submissionIDs, s.ID)
-		result = append(result, map[string]interface{}{
-			"id":                 s.ID,
-			"form_id":            s.FormID,
-			"respondent_email":   s.RespondentEmail,
-			"respondent_user_id": s.RespondentUserID,
-			"ip_address":         s.IPAddress,
-			"user_agent":         s.UserAgent,
-			"created_at":         s.CreatedAt,
-			"answers":            []interface{}{},
-		})
 	}
-	subRows.Close()
+	return nil
+}
 
-	fmt.Printf("DEBUG: Found %d submissions\n", len(submissionIDs))
+const (
+	headerTotalCount = "X-Total-Count"
+	headerNextCursor = "X-Next-Cursor"
+)
 
-	if len(submissionIDs) == 0 {
-		return c.JSON(result)
+// parseQueryTime accepts an RFC 3339 timestamp or a YYYY-MM-DD date, taken
+// as midnight in loc.
+func parseQueryTime(raw string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
 	}
+	return time.ParseInLocation(time.DateOnly, raw, loc)
+}
 
-	ansRows, err := h.DB.Pool.Query(ctx, `
-		SELECT id, submission_id, question_id, value, created_at
-		FROM answers
-		WHERE submission_id = ANY($1)
-	`, submissionIDs)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to fetch answers",
-		})
+// submissionQuery reads the listing parameters of GetFormSubmissions:
+//
+//	limit, cursor         page size and the X-Next-Cursor of the previous page
+//	from, to              created_at range, RFC 3339 or YYYY-MM-DD, to exclusive
+//	q                     full-text search over text answers
+//	answer.<question_id>  answer value; a JSON string or array, or plain text
+func submissionQuery(c fiber.Ctx) (store.SubmissionQuery, error) {
+	var q store.SubmissionQuery
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return q, errors.New("Query parameter 'limit' must be a positive number")
+		}
+		q.Limit = limit
 	}
-	defer ansRows.Close()
 
-	answersMap := make(map[uuid.UUID][]map[string]interface{})
-	for ansRows.Next() {
-		var a struct {
-			ID           uuid.UUID       `json:"id"`
-			SubmissionID uuid.UUID       `json:"submission_id"`
-			QuestionID   uuid.UUID       `json:"question_id"`
-			Value        json.RawMessage `json:"value"`
-			CreatedAt    time.Time       `json:"created_at"`
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := store.ParseSubmissionCursor(raw)
+		if err != nil {
+			return q, errors.New("Invalid cursor")
 		}
-		if err := ansRows.Scan(&a.ID, &a.SubmissionID, &a.QuestionID, &a.Value, &a.CreatedAt); err != nil {
+		q.After = &cursor
+	}
+
+	for name, dst := range map[string]**time.Time{"from": &q.CreatedFrom, "to": &q.CreatedTo} {
+		raw := c.Query(name)
+		if raw == "" {
 			continue
 		}
+		t, err := parseQueryTime(raw, time.UTC)
+		if err != nil {
+			return q, fmt.Errorf("Query parameter '%s' must be an RFC 3339 timestamp or a date", name)
+		}
+		*dst = &t
+	}
 
-		var val interface{}
-		_ = json.Unmarshal(a.Value, &val)
+	q.Search = strings.TrimSpace(c.Query("q"))
 
-		answersMap[a.SubmissionID] = append(answersMap[a.SubmissionID], map[string]interface{}{
-			"id":          a.ID,
-			"question_id": a.QuestionID,
-			"value":       val,
-			"created_at":  a.CreatedAt,
-		})
-	}
+	for key, value := range c.RequestCtx().QueryArgs().All() {
+		name, ok := strings.CutPrefix(string(key), "answer.")
+		if !ok {
+			continue
+		}
+		questionID, err := uuid.Parse(name)
+		if err != nil {
+			return q, fmt.Errorf("Invalid question ID in '%s'", key)
+		}
 
-	for i, sub := range result {
-		sID := sub["id"].(uuid.UUID)
-		if ans, ok := answersMap[sID]; ok {
-			result[i]["answers"] = ans
+		// text and choice answers are JSON strings or arrays of strings, so
+		// anything else is taken as plain text
+		filter := json.RawMessage(string(value))
+		if !json.Valid(filter) || (value[0] != '"' && value[0] != '[') {
+			filter, _ = json.Marshal(string(value))
 		}
+		q.Answers = append(q.Answers, store.AnswerFilter{QuestionID: questionID, Value: filter})
 	}
 
-	return c.JSON(result)
+	return q, nil
 }
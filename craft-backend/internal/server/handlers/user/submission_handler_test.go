@@ -0,0 +1,269 @@
+package user
+
+import (
+	"craft/internal/authz"
+	"craft/internal/model"
+	"craft/internal/server/middlewares"
+	"craft/internal/store"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// submitApp routes the public submit endpoint, as an anonymous caller.
+func submitApp(st store.Stores) *fiber.App {
+	h := NewSubmissionHandler(nil, st.Forms, st.Submissions, st.Webhooks, st.Autoresponders, st.Jobs, st.Uploads, nil, nil, st.Partials, st.Notifications)
+	app := fiber.New()
+	app.Post("/forms/:id/submit", h.SubmitForm)
+	return app
+}
+
+func lunchForm(t *testing.T, st store.Stores, owner uuid.UUID) *model.Form {
+	return publishedForm(t, st, owner,
+		model.Question{Type: model.QuestionTypeShortText, Title: "Name", Required: true},
+		model.Question{Type: model.QuestionTypeSingleSelect, Title: "Main", Options: []model.Option{{Label: "Pasta"}, {Label: "Salad"}}},
+		model.Question{Type: model.QuestionTypeRating, Title: "Hunger", Rating: &model.RatingSettings{Scale: 3}},
+	)
+}
+
+func TestSubmitForm(t *testing.T) {
+	st := store.NewMemory().Stores()
+	f := lunchForm(t, st, uuid.New())
+	name, main, hunger := f.Questions[0].ID.String(), f.Questions[1].ID.String(), f.Questions[2].ID.String()
+
+	r := send(t, submitApp(st), "POST", "/forms/"+f.ID.String()+"/submit",
+		`{"respondent_email":"ada@example.com","answers":[{"question_id":"`+name+`","value":"Ada"},{"question_id":"`+main+`","value":"Salad"},{"question_id":"`+hunger+`","value":3}]}`)
+	expectStatus(t, r, fiber.StatusCreated)
+	var created struct {
+		ID uuid.UUID `json:"id"`
+	}
+	r.decode(t, &created)
+
+	page, err := st.Submissions.ListByForm(t.Context(), f.ID, store.SubmissionQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 {
+		t.Fatalf("%d submissions, want 1", page.Total)
+	}
+	sub := page.Submissions[0]
+	if sub.ID != created.ID || sub.RespondentEmail == nil || *sub.RespondentEmail != "ada@example.com" {
+		t.Fatalf("submission %+v", sub.Submission)
+	}
+	if len(sub.Answers) != 3 || string(sub.Answers[0].Value) != `"Ada"` || string(sub.Answers[1].Value) != `"Salad"` || string(sub.Answers[2].Value) != `3` {
+		t.Fatalf("answers %+v", sub.Answers)
+	}
+}
+
+func TestSubmitFormRejectsInvalidAnswers(t *testing.T) {
+	st := store.NewMemory().Stores()
+	f := lunchForm(t, st, uuid.New())
+	name, main, hunger := f.Questions[0].ID.String(), f.Questions[1].ID.String(), f.Questions[2].ID.String()
+	path := "/forms/" + f.ID.String() + "/submit"
+
+	tests := []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{"required unanswered", `{"answers":[{"question_id":"` + main + `","value":"Pasta"}]}`, name},
+		{"not an option", `{"answers":[{"question_id":"` + name + `","value":"Ada"},{"question_id":"` + main + `","value":"Soup"}]}`, main},
+		{"unknown question", `{"answers":[{"question_id":"` + name + `","value":"Ada"},{"question_id":"` + f.ID.String() + `","value":"x"}]}`, f.ID.String()},
+		{"rating above the scale", `{"answers":[{"question_id":"` + name + `","value":"Ada"},{"question_id":"` + hunger + `","value":4}]}`, hunger},
+		{"rating as text", `{"answers":[{"question_id":"` + name + `","value":"Ada"},{"question_id":"` + hunger + `","value":"3"}]}`, hunger},
+		{"answered twice", `{"answers":[{"question_id":"` + name + `","value":"Ada"},{"question_id":"` + name + `","value":"Bo"}]}`, name},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := send(t, submitApp(st), "POST", path, tt.body)
+			expectStatus(t, r, fiber.StatusUnprocessableEntity)
+			var got struct {
+				Code   string            `json:"code"`
+				Fields map[string]string `json:"fields"`
+			}
+			r.decode(t, &got)
+			if got.Code != "invalid_answers" || got.Fields[tt.field] == "" {
+				t.Fatalf("got %s, want an error for %s", r.Body, tt.field)
+			}
+		})
+	}
+
+	page, err := st.Submissions.ListByForm(t.Context(), f.ID, store.SubmissionQuery{})
+	if err != nil || page.Total != 0 {
+		t.Fatalf("%d submissions saved, err %v", page.Total, err)
+	}
+}
+
+func TestSubmitFormLifecycle(t *testing.T) {
+	st := store.NewMemory().Stores()
+	owner := uuid.New()
+	app := submitApp(st)
+	answers := func(f *model.Form) string {
+		return `{"answers":[{"question_id":"` + f.Questions[0].ID.String() + `","value":"Ada"}]}`
+	}
+
+	r := send(t, app, "POST", "/forms/"+uuid.NewString()+"/submit", `{"answers":[]}`)
+	expectStatus(t, r, fiber.StatusNotFound)
+	r = send(t, app, "POST", "/forms/not-a-uuid/submit", `{"answers":[]}`)
+	expectStatus(t, r, fiber.StatusBadRequest)
+
+	draft, err := st.Forms.Create(t.Context(), owner, "Draft", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = send(t, app, "POST", "/forms/"+draft.ID.String()+"/submit", `{"answers":[]}`)
+	expectStatus(t, r, fiber.StatusForbidden)
+
+	closed := lunchForm(t, st, owner)
+	past := time.Now().Add(-time.Hour)
+	closed.CloseDate = &past
+	if err := st.Forms.UpdateSettings(t.Context(), owner, closed); err != nil {
+		t.Fatal(err)
+	}
+	r = send(t, app, "POST", "/forms/"+closed.ID.String()+"/submit", answers(closed))
+	expectStatus(t, r, fiber.StatusGone)
+
+	// the form takes a single response, recognised by the device cookie
+	once := lunchForm(t, st, owner)
+	path := "/forms/" + once.ID.String() + "/submit"
+	r = send(t, app, "POST", path, answers(once))
+	expectStatus(t, r, fiber.StatusCreated)
+	cookie := r.cookie(deviceCookie)
+	if cookie == "" {
+		t.Fatal("no device cookie issued")
+	}
+	r = send(t, app, "POST", path, answers(once), "Cookie", cookie)
+	expectStatus(t, r, fiber.StatusConflict)
+}
+
+// listApp routes the submissions listing for a caller signed in as userID.
+func listApp(st store.Stores, userID uuid.UUID) *fiber.App {
+	h := NewSubmissionHandler(nil, st.Forms, st.Submissions, st.Webhooks, st.Autoresponders, st.Jobs, st.Uploads, nil, nil, st.Partials, st.Notifications)
+	app := fiber.New()
+	app.Use(signedIn(userID, "user"))
+	app.Get("/forms/:id/submissions", middlewares.FormAccess(authz.New(st.Forms)), h.GetFormSubmissions)
+	return app
+}
+
+func TestGetFormSubmissions(t *testing.T) {
+	st := store.NewMemory().Stores()
+	owner := uuid.New()
+	f := publishedForm(t, st, owner,
+		model.Question{Type: model.QuestionTypeShortText, Title: "Name"},
+		model.Question{Type: model.QuestionTypeMultiSelect, Title: "Diet", Options: []model.Option{{Label: "Vegan"}, {Label: "Halal"}}},
+		model.Question{Type: model.QuestionTypeLongText, Title: "Notes"},
+	)
+	name, diet, notes := f.Questions[0].ID, f.Questions[1].ID, f.Questions[2].ID
+
+	// oldest first; each one later than the last
+	var subs []model.Submission
+	for _, s := range []struct{ name, diet, notes string }{
+		{"Ada", `["Vegan"]`, `"No nuts, please"`},
+		{"Grace", `["Vegan","Halal"]`, `"Running late"`},
+		{"Edsger", `[]`, `"Nuts are fine"`},
+		{"Barbara", `["Halal"]`, `""`},
+		{"Alan", `["Vegan"]`, `"Bringing a friend"`},
+	} {
+		sub := model.Submission{FormID: f.ID}
+		answers := []model.Answer{
+			{QuestionID: name, Value: json.RawMessage(`"` + s.name + `"`)},
+			{QuestionID: diet, Value: json.RawMessage(s.diet)},
+			{QuestionID: notes, Value: json.RawMessage(s.notes)},
+		}
+		if err := st.Submissions.Create(t.Context(), &sub, answers, nil); err != nil {
+			t.Fatal(err)
+		}
+		subs = append(subs, sub)
+		time.Sleep(time.Millisecond)
+	}
+
+	app := listApp(st, owner)
+	path := "/forms/" + f.ID.String() + "/submissions"
+	list := func(query string) (response, []string) {
+		t.Helper()
+		r := send(t, app, "GET", path+query, "")
+		expectStatus(t, r, fiber.StatusOK)
+		var page []model.SubmissionWithAnswers
+		r.decode(t, &page)
+		var names []string
+		for _, sub := range page {
+			var n string
+			for _, a := range sub.Answers {
+				if a.QuestionID == name {
+					json.Unmarshal(a.Value, &n)
+				}
+			}
+			names = append(names, n)
+		}
+		return r, names
+	}
+
+	// the cursor walks every submission once, newest first
+	var got []string
+	query := "?limit=2"
+	for pages := 0; ; pages++ {
+		if pages == 3 {
+			t.Fatalf("more than 3 pages of 2: %v", got)
+		}
+		r, names := list(query)
+		if total := r.Header.Get(headerTotalCount); total != "5" {
+			t.Fatalf("%s is %s, want 5", headerTotalCount, total)
+		}
+		got = append(got, names...)
+		next := r.Header.Get(headerNextCursor)
+		if next == "" {
+			break
+		}
+		query = "?limit=2&cursor=" + next
+	}
+	if want := "Alan Barbara Edsger Grace Ada"; strings.Join(got, " ") != want {
+		t.Fatalf("paged through %v, want %s", got, want)
+	}
+
+	at := func(i int) string { return url.QueryEscape(subs[i].CreatedAt.Format(time.RFC3339Nano)) }
+	tests := []struct {
+		query string
+		want  string
+	}{
+		// from is inclusive, to exclusive
+		{"?from=" + at(1) + "&to=" + at(3), "Edsger Grace"},
+		{"?from=" + subs[0].CreatedAt.Format(time.DateOnly), "Alan Barbara Edsger Grace Ada"},
+		{"?answer." + name.String() + "=Ada", "Ada"},
+		// a choice matches multi-select answers that include it
+		{"?answer." + diet.String() + "=Halal", "Barbara Grace"},
+		{"?answer." + diet.String() + "=Halal&answer." + name.String() + "=Grace", "Grace"},
+		{"?q=nuts", "Edsger Ada"},
+		{"?q=NUTS+please", "Ada"},
+		{"?q=nuts&from=" + at(1), "Edsger"},
+	}
+	for _, tt := range tests {
+		r, names := list(tt.query)
+		if strings.Join(names, " ") != tt.want {
+			t.Errorf("%s: got %v, want %s", tt.query, names, tt.want)
+		}
+		if total := r.Header.Get(headerTotalCount); total != strconv.Itoa(len(names)) || r.Header.Get(headerNextCursor) != "" {
+			t.Errorf("%s: total %s, next %q", tt.query, total, r.Header.Get(headerNextCursor))
+		}
+	}
+
+	for _, bad := range []string{
+		"?cursor=not-a-cursor",
+		"?cursor=" + base64.RawURLEncoding.EncodeToString([]byte("yesterday|"+uuid.NewString())),
+		"?limit=0",
+		"?from=last+week",
+		"?answer.not-a-uuid=Ada",
+	} {
+		r := send(t, app, "GET", path+bad, "")
+		expectStatus(t, r, fiber.StatusBadRequest)
+	}
+
+	r := send(t, listApp(st, uuid.New()), "GET", path, "")
+	expectStatus(t, r, fiber.StatusNotFound)
+}
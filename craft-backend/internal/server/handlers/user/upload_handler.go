@@ -0,0 +1,288 @@
+package user
+
+import (
+	"craft/internal/model"
+	"craft/internal/model/payload"
+	"craft/internal/store"
+	"craft/internal/uploads"
+	"craft/internal/validation"
+	"craft/pkg"
+	"errors"
+	"mime"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/supabase-community/supabase-go"
+)
+
+// downloadRedirectTTL is how long the storage URL a download link redirects
+// to stays valid; the client follows it straight away.
+const downloadRedirectTTL = 5 * time.Minute
+
+type UploadHandler struct {
+	supabase *supabase.Client
+	Forms    store.FormStore
+	Uploads  store.UploadStore
+	Blobs    uploads.BlobStore
+	Links    *uploads.Links
+}
+
+func NewUploadHandler(supabase *supabase.Client, forms store.FormStore, uploadStore store.UploadStore, blobs uploads.BlobStore, links *uploads.Links) *UploadHandler {
+	return &UploadHandler{
+		supabase: supabase,
+		Forms:    forms,
+		Uploads:  uploadStore,
+		Blobs:    blobs,
+		Links:    links,
+	}
+}
+
+// acceptingForm loads the form in the path. When it is missing or closed to
+// submissions it answers the request itself and returns a nil form.
+func acceptingForm(c fiber.Ctx, forms store.FormStore) (*model.Form, error) {
+	formID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid form ID",
+			"code":  "invalid_request",
+		})
+	}
+
+	form, err := forms.Get(c.Context(), formID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Form not found",
+			"code":  "form_not_found",
+		})
+	}
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch form",
+		})
+	}
+
+	var lifecycleErr *validation.LifecycleError
+	if errors.As(validation.CheckAcceptingSubmissions(form, time.Now()), &lifecycleErr) {
+		return nil, c.Status(lifecycleStatus(lifecycleErr)).JSON(fiber.Map{
+			"error": lifecycleErr.Message,
+			"code":  lifecycleErr.Code,
+		})
+	}
+	return form, nil
+}
+
+func fileQuestion(f *model.Form, questionID uuid.UUID) (model.Question, bool) {
+	for _, q := range f.Questions {
+		if q.ID == questionID && q.Type == model.QuestionTypeFileUpload {
+			return q, true
+		}
+	}
+	return model.Question{}, false
+}
+
+// UploadFile stores a file sent as multipart form data, with fields
+// question_id and file. Its ID goes in the answer to that question.
+func (h *UploadHandler) UploadFile(c fiber.Ctx) error {
+	form, err := acceptingForm(c, h.Forms)
+	if form == nil {
+		return err
+	}
+
+	questionID, err := uuid.Parse(c.FormValue("question_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid question ID",
+			"code":  "invalid_request",
+		})
+	}
+	q, ok := fileQuestion(form, questionID)
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "File upload question not found",
+		})
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing file",
+			"code":  "invalid_request",
+		})
+	}
+	contentType := uploads.ContentType(file.Header.Get("Content-Type"))
+	if reason := uploads.CheckFile(q, contentType, file.Size); reason != "" {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":  "File not accepted",
+			"code":   "invalid_file",
+			"fields": validation.FieldErrors{"file": reason},
+		})
+	}
+
+	upload := model.Upload{
+		ID:          uuid.New(),
+		FormID:      form.ID,
+		QuestionID:  q.ID,
+		Filename:    uploads.CleanFilename(file.Filename),
+		ContentType: contentType,
+		Size:        file.Size,
+	}
+	upload.BlobKey = uploads.BlobKey(form.ID, upload.ID)
+
+	r, err := file.Open()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to read file",
+		})
+	}
+	defer r.Close()
+	if err := h.Blobs.Put(c.Context(), upload.BlobKey, r, upload.Size, upload.ContentType); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to store file",
+			"detail": err.Error(),
+		})
+	}
+	if err := h.Uploads.Create(c.Context(), &upload); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to save upload",
+			"detail": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(upload)
+}
+
+// PresignUpload reserves an upload and returns a URL the client PUTs the
+// file to, straight to storage. Only storage that supports presigned URLs
+// offers this; others take files through UploadFile.
+func (h *UploadHandler) PresignUpload(c fiber.Ctx) error {
+	presigner, ok := h.Blobs.(uploads.Presigner)
+	if !ok {
+		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{
+			"error": "Direct uploads are not available, send the file as multipart form data",
+		})
+	}
+
+	form, err := acceptingForm(c, h.Forms)
+	if form == nil {
+		return err
+	}
+
+	var req payload.PresignUploadRequest
+	if err := c.Bind().JSON(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+			"code":  "invalid_request",
+		})
+	}
+	if err := pkg.Validator.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"code":   "invalid_request",
+			"detail": err.Error(),
+		})
+	}
+
+	q, ok := fileQuestion(form, req.QuestionID)
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "File upload question not found",
+		})
+	}
+	contentType := uploads.ContentType(req.ContentType)
+	if reason := uploads.CheckFile(q, contentType, req.Size); reason != "" {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":  "File not accepted",
+			"code":   "invalid_file",
+			"fields": validation.FieldErrors{"file": reason},
+		})
+	}
+
+	upload := model.Upload{
+		ID:          uuid.New(),
+		FormID:      form.ID,
+		QuestionID:  q.ID,
+		Filename:    uploads.CleanFilename(req.Filename),
+		ContentType: contentType,
+		Size:        req.Size,
+	}
+	upload.BlobKey = uploads.BlobKey(form.ID, upload.ID)
+
+	url, header, err := presigner.PresignPut(c.Context(), upload.BlobKey, upload.ContentType, upload.Size, uploads.PresignTTL)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to prepare upload",
+			"detail": err.Error(),
+		})
+	}
+	if err := h.Uploads.Create(c.Context(), &upload); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to save upload",
+			"detail": err.Error(),
+		})
+	}
+
+	headers := make(map[string]string, len(header))
+	for name := range header {
+		headers[name] = header.Get(name)
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"upload":     upload,
+		"url":        url,
+		"method":     fiber.MethodPut,
+		"headers":    headers,
+		"expires_at": time.Now().Add(uploads.PresignTTL),
+	})
+}
+
+// DownloadUpload serves a file through a signed link from
+// GetFormSubmissions, redirecting to storage when it can presign URLs.
+func (h *UploadHandler) DownloadUpload(c fiber.Ctx) error {
+	uploadID, err := uuid.Parse(c.Params("id"))
+	if err != nil || !h.Links.Verify(uploadID, c.Query("expires"), c.Query("signature"), time.Now()) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Invalid or expired download link",
+		})
+	}
+
+	upload, err := h.Uploads.Get(c.Context(), uploadID)
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "File not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch upload",
+		})
+	}
+
+	if presigner, ok := h.Blobs.(uploads.Presigner); ok {
+		url, err := presigner.PresignGet(c.Context(), upload.BlobKey, upload.Filename, downloadRedirectTTL)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":  "Failed to prepare download",
+				"detail": err.Error(),
+			})
+		}
+		return c.Redirect().Status(fiber.StatusFound).To(url)
+	}
+
+	r, err := h.Blobs.Open(c.Context(), upload.BlobKey)
+	if errors.Is(err, uploads.ErrBlobNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "File not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to read file",
+		})
+	}
+
+	// respondents choose the content, so never let a browser render it
+	c.Set(fiber.HeaderContentType, upload.ContentType)
+	c.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": upload.Filename}))
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	return c.SendStream(r, int(upload.Size))
+}
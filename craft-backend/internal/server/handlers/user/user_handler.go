@@ -1,8 +1,7 @@
 package user
 
 import (
-	"craft/internal/db"
-	"craft/internal/model"
+	"craft/internal/store"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
@@ -10,14 +9,16 @@ import (
 )
 
 type UserHandler struct {
-	supabase *supabase.Client
-	DB       *db.Database
+	supabase    *supabase.Client
+	Forms       store.FormStore
+	Submissions store.SubmissionStore
 }
 
-func NewUserHandler(supabase *supabase.Client, DB *db.Database) *UserHandler {
+func NewUserHandler(supabase *supabase.Client, forms store.FormStore, submissions store.SubmissionStore) *UserHandler {
 	return &UserHandler{
-		supabase: supabase,
-		DB:       DB,
+		supabase:    supabase,
+		Forms:       forms,
+		Submissions: submissions,
 	}
 }
 
@@ -32,40 +33,13 @@ func (h *UserHandler) GetDashboardData(c fiber.Ctx) error {
 
 	userID := userIDRaw.(uuid.UUID)
 
-	rows, err := h.DB.Pool.Query(ctx, `
-		SELECT f.id, f.owner_id, f.title, f.description, f.status, f.is_public, 
-		       f.allow_multiple_submissions, f.close_date, f.thank_you_message, f.redirect_url, 
-		       f.created_at, f.updated_at,
-		       (SELECT COUNT(*) FROM submissions s WHERE s.form_id = f.id) as response_count
-		FROM forms f
-		WHERE f.owner_id = $1
-		ORDER BY f.updated_at DESC
-	`, userID)
+	forms, err := h.Forms.ListByOwner(ctx, userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":  "Failed to retrieve forms",
 			"detail": err.Error(),
 		})
 	}
-	defer rows.Close()
-
-	var forms []model.Form
-	for rows.Next() {
-		var f model.Form
-		err := rows.Scan(
-			&f.ID, &f.OwnerID, &f.Title, &f.Description, &f.Status,
-			&f.IsPublic, &f.AllowMultipleSubmissions, &f.CloseDate,
-			&f.ThankYouMessage, &f.RedirectURL, &f.CreatedAt, &f.UpdatedAt,
-			&f.Responses,
-		)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":  "Failed to scan form data",
-				"detail": err.Error(),
-			})
-		}
-		forms = append(forms, f)
-	}
 
 	totalForms := len(forms)
 	activeForms := 0
@@ -78,13 +52,7 @@ func (h *UserHandler) GetDashboardData(c fiber.Ctx) error {
 		}
 	}
 
-	var totalResponses int
-	err = h.DB.Pool.QueryRow(ctx, `
-		SELECT COUNT(*) 
-		FROM submissions s
-		JOIN forms f ON f.id = s.form_id
-		WHERE f.owner_id = $1
-	`, userID).Scan(&totalResponses)
+	totalResponses, err := h.Submissions.CountByOwner(ctx, userID)
 	if err != nil {
 		totalResponses = 0
 	}
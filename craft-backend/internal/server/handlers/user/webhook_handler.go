@@ -0,0 +1,306 @@
+package user
+
+import (
+	"craft/internal/model"
+	"craft/internal/model/payload"
+	"craft/internal/store"
+	"craft/internal/webhooks"
+	"craft/pkg"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/supabase-community/supabase-go"
+)
+
+const (
+	defaultDeliveryLogSize = 50
+	maxDeliveryLogSize     = 200
+)
+
+type WebhookHandler struct {
+	supabase *supabase.Client
+	Webhooks store.WebhookStore
+	Jobs     store.JobStore
+}
+
+func NewWebhookHandler(supabase *supabase.Client, webhooks store.WebhookStore, jobs store.JobStore) *WebhookHandler {
+	return &WebhookHandler{
+		supabase: supabase,
+		Webhooks: webhooks,
+		Jobs:     jobs,
+	}
+}
+
+// ListWebhooks lists the webhooks of the form resolved by the FormAccess
+// middleware. Secrets are never listed.
+func (h *WebhookHandler) ListWebhooks(c fiber.Ctx) error {
+	f, ok := c.Locals("form").(*model.Form)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Form not resolved",
+		})
+	}
+
+	hooks, err := h.Webhooks.List(c.Context(), f.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch webhooks",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"webhooks": hooks,
+	})
+}
+
+// CreateWebhook subscribes a URL to the form's events. The signing secret is
+// only returned in this response.
+func (h *WebhookHandler) CreateWebhook(c fiber.Ctx) error {
+	f, ok := c.Locals("form").(*model.Form)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Form not resolved",
+		})
+	}
+
+	var req payload.CreateWebhookRequest
+	if err := c.Bind().JSON(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if err := pkg.Validator.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Webhook needs an http(s) URL and known event types",
+		})
+	}
+	if err := webhooks.CheckURL(req.URL); err != nil {
+		return publicURLRequired(c)
+	}
+
+	hook := model.Webhook{
+		FormID: f.ID,
+		URL:    req.URL,
+		Secret: webhooks.NewSecret(),
+		Events: req.Events,
+		Active: true,
+	}
+	if len(hook.Events) == 0 {
+		hook.Events = model.WebhookEvents
+	}
+
+	if err := h.Webhooks.Create(c.Context(), &hook); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to create webhook",
+			"detail": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(hook)
+}
+
+func (h *WebhookHandler) UpdateWebhook(c fiber.Ctx) error {
+	ctx := c.Context()
+	f, ok := c.Locals("form").(*model.Form)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Form not resolved",
+		})
+	}
+
+	webhookID, err := uuid.Parse(c.Params("webhookId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid webhook ID",
+		})
+	}
+
+	var req payload.UpdateWebhookRequest
+	if err := c.Bind().JSON(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if err := pkg.Validator.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Webhook needs an http(s) URL and known event types",
+		})
+	}
+	if req.URL != nil {
+		if err := webhooks.CheckURL(*req.URL); err != nil {
+			return publicURLRequired(c)
+		}
+	}
+
+	hook, err := h.Webhooks.Get(ctx, f.ID, webhookID)
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Webhook not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch webhook",
+		})
+	}
+
+	if req.URL != nil {
+		hook.URL = *req.URL
+	}
+	if req.Events != nil {
+		hook.Events = req.Events
+	}
+	if req.Active != nil {
+		hook.Active = *req.Active
+	}
+	if req.RotateSecret {
+		hook.Secret = webhooks.NewSecret()
+	}
+
+	err = h.Webhooks.Update(ctx, hook)
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Webhook not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to update webhook",
+			"detail": err.Error(),
+		})
+	}
+
+	return c.JSON(hook)
+}
+
+func (h *WebhookHandler) DeleteWebhook(c fiber.Ctx) error {
+	f, ok := c.Locals("form").(*model.Form)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Form not resolved",
+		})
+	}
+
+	webhookID, err := uuid.Parse(c.Params("webhookId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid webhook ID",
+		})
+	}
+
+	err = h.Webhooks.Delete(c.Context(), f.ID, webhookID)
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Webhook not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete webhook",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Webhook deleted successfully",
+	})
+}
+
+// ListDeliveries returns the webhook's delivery log, newest first.
+func (h *WebhookHandler) ListDeliveries(c fiber.Ctx) error {
+	ctx := c.Context()
+	f, ok := c.Locals("form").(*model.Form)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Form not resolved",
+		})
+	}
+
+	webhookID, err := uuid.Parse(c.Params("webhookId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid webhook ID",
+		})
+	}
+
+	limit := defaultDeliveryLogSize
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "limit must be a positive integer",
+			})
+		}
+		limit = min(n, maxDeliveryLogSize)
+	}
+
+	if _, err := h.Webhooks.Get(ctx, f.ID, webhookID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Webhook not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch webhook",
+		})
+	}
+
+	deliveries, err := h.Webhooks.ListDeliveries(ctx, f.ID, webhookID, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch deliveries",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"deliveries": deliveries,
+	})
+}
+
+// Redeliver queues a fresh copy of an earlier delivery, whatever its outcome.
+func (h *WebhookHandler) Redeliver(c fiber.Ctx) error {
+	f, ok := c.Locals("form").(*model.Form)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Form not resolved",
+		})
+	}
+
+	webhookID, err := uuid.Parse(c.Params("webhookId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid webhook ID",
+		})
+	}
+	deliveryID, err := uuid.Parse(c.Params("deliveryId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid delivery ID",
+		})
+	}
+
+	delivery, err := webhooks.Redeliver(c.Context(), h.Webhooks, h.Jobs, f.ID, webhookID, deliveryID)
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Delivery not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to queue redelivery",
+			"detail": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(delivery)
+}
+
+// publicURLRequired answers a webhook URL that would reach the server's own
+// network, such as localhost, a private address or a metadata service.
+func publicURLRequired(c fiber.Ctx) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": "Webhook URL must be a public address",
+		"code":  "private_address",
+	})
+}
@@ -9,7 +9,8 @@ func CORS() fiber.Handler {
 	return cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:8080", "http://127.0.0.1:8080", "http://localhost:5173", "http://127.0.0.1:5173"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type", "X-Request-ID", "X-Requested-With", "Origin"},
+		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type", "If-Match", "X-Request-ID", "X-Requested-With", "Origin"},
+		ExposeHeaders:    []string{"ETag", "X-Total-Count", "X-Next-Cursor"},
 		AllowCredentials: false,
 		MaxAge:           86400,
 	})
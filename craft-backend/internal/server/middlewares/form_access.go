@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"craft/internal/authz"
+	"craft/internal/store"
+	"errors"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// FormAccess resolves the form named by the :id route parameter and rejects
+// callers that may not read it. Forms the caller cannot see are reported as
+// not found so their existence does not leak. On success it sets the "form"
+// (*model.Form) and "form_access" (authz.Access) locals. Requires
+// AuthMiddleware.
+func FormAccess(authorizer *authz.Authorizer) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(uuid.UUID)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "User not authenticated",
+			})
+		}
+		role, _ := c.Locals("user_role").(string)
+
+		formID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid form ID",
+			})
+		}
+
+		f, access, err := authorizer.Form(c.Context(), formID, authz.Principal{UserID: userID, Role: role})
+		if errors.Is(err, store.ErrNotFound) || (err == nil && !access.CanRead()) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Form not found",
+			})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to fetch form",
+			})
+		}
+
+		c.Locals("form", f)
+		c.Locals("form_access", access)
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,20 @@
+package middlewares
+
+import (
+	"craft/internal/authz"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// FormOwner rejects callers who may read but not change the form resolved by
+// FormAccess, which must run first.
+func FormOwner() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if access, _ := c.Locals("form_access").(authz.Access); access < authz.AccessOwner {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Only the form owner can do this",
+			})
+		}
+		return c.Next()
+	}
+}
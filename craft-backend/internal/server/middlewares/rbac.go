@@ -52,6 +52,29 @@ func AuthMiddleware(supabase *supabase.Client, database *db.Database) fiber.Hand
 	}
 }
 
+// OptionalAuthMiddleware identifies the caller when a valid bearer token is
+// sent and otherwise lets the request through anonymously. It sets the same
+// locals as AuthMiddleware, except user_role.
+func OptionalAuthMiddleware(supabase *supabase.Client) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		token := strings.TrimPrefix(c.Get("Authorization"), "Bearer ")
+		if token == "" || token == c.Get("Authorization") {
+			return c.Next()
+		}
+
+		user, err := supabase.Auth.WithToken(token).GetUser()
+		if err != nil || user == nil {
+			return c.Next()
+		}
+
+		c.Locals("user_id", user.ID)
+		c.Locals("user_email", user.Email)
+		c.Locals("user", user)
+
+		return c.Next()
+	}
+}
+
 func RBACMiddleware(allowedRoles ...string) fiber.Handler {
 	return func(c fiber.Ctx) error {
 		userRole := c.Locals("user_role")
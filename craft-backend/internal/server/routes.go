@@ -1,10 +1,12 @@
 package server
 
 import (
+	"craft/internal/authz"
 	auth "craft/internal/server/handlers"
 	"craft/internal/server/handlers/admin"
 	"craft/internal/server/handlers/user"
 	"craft/internal/server/middlewares"
+	"craft/pkg"
 )
 
 func (s *FiberServer) RegisterFiberRoutes() {
@@ -19,10 +21,20 @@ func (s *FiberServer) RegisterFiberRoutes() {
 func (s *FiberServer) registerAPIv1Routes() {
 	v1 := s.App.Group("/api/v1")
 	authHandler := auth.NewAuthHandler(s.Supabase, s.DB)
-	adminHandler := admin.NewAdminHandler(s.Supabase, s.DB)
-	userHandler := user.NewUserHandler(s.Supabase, s.DB)
-	formHandler := user.NewFormHandler(s.Supabase, s.DB)
-	submissionHandler := user.NewSubmissionHandler(s.Supabase, s.DB)
+	adminHandler := admin.NewAdminHandler(s.Supabase, s.Stores.Forms, s.Stores.Users, s.Stores.Jobs)
+	userHandler := user.NewUserHandler(s.Supabase, s.Stores.Forms, s.Stores.Submissions)
+	formHandler := user.NewFormHandler(s.Supabase, s.Stores.Forms)
+	revisionHandler := user.NewRevisionHandler(s.Supabase, s.Stores.Forms, s.Stores.Revisions)
+	submissionHandler := user.NewSubmissionHandler(s.Supabase, s.Stores.Forms, s.Stores.Submissions, s.Stores.Webhooks, s.Stores.Autoresponders, s.Stores.Jobs, s.Stores.Uploads, s.Blobs, s.Links, s.Stores.Partials, s.Stores.Notifications)
+	uploadHandler := user.NewUploadHandler(s.Supabase, s.Stores.Forms, s.Stores.Uploads, s.Blobs, s.Links)
+	partialHandler := user.NewPartialHandler(s.Supabase, s.Stores.Forms, s.Stores.Partials, s.Stores.Users, s.Stores.Jobs, s.Stores.Notifications, pkg.Envs.APP_URL)
+	exportHandler := user.NewExportHandler(s.Supabase, s.Stores.Forms, s.Stores.Submissions, s.Stores.Revisions, s.Stores.Uploads)
+	analyticsHandler := user.NewAnalyticsHandler(s.Supabase, s.Stores.Forms, s.Stores.Analytics)
+	webhookHandler := user.NewWebhookHandler(s.Supabase, s.Stores.Webhooks, s.Stores.Jobs)
+	autoresponderHandler := user.NewAutoresponderHandler(s.Supabase, s.Stores.Autoresponders)
+	digestHandler := user.NewDigestHandler(s.Supabase, s.Stores.Digests)
+	formAccess := middlewares.FormAccess(authz.New(s.Stores.Forms))
+	formOwner := middlewares.FormOwner()
 
 	// checkups
 	v1.Get("/ping", s.PingPongHandler)
@@ -39,26 +51,57 @@ func (s *FiberServer) registerAPIv1Routes() {
 
 	// users
 	userGroup := v1.Group("/user")
-	userGroup.Use(middlewares.AuthMiddleware(s.Supabase, s.DB))
+	userGroup.Use(s.Auth)
 	userGroup.Use(middlewares.RBACMiddleware("user", "admin"))
 	userGroup.Get("/dashboard", userHandler.GetDashboardData)
+	userGroup.Get("/dashboard/responses", analyticsHandler.GetResponseSeries)
+	userGroup.Get("/digest", digestHandler.GetDigestPreference)
+	userGroup.Put("/digest", digestHandler.UpdateDigestPreference)
 	userGroup.Post("/forms", formHandler.CreateForm)
-	userGroup.Get("/forms/:id", formHandler.GetForm)
+	userGroup.Get("/forms/:id", formAccess, formHandler.GetForm)
 	userGroup.Put("/forms/:id", formHandler.UpdateForm)
+	userGroup.Patch("/forms/:id/settings", formHandler.UpdateFormSettings)
 	userGroup.Post("/forms/:id/duplicate", formHandler.DuplicateForm)
 	userGroup.Put("/forms/:id/publish", formHandler.PublishForm)
 	userGroup.Put("/forms/:id/unpublish", formHandler.UnpublishForm)
 	userGroup.Delete("/forms/:id", formHandler.DeleteForm)
-	userGroup.Get("/forms/:id/submissions", submissionHandler.GetFormSubmissions)
+	userGroup.Get("/forms/:id/revisions", formAccess, revisionHandler.ListRevisions)
+	userGroup.Get("/forms/:id/revisions/diff", formAccess, revisionHandler.DiffRevisions)
+	userGroup.Get("/forms/:id/revisions/:revision", formAccess, revisionHandler.GetRevision)
+	userGroup.Post("/forms/:id/revisions/:revision/restore", revisionHandler.RestoreRevision)
+	userGroup.Get("/forms/:id/submissions", formAccess, submissionHandler.GetFormSubmissions)
+	userGroup.Get("/forms/:id/submissions/export.csv", formAccess, exportHandler.ExportCSV)
+	userGroup.Get("/forms/:id/submissions/export.xlsx", formAccess, exportHandler.ExportXLSX)
+	userGroup.Get("/forms/:id/submissions/export.ndjson", formAccess, exportHandler.ExportNDJSON)
+	userGroup.Get("/forms/:id/analytics", formAccess, analyticsHandler.GetFormAnalytics)
+	userGroup.Get("/forms/:id/analytics/responses", formAccess, analyticsHandler.GetFormResponseSeries)
+	userGroup.Get("/forms/:id/analytics/funnel", formAccess, analyticsHandler.GetFormFunnel)
+	userGroup.Get("/forms/:id/webhooks", formAccess, webhookHandler.ListWebhooks)
+	userGroup.Post("/forms/:id/webhooks", formAccess, formOwner, webhookHandler.CreateWebhook)
+	userGroup.Patch("/forms/:id/webhooks/:webhookId", formAccess, formOwner, webhookHandler.UpdateWebhook)
+	userGroup.Delete("/forms/:id/webhooks/:webhookId", formAccess, formOwner, webhookHandler.DeleteWebhook)
+	userGroup.Get("/forms/:id/webhooks/:webhookId/deliveries", formAccess, webhookHandler.ListDeliveries)
+	userGroup.Post("/forms/:id/webhooks/:webhookId/deliveries/:deliveryId/redeliver", formAccess, formOwner, webhookHandler.Redeliver)
+	userGroup.Get("/forms/:id/autoresponder", formAccess, autoresponderHandler.GetAutoresponder)
+	userGroup.Put("/forms/:id/autoresponder", formAccess, formOwner, autoresponderHandler.PutAutoresponder)
+	userGroup.Delete("/forms/:id/autoresponder", formAccess, formOwner, autoresponderHandler.DeleteAutoresponder)
 
 	// public
 	publicGroup := v1.Group("/public")
 	publicGroup.Get("/forms/:username/:slug", formHandler.GetPublicForm)
-	publicGroup.Post("/forms/:id/submit", submissionHandler.SubmitForm)
+	publicGroup.Post("/forms/:id/submit", middlewares.OptionalAuthMiddleware(s.Supabase), submissionHandler.SubmitForm)
+	publicGroup.Post("/forms/:id/events", analyticsHandler.RecordFormEvent)
+	publicGroup.Post("/forms/:id/uploads", uploadHandler.UploadFile)
+	publicGroup.Post("/forms/:id/uploads/presign", uploadHandler.PresignUpload)
+	publicGroup.Get("/uploads/:id", uploadHandler.DownloadUpload)
+	publicGroup.Post("/forms/:id/partials", partialHandler.CreatePartial)
+	publicGroup.Get("/forms/:id/partials/:token", partialHandler.GetPartial)
+	publicGroup.Put("/forms/:id/partials/:token", partialHandler.UpdatePartial)
+	publicGroup.Delete("/forms/:id/partials/:token", partialHandler.DeletePartial)
 
 	// admin
 	admin := v1.Group("/admin")
-	admin.Use(middlewares.AuthMiddleware(s.Supabase, s.DB))
+	admin.Use(s.Auth)
 	admin.Use(middlewares.RBACMiddleware("admin"))
 	admin.Get("/users", adminHandler.GetAllUsers)
 	admin.Get("/forms", adminHandler.GetAllForms)
@@ -67,5 +110,7 @@ func (s *FiberServer) registerAPIv1Routes() {
 	admin.Delete("/users/:id", adminHandler.DeleteUser)
 	admin.Delete("/forms/:id", adminHandler.DeleteForm)
 	admin.Get("/published-count", adminHandler.GetPublishedFormsCount)
+	admin.Get("/jobs/dead", adminHandler.GetDeadJobs)
+	admin.Post("/jobs/:id/retry", adminHandler.RetryJob)
 
 }
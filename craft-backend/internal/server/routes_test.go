@@ -0,0 +1,253 @@
+package server
+
+import (
+	"craft/internal/authz"
+	"craft/internal/model"
+	"craft/internal/store"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// testAuth stands in for AuthMiddleware. The bearer token is the caller's
+// user ID, followed by ":admin" for administrators.
+func testAuth(c fiber.Ctx) error {
+	token, ok := strings.CutPrefix(c.Get("Authorization"), "Bearer ")
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	id, role, _ := strings.Cut(token, ":")
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	if role == "" {
+		role = "user"
+	}
+	c.Locals("user_id", userID)
+	c.Locals("user_role", role)
+	return c.Next()
+}
+
+// formFixture is a form with a revision, a submission, a webhook with a
+// delivery and an autoresponder, so every form route has something to find.
+type formFixture struct {
+	app      *fiber.App
+	owner    uuid.UUID
+	form     *model.Form
+	webhook  uuid.UUID
+	delivery uuid.UUID
+}
+
+func newFormFixture(t *testing.T) *formFixture {
+	t.Helper()
+	ctx := t.Context()
+	st := store.NewMemory().Stores()
+	s := &FiberServer{App: fiber.New(), Stores: st, Auth: testAuth}
+	s.RegisterFiberRoutes()
+
+	owner := uuid.New()
+	f, err := st.Forms.Create(ctx, owner, "Team lunch", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Questions = []model.Question{{Type: model.QuestionTypeEmail, Title: "Email"}}
+	if err := st.Forms.Update(ctx, owner, f); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Forms.SetStatus(ctx, f.ID, owner, model.FormStatusPublished, true, f.Version); err != nil {
+		t.Fatal(err)
+	}
+	if f, err = st.Forms.Get(ctx, f.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	sub := model.Submission{FormID: f.ID}
+	if err := st.Submissions.Create(ctx, &sub, []model.Answer{{QuestionID: f.Questions[0].ID, Value: json.RawMessage(`"ada@example.com"`)}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	hook := model.Webhook{FormID: f.ID, URL: "https://hooks.example.com/craft", Secret: "s3cret", Events: model.WebhookEvents, Active: true}
+	if err := st.Webhooks.Create(ctx, &hook); err != nil {
+		t.Fatal(err)
+	}
+	deliveries, err := st.Webhooks.Enqueue(ctx, f.ID, model.WebhookEventSubmissionCreated, json.RawMessage(`{}`))
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("enqueued %d deliveries, err %v", len(deliveries), err)
+	}
+	ar := model.Autoresponder{FormID: f.ID, Enabled: true, QuestionID: &f.Questions[0].ID, Subject: "Thanks", Body: "Got it"}
+	if err := st.Autoresponders.Put(ctx, &ar); err != nil {
+		t.Fatal(err)
+	}
+
+	return &formFixture{app: s.App, owner: owner, form: f, webhook: hook.ID, delivery: deliveries[0]}
+}
+
+// replace fills the route parameters in s with the fixture's IDs.
+func (fx *formFixture) replace(s string) string {
+	return strings.NewReplacer(
+		":id", fx.form.ID.String(),
+		":webhookId", fx.webhook.String(),
+		":deliveryId", fx.delivery.String(),
+		":questionId", fx.form.Questions[0].ID.String(),
+	).Replace(s)
+}
+
+func (fx *formFixture) path(format string) string {
+	return "/api/v1/user/forms/" + fx.replace(format)
+}
+
+func (fx *formFixture) send(t *testing.T, token, method, path, body string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", "*")
+	resp, err := fx.app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(b)
+}
+
+// formReads are the routes that read a form or data collected by it.
+var formReads = []string{
+	":id",
+	":id/revisions",
+	":id/revisions/diff?from=1&to=2",
+	":id/revisions/1",
+	":id/submissions",
+	":id/submissions/export.csv",
+	":id/submissions/export.xlsx",
+	":id/submissions/export.ndjson",
+	":id/analytics",
+	":id/analytics/responses",
+	":id/analytics/funnel",
+	":id/webhooks",
+	":id/webhooks/:webhookId/deliveries",
+	":id/autoresponder",
+}
+
+// formWrites are the routes that change a form or its integrations, with a
+// body the owner may send. Route parameters in the body are filled in too.
+var formWrites = []struct{ method, path, body string }{
+	{"PUT", ":id", `{"title":"Team dinner","questions":[]}`},
+	{"PATCH", ":id/settings", `{"allow_multiple_submissions":true}`},
+	{"POST", ":id/duplicate", ``},
+	{"PUT", ":id/publish", ``},
+	{"PUT", ":id/unpublish", ``},
+	{"POST", ":id/revisions/1/restore", ``},
+	{"POST", ":id/webhooks", `{"url":"https://hooks.example.com/other","events":["submission.created"]}`},
+	{"PATCH", ":id/webhooks/:webhookId", `{"active":false}`},
+	{"POST", ":id/webhooks/:webhookId/deliveries/:deliveryId/redeliver", ``},
+	{"PUT", ":id/autoresponder", `{"enabled":false,"question_id":":questionId","subject":"Thanks","body":"Got it"}`},
+	{"DELETE", ":id/webhooks/:webhookId", ``},
+	{"DELETE", ":id/autoresponder", ``},
+	{"DELETE", ":id", ``},
+}
+
+func TestFormRoutesHideOtherUsersForms(t *testing.T) {
+	fx := newFormFixture(t)
+	stranger := uuid.NewString()
+
+	for _, path := range formReads {
+		t.Run("GET "+path, func(t *testing.T) {
+			if code, body := fx.send(t, fx.owner.String(), "GET", fx.path(path), ""); code != fiber.StatusOK {
+				t.Fatalf("owner got %d: %s", code, body)
+			}
+			code, body := fx.send(t, stranger, "GET", fx.path(path), "")
+			if code != fiber.StatusNotFound {
+				t.Fatalf("another user got %d: %s", code, body)
+			}
+			if strings.Contains(body, "Team lunch") || strings.Contains(body, "ada@example.com") {
+				t.Fatalf("another user saw the form: %s", body)
+			}
+		})
+	}
+
+	for _, w := range formWrites {
+		t.Run(w.method+" "+w.path, func(t *testing.T) {
+			code, body := fx.send(t, stranger, w.method, fx.path(w.path), fx.replace(w.body))
+			if code != fiber.StatusNotFound {
+				t.Fatalf("another user got %d: %s", code, body)
+			}
+		})
+	}
+
+	// nothing was changed along the way
+	code, body := fx.send(t, fx.owner.String(), "GET", fx.path(":id"), "")
+	if code != fiber.StatusOK || !strings.Contains(body, `"version":`+itoa(fx.form.Version)) {
+		t.Fatalf("form changed: %d %s", code, body)
+	}
+}
+
+func TestFormRoutesLetAdminsReadButNotWrite(t *testing.T) {
+	fx := newFormFixture(t)
+	admin := uuid.NewString() + ":" + authz.RoleAdmin
+
+	for _, path := range formReads {
+		if code, body := fx.send(t, admin, "GET", fx.path(path), ""); code != fiber.StatusOK {
+			t.Errorf("GET %s: admin got %d: %s", path, code, body)
+		}
+	}
+
+	for _, w := range formWrites {
+		t.Run(w.method+" "+w.path, func(t *testing.T) {
+			code, body := fx.send(t, admin, w.method, fx.path(w.path), fx.replace(w.body))
+			if code != fiber.StatusForbidden && code != fiber.StatusNotFound {
+				t.Fatalf("admin got %d: %s", code, body)
+			}
+		})
+	}
+
+	code, body := fx.send(t, fx.owner.String(), "GET", fx.path(":id"), "")
+	if code != fiber.StatusOK || !strings.Contains(body, `"version":`+itoa(fx.form.Version)) {
+		t.Fatalf("form changed: %d %s", code, body)
+	}
+	code, body = fx.send(t, fx.owner.String(), "GET", fx.path(":id/webhooks"), "")
+	if code != fiber.StatusOK || !strings.Contains(body, fx.webhook.String()) || !strings.Contains(body, `"active":true`) {
+		t.Fatalf("webhook changed: %d %s", code, body)
+	}
+	code, body = fx.send(t, fx.owner.String(), "GET", fx.path(":id/autoresponder"), "")
+	if code != fiber.StatusOK || !strings.Contains(body, `"enabled":true`) {
+		t.Fatalf("autoresponder changed: %d %s", code, body)
+	}
+}
+
+func TestFormRoutesLetOwnersWrite(t *testing.T) {
+	for _, w := range formWrites {
+		// a fresh form each time, as replacing the questions would leave
+		// nothing for the autoresponder to send to
+		fx := newFormFixture(t)
+		code, body := fx.send(t, fx.owner.String(), w.method, fx.path(w.path), fx.replace(w.body))
+		if code >= 400 {
+			t.Errorf("%s %s: owner got %d: %s", w.method, w.path, code, body)
+		}
+	}
+}
+
+func TestWebhookRoutesRejectPrivateURLs(t *testing.T) {
+	fx := newFormFixture(t)
+	owner := fx.owner.String()
+
+	for _, url := range []string{"http://localhost:8080/hook", "http://10.0.0.8/hook", "http://169.254.169.254/latest/meta-data/"} {
+		code, body := fx.send(t, owner, "POST", fx.path(":id/webhooks"), `{"url":"`+url+`"}`)
+		if code != fiber.StatusBadRequest || !strings.Contains(body, "private_address") {
+			t.Errorf("creating %s: %d %s", url, code, body)
+		}
+		code, body = fx.send(t, owner, "PATCH", fx.path(":id/webhooks/:webhookId"), `{"url":"`+url+`"}`)
+		if code != fiber.StatusBadRequest || !strings.Contains(body, "private_address") {
+			t.Errorf("updating to %s: %d %s", url, code, body)
+		}
+	}
+}
+
+func itoa(n int) string {
+	b, _ := json.Marshal(n)
+	return string(b)
+}
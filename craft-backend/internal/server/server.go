@@ -2,6 +2,10 @@ package server
 
 import (
 	"craft/internal/db"
+	"craft/internal/server/middlewares"
+	"craft/internal/store"
+	"craft/internal/uploads"
+	"craft/internal/validation"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/supabase-community/supabase-go"
@@ -10,18 +14,30 @@ import (
 type FiberServer struct {
 	*fiber.App
 	DB       *db.Database
+	Stores   store.Stores
 	Supabase *supabase.Client
+	Blobs    uploads.BlobStore
+	Links    *uploads.Links
+	// Auth authenticates the user and admin routes.
+	Auth fiber.Handler
 }
 
-func New(db *db.Database, supabaseClient *supabase.Client) *FiberServer {
+func New(db *db.Database, supabaseClient *supabase.Client, blobs uploads.BlobStore, links *uploads.Links) *FiberServer {
 
 	server := &FiberServer{
 		App: fiber.New(fiber.Config{
 			ServerHeader: "craft",
 			AppName:      "craft",
+			// room for the largest file a question may accept, plus the
+			// rest of the multipart form
+			BodyLimit: validation.MaxFileSize + 1<<20,
 		}),
 		DB:       db,
+		Stores:   store.NewPgStores(db),
 		Supabase: supabaseClient,
+		Blobs:    blobs,
+		Links:    links,
+		Auth:     middlewares.AuthMiddleware(supabaseClient, db),
 	}
 
 	return server
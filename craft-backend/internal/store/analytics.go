@@ -0,0 +1,237 @@
+package store
+
+import (
+	"craft/internal/model"
+	"craft/internal/model/payload"
+	"craft/internal/validation"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// TopTermsLimit caps the most common terms reported per text question.
+	TopTermsLimit = 10
+	// MaxDistributionValues caps the distinct values reported in a numeric
+	// distribution, lowest first.
+	MaxDistributionValues = 50
+
+	minTermLength = 3
+)
+
+// stopWords are left out of the most common terms of text answers.
+var stopWords = []string{
+	"about", "and", "are", "but", "can", "for", "from", "had", "has", "have",
+	"her", "his", "its", "not", "our", "out", "she", "that", "the", "their",
+	"them", "then", "there", "they", "this", "was", "were", "what", "when",
+	"which", "who", "will", "with", "would", "you", "your",
+}
+
+func isChoiceQuestion(q model.Question) bool {
+	return q.Type == model.QuestionTypeSingleSelect || q.Type == model.QuestionTypeMultiSelect || q.Type == model.QuestionTypeDropdown
+}
+
+func isTextQuestion(q model.Question) bool {
+	return q.Type == model.QuestionTypeShortText || q.Type == model.QuestionTypeLongText
+}
+
+// newFormAnalytics lays out one entry per live question of f, with option
+// counts zeroed for choice questions.
+func newFormAnalytics(f *model.Form) (*model.FormAnalytics, map[uuid.UUID]*model.QuestionAnalytics) {
+	a := &model.FormAnalytics{FormID: f.ID, Questions: make([]model.QuestionAnalytics, 0, len(f.Questions))}
+	for _, q := range f.Questions {
+		if q.ArchivedAt != nil {
+			continue
+		}
+		qa := model.QuestionAnalytics{QuestionID: q.ID, Title: q.Title, Type: q.Type, Required: q.Required}
+		if isChoiceQuestion(q) {
+			qa.Options = make([]model.OptionCount, 0, len(q.Options))
+			for _, opt := range q.Options {
+				if opt.ArchivedAt == nil {
+					qa.Options = append(qa.Options, model.OptionCount{OptionID: opt.ID, Label: opt.Label})
+				}
+			}
+		}
+		if isTextQuestion(q) {
+			qa.Text = &model.TextStats{TopTerms: []model.TermCount{}}
+		}
+		a.Questions = append(a.Questions, qa)
+	}
+
+	byID := make(map[uuid.UUID]*model.QuestionAnalytics, len(a.Questions))
+	for i := range a.Questions {
+		byID[a.Questions[i].QuestionID] = &a.Questions[i]
+	}
+	return a, byID
+}
+
+// countChoice adds n to the option a choice names, by label or option ID.
+func countChoice(qa *model.QuestionAnalytics, choice string, n int) {
+	i := slices.IndexFunc(qa.Options, func(o model.OptionCount) bool {
+		return o.Label == choice || o.OptionID.String() == choice
+	})
+	if i >= 0 {
+		qa.Options[i].Count += n
+	}
+}
+
+// showsConditionally reports whether f has questions shown only on
+// conditions or pages that jumps can skip, so which ones a response was
+// shown depends on its answers.
+func showsConditionally(f *model.Form) bool {
+	return slices.ContainsFunc(f.Questions, func(q model.Question) bool { return q.Visibility != nil }) ||
+		slices.ContainsFunc(f.Sections, func(s model.Section) bool { return len(s.Jumps) > 0 })
+}
+
+// shownTo returns the questions of f a response with answers was shown.
+func shownTo(f *model.Form, answers []model.Answer) []model.Question {
+	if !showsConditionally(f) {
+		return f.Questions
+	}
+	given := make([]payload.AnswerInput, len(answers))
+	for i, a := range answers {
+		given[i] = payload.AnswerInput{QuestionID: a.QuestionID, Value: a.Value}
+	}
+	shown, _ := validation.VisibleQuestions(f, given)
+	return shown
+}
+
+// countShown adds n responses shown questions to the analytics of those that
+// are live. Responses without a recorded revision count as shown every
+// question, as nothing tells which ones their form had.
+func countShown(byID map[uuid.UUID]*model.QuestionAnalytics, questions []model.Question, n int) {
+	for _, q := range questions {
+		if qa, ok := byID[q.ID]; ok {
+			qa.Shown += n
+		}
+	}
+}
+
+// finishAnalytics derives skip rates, option percentages and average word
+// counts once responses and counts are filled in.
+func finishAnalytics(a *model.FormAnalytics) {
+	for i := range a.Questions {
+		qa := &a.Questions[i]
+		qa.Skipped = max(qa.Shown-qa.Answered, 0)
+		qa.SkipRate = rate(qa.Skipped, qa.Shown)
+		for j := range qa.Options {
+			if qa.Answered > 0 {
+				qa.Options[j].Percentage = 100 * float64(qa.Options[j].Count) / float64(qa.Answered)
+			}
+		}
+		if qa.Text != nil && qa.Answered > 0 {
+			qa.Text.AverageWords = float64(qa.Text.TotalWords) / float64(qa.Answered)
+		}
+	}
+}
+
+// MaxSeriesPoints caps the number of buckets a ResponseSeries may span.
+const MaxSeriesPoints = 1000
+
+// SeriesQuery selects the buckets of a ResponseSeries. From is rounded down
+// to the start of its bucket in Location.
+type SeriesQuery struct {
+	Interval string // one of the model.SeriesInterval constants
+	Location *time.Location
+	From     time.Time
+	To       time.Time
+}
+
+// truncateBucket returns the start of the bucket containing t, in loc.
+// Weeks start on Monday, as with Postgres date_trunc.
+func truncateBucket(t time.Time, interval string, loc *time.Location) time.Time {
+	t = t.In(loc)
+	switch interval {
+	case model.SeriesIntervalHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+	case model.SeriesIntervalWeek:
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+}
+
+func nextBucket(t time.Time, interval string) time.Time {
+	switch interval {
+	case model.SeriesIntervalHour:
+		return t.Add(time.Hour)
+	case model.SeriesIntervalWeek:
+		return t.AddDate(0, 0, 7)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// previousPeriod returns the period of equal length ending at q.From.
+func (q SeriesQuery) previousPeriod() (time.Time, time.Time) {
+	from := truncateBucket(q.From, q.Interval, q.Location)
+	return from.Add(-q.To.Sub(from)), from
+}
+
+// buildSeries lays out every bucket of q, zero-filled, with counts keyed by
+// bucket start.
+func buildSeries(q SeriesQuery, counts map[int64]int, previousTotal int) *model.ResponseSeries {
+	from := truncateBucket(q.From, q.Interval, q.Location)
+	series := &model.ResponseSeries{
+		Interval:      q.Interval,
+		Timezone:      q.Location.String(),
+		From:          from,
+		To:            q.To.In(q.Location),
+		Points:        []model.SeriesPoint{},
+		PreviousTotal: previousTotal,
+	}
+
+	for start := from; start.Before(q.To) && len(series.Points) < MaxSeriesPoints; start = nextBucket(start, q.Interval) {
+		n := counts[start.Unix()]
+		series.Points = append(series.Points, model.SeriesPoint{Start: start, Count: n})
+		series.Total += n
+	}
+
+	series.Delta = series.Total - previousTotal
+	if previousTotal > 0 {
+		pct := 100 * float64(series.Delta) / float64(previousTotal)
+		series.DeltaPercent = &pct
+	}
+	return series
+}
+
+// newFormFunnel lays out one drop-off entry per live question of f, in order.
+func newFormFunnel(f *model.Form) (*model.FormFunnel, map[uuid.UUID]*model.QuestionDropOff) {
+	fn := &model.FormFunnel{FormID: f.ID, Questions: make([]model.QuestionDropOff, 0, len(f.Questions))}
+	for _, q := range f.Questions {
+		if q.ArchivedAt == nil {
+			fn.Questions = append(fn.Questions, model.QuestionDropOff{QuestionID: q.ID, Title: q.Title, Position: q.Position})
+		}
+	}
+	slices.SortStableFunc(fn.Questions, func(a, b model.QuestionDropOff) int { return a.Position - b.Position })
+
+	byID := make(map[uuid.UUID]*model.QuestionDropOff, len(fn.Questions))
+	for i := range fn.Questions {
+		byID[fn.Questions[i].QuestionID] = &fn.Questions[i]
+	}
+	return fn, byID
+}
+
+func rate(n, base int) float64 {
+	if base == 0 {
+		return 0
+	}
+	return float64(n) / float64(base)
+}
+
+// finishFunnel derives the rates and picks the worst drop-off question.
+func finishFunnel(fn *model.FormFunnel) {
+	fn.StartRate = rate(fn.Starts, fn.Views)
+	fn.CompletionRate = rate(fn.Completions, fn.Starts)
+	fn.ConversionRate = rate(fn.Completions, fn.Views)
+
+	for i := range fn.Questions {
+		q := &fn.Questions[i]
+		q.DropOffRate = rate(q.DroppedOff, q.Reached)
+		if q.DroppedOff > 0 && (fn.DropOff == nil || q.DroppedOff > fn.DropOff.DroppedOff) {
+			fn.DropOff = q
+		}
+	}
+}
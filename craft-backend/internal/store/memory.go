@@ -0,0 +1,1811 @@
+package store
+
+import (
+	"context"
+	"craft/internal/model"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// Memory is an in-process implementation of every store, intended for
+// handler tests and local experiments without Postgres.
+type Memory struct {
+	mu             sync.RWMutex
+	users          map[uuid.UUID]model.User
+	forms          map[uuid.UUID]model.Form
+	revisions      map[uuid.UUID][]model.FormRevision
+	submissions    []model.SubmissionWithAnswers
+	events         []model.FormEvent
+	webhooks       map[uuid.UUID]model.Webhook
+	deliveries     []model.WebhookDelivery
+	jobs           []model.Job
+	watermarks     map[string]SubmissionCursor
+	throttles      map[string]throttle
+	autoresponders map[uuid.UUID]model.Autoresponder
+	digests        map[uuid.UUID]model.DigestPreference
+	uploads        map[uuid.UUID]model.Upload
+	partials       map[uuid.UUID]model.PartialResponse
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		users:          make(map[uuid.UUID]model.User),
+		forms:          make(map[uuid.UUID]model.Form),
+		revisions:      make(map[uuid.UUID][]model.FormRevision),
+		webhooks:       make(map[uuid.UUID]model.Webhook),
+		watermarks:     make(map[string]SubmissionCursor),
+		throttles:      make(map[string]throttle),
+		autoresponders: make(map[uuid.UUID]model.Autoresponder),
+		digests:        make(map[uuid.UUID]model.DigestPreference),
+		uploads:        make(map[uuid.UUID]model.Upload),
+		partials:       make(map[uuid.UUID]model.PartialResponse),
+	}
+}
+
+func (m *Memory) Stores() Stores {
+	return Stores{
+		Forms:          memoryForms{m},
+		Submissions:    memorySubmissions{m},
+		Revisions:      memoryRevisions{m},
+		Analytics:      memoryAnalytics{m},
+		Webhooks:       memoryWebhooks{m},
+		Jobs:           memoryJobs{m},
+		Notifications:  memoryNotifications{m},
+		Autoresponders: memoryAutoresponders{m},
+		Digests:        memoryDigests{m},
+		Uploads:        memoryUploads{m},
+		Partials:       memoryPartials{m},
+		Users:          memoryUsers{m},
+	}
+}
+
+// PutUser inserts or replaces a user, standing in for the Supabase signup trigger.
+func (m *Memory) PutUser(u model.User) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.users[u.ID] = u
+}
+
+func slugify(s string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), " ", "_"))
+}
+
+func copyQuestions(questions []model.Question) []model.Question {
+	if questions == nil {
+		return nil
+	}
+	out := make([]model.Question, len(questions))
+	for i, q := range questions {
+		out[i] = q
+		if q.Options != nil {
+			out[i].Options = append([]model.Option(nil), q.Options...)
+		}
+		if q.Number != nil {
+			n := *q.Number
+			out[i].Number = &n
+		}
+		if q.Rating != nil {
+			r := *q.Rating
+			out[i].Rating = &r
+		}
+		if q.FileUpload != nil {
+			fu := *q.FileUpload
+			fu.AllowedTypes = slices.Clone(fu.AllowedTypes)
+			out[i].FileUpload = &fu
+		}
+		out[i].Visibility = q.Visibility.Remap(nil)
+	}
+	return out
+}
+
+func copySections(sections []model.Section) []model.Section {
+	if sections == nil {
+		return nil
+	}
+	out := make([]model.Section, len(sections))
+	for i, sec := range sections {
+		out[i] = sec.Remap(nil)
+	}
+	return out
+}
+
+// setSections mirrors the Postgres syncSections: the form's pages become
+// sections, in order, and questions on pages that are gone lose their page.
+func setSections(f *model.Form, sections []model.Section) {
+	f.Sections = copySections(sections)
+	kept := make(map[uuid.UUID]bool, len(f.Sections))
+	for i := range f.Sections {
+		f.Sections[i].FormID = f.ID
+		f.Sections[i].Position = i
+		kept[f.Sections[i].ID] = true
+	}
+	for i, q := range f.Questions {
+		if q.SectionID != nil && !kept[*q.SectionID] {
+			f.Questions[i].SectionID = nil
+		}
+	}
+}
+
+// copyForm returns a copy of f that only carries its live questions and options.
+func copyForm(f model.Form) *model.Form {
+	var live []model.Question
+	for _, q := range f.Questions {
+		if q.ArchivedAt != nil {
+			continue
+		}
+		var opts []model.Option
+		for _, opt := range q.Options {
+			if opt.ArchivedAt == nil {
+				opts = append(opts, opt)
+			}
+		}
+		q.Options = opts
+		live = append(live, q)
+	}
+	f.Questions = live
+	f.Sections = copySections(f.Sections)
+	return &f
+}
+
+// mergeQuestions mirrors the Postgres syncQuestions: incoming questions and
+// options replace stored ones with the same ID, new ones are added, and
+// stored ones that are left out are archived rather than dropped.
+func mergeQuestions(stored, incoming []model.Question, formID uuid.UUID, now time.Time) []model.Question {
+	byID := make(map[uuid.UUID]model.Question, len(stored))
+	for _, q := range stored {
+		byID[q.ID] = q
+	}
+
+	merged := make([]model.Question, 0, len(incoming)+len(stored))
+	seen := make(map[uuid.UUID]bool, len(incoming))
+	for i, q := range copyQuestions(incoming) {
+		if q.ID == uuid.Nil || seen[q.ID] {
+			q.ID = uuid.New()
+		}
+		seen[q.ID] = true
+		q.FormID = formID
+		q.Position = i
+		q.ArchivedAt = nil
+		q.Options = mergeOptions(byID[q.ID].Options, q.Options, q.ID, now)
+		merged = append(merged, q)
+	}
+
+	for _, q := range stored {
+		if seen[q.ID] {
+			continue
+		}
+		if q.ArchivedAt == nil {
+			q.ArchivedAt = &now
+		}
+		merged = append(merged, q)
+	}
+	return merged
+}
+
+func mergeOptions(stored, incoming []model.Option, questionID uuid.UUID, now time.Time) []model.Option {
+	merged := make([]model.Option, 0, len(incoming)+len(stored))
+	seen := make(map[uuid.UUID]bool, len(incoming))
+	for j, opt := range incoming {
+		if opt.ID == uuid.Nil || seen[opt.ID] {
+			opt.ID = uuid.New()
+		}
+		seen[opt.ID] = true
+		opt.QuestionID = questionID
+		opt.Position = j
+		opt.ArchivedAt = nil
+		merged = append(merged, opt)
+	}
+
+	for _, opt := range stored {
+		if seen[opt.ID] {
+			continue
+		}
+		if opt.ArchivedAt == nil {
+			opt.ArchivedAt = &now
+		}
+		merged = append(merged, opt)
+	}
+	return merged
+}
+
+type memoryForms struct{ m *Memory }
+
+func (s memoryForms) Create(ctx context.Context, ownerID uuid.UUID, title string, description *string) (*model.Form, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	now := time.Now()
+	f := model.Form{
+		ID:          uuid.New(),
+		OwnerID:     ownerID,
+		Title:       title,
+		Description: description,
+		Status:      "draft",
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Version:     1,
+	}
+	s.m.forms[f.ID] = f
+	return copyForm(f), nil
+}
+
+func (s memoryForms) Get(ctx context.Context, formID uuid.UUID) (*model.Form, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	f, ok := s.m.forms[formID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyForm(f), nil
+}
+
+func (s memoryForms) ArchivedQuestions(ctx context.Context, formID uuid.UUID) ([]model.Question, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	var archived []model.Question
+	for _, q := range copyQuestions(s.m.forms[formID].Questions) {
+		if q.ArchivedAt != nil {
+			archived = append(archived, q)
+		}
+	}
+	sort.SliceStable(archived, func(i, j int) bool {
+		return archived[i].Position < archived[j].Position
+	})
+	return archived, nil
+}
+
+func (s memoryForms) GetByOwner(ctx context.Context, formID, ownerID uuid.UUID) (*model.Form, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	f, ok := s.m.forms[formID]
+	if !ok || f.OwnerID != ownerID {
+		return nil, ErrNotFound
+	}
+	return copyForm(f), nil
+}
+
+func (s memoryForms) GetPublished(ctx context.Context, username, slug string) (*model.PublicForm, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	for _, f := range s.m.forms {
+		owner, ok := s.m.users[f.OwnerID]
+		if !ok || f.Status != "published" || !f.IsPublic {
+			continue
+		}
+		if slugify(owner.FirstName) == strings.ToLower(username) && slugify(f.Title) == strings.ToLower(slug) {
+			return copyForm(f).Public(), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s memoryForms) ListByOwner(ctx context.Context, ownerID uuid.UUID) ([]model.Form, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	counts := make(map[uuid.UUID]int)
+	for _, sub := range s.m.submissions {
+		counts[sub.FormID]++
+	}
+
+	var forms []model.Form
+	for _, f := range s.m.forms {
+		if f.OwnerID != ownerID {
+			continue
+		}
+		f.Questions = nil
+		f.Sections = nil
+		f.Responses = counts[f.ID]
+		forms = append(forms, f)
+	}
+	sort.Slice(forms, func(i, j int) bool {
+		return forms[i].UpdatedAt.After(forms[j].UpdatedAt)
+	})
+	return forms, nil
+}
+
+func (s memoryForms) ListAll(ctx context.Context) ([]model.Form, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	var forms []model.Form
+	for _, f := range s.m.forms {
+		f.Questions = nil
+		f.Sections = nil
+		forms = append(forms, f)
+	}
+	sort.Slice(forms, func(i, j int) bool {
+		return forms[i].CreatedAt.After(forms[j].CreatedAt)
+	})
+	return forms, nil
+}
+
+func (s memoryForms) Update(ctx context.Context, ownerID uuid.UUID, f *model.Form) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	current, err := s.m.versionedFormLocked(f.ID, ownerID, f.Version)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	current.Version++
+	f.Version = current.Version
+	current.Title = f.Title
+	current.Description = f.Description
+	current.UpdatedAt = now
+	current.Questions = mergeQuestions(current.Questions, f.Questions, f.ID, now)
+	setSections(&current, f.Sections)
+
+	s.m.forms[f.ID] = current
+	s.m.snapshotLocked(f.ID, model.RevisionReasonSave)
+	*f = *copyForm(current)
+	return nil
+}
+
+func (s memoryForms) UpdateSettings(ctx context.Context, ownerID uuid.UUID, f *model.Form) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	current, err := s.m.versionedFormLocked(f.ID, ownerID, f.Version)
+	if err != nil {
+		return err
+	}
+
+	current.Version++
+	f.Version = current.Version
+	current.IsPublic = f.IsPublic
+	current.AllowMultipleSubmissions = f.AllowMultipleSubmissions
+	current.CloseDate = f.CloseDate
+	current.ThankYouMessage = f.ThankYouMessage
+	current.RedirectURL = f.RedirectURL
+	current.NotifyOwner = f.NotifyOwner
+	current.UpdatedAt = time.Now()
+	f.UpdatedAt = current.UpdatedAt
+
+	s.m.forms[f.ID] = current
+	return nil
+}
+
+func (s memoryForms) Duplicate(ctx context.Context, formID, ownerID uuid.UUID) (*model.Form, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	original, ok := s.m.forms[formID]
+	if !ok || original.OwnerID != ownerID {
+		return nil, ErrNotFound
+	}
+
+	now := time.Now()
+	dup := model.Form{
+		ID:          uuid.New(),
+		OwnerID:     ownerID,
+		Title:       "Copy of " + original.Title,
+		Description: original.Description,
+		Status:      "draft",
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Version:     1,
+		Questions:   copyForm(original).Questions,
+	}
+	ids := make(map[uuid.UUID]uuid.UUID)
+	for _, sec := range original.Sections {
+		ids[sec.ID] = uuid.New()
+	}
+	for i := range dup.Questions {
+		q := &dup.Questions[i]
+		ids[q.ID] = uuid.New()
+		q.ID = ids[q.ID]
+		q.FormID = dup.ID
+		for j := range q.Options {
+			ids[q.Options[j].ID] = uuid.New()
+			q.Options[j].ID = ids[q.Options[j].ID]
+			q.Options[j].QuestionID = q.ID
+		}
+	}
+	for i := range dup.Questions {
+		q := &dup.Questions[i]
+		q.Visibility = q.Visibility.Remap(ids)
+		if q.SectionID != nil {
+			id := ids[*q.SectionID]
+			q.SectionID = &id
+		}
+	}
+	for _, sec := range original.Sections {
+		sec = sec.Remap(ids)
+		sec.FormID = dup.ID
+		dup.Sections = append(dup.Sections, sec)
+	}
+	s.m.forms[dup.ID] = dup
+
+	dup.Questions = nil
+	dup.Sections = nil
+	return &dup, nil
+}
+
+func (s memoryForms) SetStatus(ctx context.Context, formID, ownerID uuid.UUID, status string, isPublic bool, version int) (int, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	f, err := s.m.versionedFormLocked(formID, ownerID, version)
+	if err != nil {
+		return 0, err
+	}
+	f.Status = status
+	f.IsPublic = isPublic
+	f.UpdatedAt = time.Now()
+	f.Version++
+	s.m.forms[formID] = f
+	if status == model.FormStatusPublished {
+		s.m.snapshotLocked(formID, model.RevisionReasonPublish)
+	}
+	return f.Version, nil
+}
+
+// versionedFormLocked returns the owner's form if version is 0 or current.
+func (m *Memory) versionedFormLocked(formID, ownerID uuid.UUID, version int) (model.Form, error) {
+	f, ok := m.forms[formID]
+	if !ok || f.OwnerID != ownerID {
+		return model.Form{}, ErrNotFound
+	}
+	if version != 0 && version != f.Version {
+		return model.Form{}, ErrVersionConflict
+	}
+	return f, nil
+}
+
+func (s memoryForms) Delete(ctx context.Context, formID, ownerID uuid.UUID) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	f, ok := s.m.forms[formID]
+	if !ok || f.OwnerID != ownerID {
+		return ErrNotFound
+	}
+	s.m.deleteFormLocked(formID)
+	return nil
+}
+
+func (s memoryForms) DeleteAny(ctx context.Context, formID uuid.UUID) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	s.m.deleteFormLocked(formID)
+	return nil
+}
+
+func (s memoryForms) CountPublished(ctx context.Context) (int, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	count := 0
+	for _, f := range s.m.forms {
+		if f.Status == "published" {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// deleteFormLocked removes a form and cascades to its submissions, like the
+// ON DELETE CASCADE foreign keys do in Postgres.
+func (m *Memory) deleteFormLocked(formID uuid.UUID) {
+	delete(m.forms, formID)
+	delete(m.revisions, formID)
+	kept := m.submissions[:0]
+	for _, sub := range m.submissions {
+		if sub.FormID != formID {
+			kept = append(kept, sub)
+		}
+	}
+	m.submissions = kept
+	// uploads outlive their submissions until cleaned up, as with
+	// ON DELETE SET NULL
+	for id, u := range m.uploads {
+		if u.FormID == formID {
+			u.SubmissionID = nil
+			m.uploads[id] = u
+		}
+	}
+	delete(m.autoresponders, formID)
+	for id, p := range m.partials {
+		if p.FormID == formID {
+			delete(m.partials, id)
+		}
+	}
+
+	for id, w := range m.webhooks {
+		if w.FormID == formID {
+			m.deleteWebhookLocked(id)
+		}
+	}
+}
+
+type memorySubmissions struct{ m *Memory }
+
+func (s memorySubmissions) Create(ctx context.Context, sub *model.Submission, answers []model.Answer, partialID *uuid.UUID) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	return s.m.insertSubmissionLocked(sub, answers, partialID)
+}
+
+func (s memorySubmissions) CreateUnique(ctx context.Context, sub *model.Submission, answers []model.Answer, partialID *uuid.UUID, match RespondentMatch) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	for _, prev := range s.m.submissions {
+		if prev.FormID == sub.FormID && match.matches(prev.Submission) {
+			return ErrAlreadySubmitted
+		}
+	}
+	return s.m.insertSubmissionLocked(sub, answers, partialID)
+}
+
+func (r RespondentMatch) matches(s model.Submission) bool {
+	equal := func(a, b *string) bool { return a != nil && b != nil && *a == *b }
+	switch {
+	case r.UserID != nil && s.RespondentUserID != nil && *r.UserID == *s.RespondentUserID:
+		return true
+	case r.Email != nil && s.RespondentEmail != nil && strings.EqualFold(*r.Email, *s.RespondentEmail):
+		return true
+	case equal(r.DeviceID, s.DeviceID), equal(r.Fingerprint, s.Fingerprint):
+		return true
+	}
+	return false
+}
+
+func (m *Memory) insertSubmissionLocked(sub *model.Submission, answers []model.Answer, partialID *uuid.UUID) error {
+	if _, ok := m.forms[sub.FormID]; !ok {
+		return ErrNotFound
+	}
+	if partialID != nil {
+		if _, ok := m.partials[*partialID]; !ok {
+			return ErrPartialGone
+		}
+		delete(m.partials, *partialID)
+	}
+
+	if sub.ID == uuid.Nil {
+		sub.ID = uuid.New()
+	}
+	sub.CreatedAt = time.Now()
+	sub.RevisionID = nil
+	if revs := m.revisions[sub.FormID]; len(revs) > 0 {
+		latest := revs[len(revs)-1].ID
+		sub.RevisionID = &latest
+	}
+
+	stored := model.SubmissionWithAnswers{Submission: *sub, Answers: []model.Answer{}}
+	for i := range answers {
+		a := &answers[i]
+		if a.ID == uuid.Nil {
+			a.ID = uuid.New()
+		}
+		a.SubmissionID = sub.ID
+		a.CreatedAt = sub.CreatedAt
+		stored.Answers = append(stored.Answers, *a)
+	}
+	m.submissions = append(m.submissions, stored)
+	return nil
+}
+
+func (s memorySubmissions) ListByForm(ctx context.Context, formID uuid.UUID, q SubmissionQuery) (*SubmissionPage, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	questions := make(map[uuid.UUID]model.Question)
+	for _, q := range s.m.forms[formID].Questions {
+		questions[q.ID] = q
+	}
+
+	var matched []model.SubmissionWithAnswers
+	for _, sub := range s.m.submissions {
+		if sub.FormID == formID && q.matches(sub) {
+			matched = append(matched, sub)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return cursorOf(matched[j]).before(cursorOf(matched[i]))
+	})
+
+	page := &SubmissionPage{Submissions: []model.SubmissionWithAnswers{}, Total: len(matched)}
+	limit := q.limit()
+	for _, sub := range matched {
+		if q.After != nil && !cursorOf(sub).before(*q.After) {
+			continue
+		}
+		if len(page.Submissions) == limit {
+			last := page.Submissions[limit-1]
+			next := cursorOf(last)
+			page.Next = &next
+			break
+		}
+
+		sub.Answers = append([]model.Answer{}, sub.Answers...)
+		for i := range sub.Answers {
+			q := questions[sub.Answers[i].QuestionID]
+			sub.Answers[i].QuestionTitle = q.Title
+			sub.Answers[i].QuestionType = q.Type
+			sub.Answers[i].QuestionArchived = q.ArchivedAt != nil
+		}
+		page.Submissions = append(page.Submissions, sub)
+	}
+	return page, nil
+}
+
+func (q SubmissionQuery) matches(sub model.SubmissionWithAnswers) bool {
+	if q.CreatedFrom != nil && sub.CreatedAt.Before(*q.CreatedFrom) {
+		return false
+	}
+	if q.CreatedTo != nil && !sub.CreatedAt.Before(*q.CreatedTo) {
+		return false
+	}
+
+	for _, f := range q.Answers {
+		found := false
+		for _, a := range sub.Answers {
+			if a.QuestionID == f.QuestionID && jsonContains(a.Value, f.Value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if q.Search != "" {
+		terms := searchTerms(q.Search)
+		found := false
+		for _, a := range sub.Answers {
+			var text string
+			if json.Unmarshal(a.Value, &text) == nil && containsTerms(searchTerms(text), terms) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonContains approximates the jsonb @> operator for the scalar and array
+// values answers hold.
+func jsonContains(value, filter json.RawMessage) bool {
+	var v, f any
+	if json.Unmarshal(value, &v) != nil || json.Unmarshal(filter, &f) != nil {
+		return false
+	}
+	if reflect.DeepEqual(v, f) {
+		return true
+	}
+
+	arr, ok := v.([]any)
+	if !ok {
+		return false
+	}
+	wanted, ok := f.([]any)
+	if !ok {
+		wanted = []any{f}
+	}
+	for _, w := range wanted {
+		if !slices.ContainsFunc(arr, func(x any) bool { return reflect.DeepEqual(x, w) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// searchTerms splits text into lowercase words like the 'simple' text search
+// configuration.
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func containsTerms(words, terms []string) bool {
+	for _, t := range terms {
+		if !slices.Contains(words, t) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s memorySubmissions) Each(ctx context.Context, formID uuid.UUID, q SubmissionQuery, fn func(*model.SubmissionWithAnswers) error) error {
+	s.m.mu.RLock()
+	questions := make(map[uuid.UUID]model.Question)
+	for _, q := range s.m.forms[formID].Questions {
+		questions[q.ID] = q
+	}
+
+	settled := time.Now().Add(-SubmissionSettleTime)
+	var matched []model.SubmissionWithAnswers
+	for _, sub := range s.m.submissions {
+		if sub.FormID != formID || !q.matches(sub) {
+			continue
+		}
+		if q.Since != nil && !q.Since.before(cursorOf(sub)) {
+			continue
+		}
+		if q.Settled && !sub.CreatedAt.Before(settled) {
+			continue
+		}
+		sub.Answers = append([]model.Answer{}, sub.Answers...)
+		for i := range sub.Answers {
+			q := questions[sub.Answers[i].QuestionID]
+			sub.Answers[i].QuestionTitle = q.Title
+			sub.Answers[i].QuestionType = q.Type
+			sub.Answers[i].QuestionArchived = q.ArchivedAt != nil
+		}
+		matched = append(matched, sub)
+	}
+	s.m.mu.RUnlock()
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return cursorOf(matched[i]).before(cursorOf(matched[j]))
+	})
+	for i := range matched {
+		// a cancelled context ends the scan, as it ends a query
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(&matched[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s memorySubmissions) CountByOwner(ctx context.Context, ownerID uuid.UUID) (int, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	total := 0
+	for _, sub := range s.m.submissions {
+		if f, ok := s.m.forms[sub.FormID]; ok && f.OwnerID == ownerID {
+			total++
+		}
+	}
+	return total, nil
+}
+
+func (m *Memory) snapshotLocked(formID uuid.UUID, reason string) model.FormRevision {
+	f := m.forms[formID]
+	revs := m.revisions[formID]
+	rev := model.FormRevision{
+		ID:          uuid.New(),
+		FormID:      formID,
+		Revision:    len(revs) + 1,
+		Reason:      reason,
+		Title:       f.Title,
+		Description: f.Description,
+		Questions:   copyForm(f).Questions,
+		Sections:    copySections(f.Sections),
+		CreatedAt:   time.Now(),
+	}
+	if rev.Questions == nil {
+		rev.Questions = []model.Question{}
+	}
+	m.revisions[formID] = append(revs, rev)
+	return rev
+}
+
+func (m *Memory) revisionResponsesLocked(revisionID uuid.UUID) int {
+	n := 0
+	for _, sub := range m.submissions {
+		if sub.RevisionID != nil && *sub.RevisionID == revisionID {
+			n++
+		}
+	}
+	return n
+}
+
+type memoryRevisions struct{ m *Memory }
+
+func (s memoryRevisions) List(ctx context.Context, formID, ownerID uuid.UUID) ([]model.FormRevision, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	if f, ok := s.m.forms[formID]; !ok || f.OwnerID != ownerID {
+		return nil, ErrNotFound
+	}
+
+	revs := s.m.revisions[formID]
+	result := make([]model.FormRevision, 0, len(revs))
+	for i := len(revs) - 1; i >= 0; i-- {
+		r := revs[i]
+		r.Questions = nil
+		r.Sections = nil
+		r.Responses = s.m.revisionResponsesLocked(r.ID)
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+func (s memoryRevisions) Get(ctx context.Context, formID, ownerID uuid.UUID, revision int) (*model.FormRevision, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return s.m.revisionLocked(formID, ownerID, revision)
+}
+
+func (m *Memory) revisionLocked(formID, ownerID uuid.UUID, revision int) (*model.FormRevision, error) {
+	f, ok := m.forms[formID]
+	if !ok || f.OwnerID != ownerID {
+		return nil, ErrNotFound
+	}
+	revs := m.revisions[formID]
+	if revision < 1 || revision > len(revs) {
+		return nil, ErrNotFound
+	}
+
+	r := revs[revision-1]
+	r.Questions = copyQuestions(r.Questions)
+	r.Sections = copySections(r.Sections)
+	r.Responses = m.revisionResponsesLocked(r.ID)
+	return &r, nil
+}
+
+func (s memoryRevisions) Restore(ctx context.Context, formID, ownerID uuid.UUID, revision, version int) (*model.FormRevision, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	f, err := s.m.versionedFormLocked(formID, ownerID, version)
+	if err != nil {
+		return nil, err
+	}
+	old, err := s.m.revisionLocked(formID, ownerID, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	f.Version++
+	f.Title = old.Title
+	f.Description = old.Description
+	f.UpdatedAt = now
+	f.Questions = mergeQuestions(f.Questions, old.Questions, formID, now)
+	setSections(&f, old.Sections)
+	s.m.forms[formID] = f
+
+	restored := s.m.snapshotLocked(formID, model.RevisionReasonRestore)
+	return &restored, nil
+}
+
+type memoryUsers struct{ m *Memory }
+
+func (s memoryUsers) Get(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	u, ok := s.m.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &u, nil
+}
+
+func (s memoryUsers) List(ctx context.Context) ([]model.User, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	var users []model.User
+	for _, u := range s.m.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].CreatedAt.After(users[j].CreatedAt)
+	})
+	return users, nil
+}
+
+func (s memoryUsers) Delete(ctx context.Context, id uuid.UUID) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	delete(s.m.users, id)
+	delete(s.m.digests, id)
+	for formID, f := range s.m.forms {
+		if f.OwnerID == id {
+			s.m.deleteFormLocked(formID)
+		}
+	}
+	return nil
+}
+
+type memoryAnalytics struct{ m *Memory }
+
+func (s memoryAnalytics) FormAnalytics(ctx context.Context, f *model.Form) (*model.FormAnalytics, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	a, byID := newFormAnalytics(f)
+	numbers := make(map[uuid.UUID][]float64)
+	terms := make(map[uuid.UUID]map[string]int)
+	revisions := make(map[uuid.UUID]model.FormRevision)
+	for _, rev := range s.m.revisions[f.ID] {
+		revisions[rev.ID] = rev
+	}
+
+	for _, sub := range s.m.submissions {
+		if sub.FormID != f.ID {
+			continue
+		}
+		a.Responses++
+		shown := f.Questions
+		if sub.RevisionID != nil {
+			if rev, ok := revisions[*sub.RevisionID]; ok {
+				shown = shownTo(&model.Form{Questions: rev.Questions, Sections: rev.Sections}, sub.Answers)
+			}
+		}
+		countShown(byID, shown, 1)
+
+		for _, ans := range sub.Answers {
+			qa, ok := byID[ans.QuestionID]
+			if !ok {
+				continue
+			}
+			var value any
+			if json.Unmarshal(ans.Value, &value) != nil {
+				continue
+			}
+
+			switch v := value.(type) {
+			case string:
+				if strings.TrimSpace(v) == "" {
+					continue
+				}
+				if qa.Options != nil {
+					countChoice(qa, v, 1)
+				}
+				if qa.Text != nil {
+					qa.Text.TotalWords += len(strings.Fields(v))
+					if terms[qa.QuestionID] == nil {
+						terms[qa.QuestionID] = make(map[string]int)
+					}
+					for _, t := range searchTerms(v) {
+						if len(t) >= minTermLength && !slices.Contains(stopWords, t) {
+							terms[qa.QuestionID][t]++
+						}
+					}
+				}
+			case []any:
+				if len(v) == 0 {
+					continue
+				}
+				for _, item := range v {
+					if choice, ok := item.(string); ok && qa.Options != nil {
+						countChoice(qa, choice, 1)
+					}
+				}
+			case float64:
+				numbers[qa.QuestionID] = append(numbers[qa.QuestionID], v)
+			case nil:
+				continue
+			}
+			qa.Answered++
+		}
+	}
+
+	for id, values := range numbers {
+		slices.Sort(values)
+		stats := &model.NumericStats{Count: len(values), Min: values[0], Max: values[len(values)-1]}
+		sum := 0.0
+		for _, v := range values {
+			sum += v
+			if n := len(stats.Distribution); n > 0 && stats.Distribution[n-1].Value == v {
+				stats.Distribution[n-1].Count++
+			} else if n < MaxDistributionValues {
+				stats.Distribution = append(stats.Distribution, model.ValueCount{Value: v, Count: 1})
+			}
+		}
+		stats.Mean = sum / float64(len(values))
+		// percentile_cont(0.5) interpolates between the middle values
+		mid := len(values) / 2
+		stats.Median = values[mid]
+		if len(values)%2 == 0 {
+			stats.Median = (values[mid-1] + values[mid]) / 2
+		}
+		byID[id].Numeric = stats
+	}
+
+	for id, counts := range terms {
+		top := make([]model.TermCount, 0, len(counts))
+		for t, n := range counts {
+			top = append(top, model.TermCount{Term: t, Count: n})
+		}
+		sort.Slice(top, func(i, j int) bool {
+			if top[i].Count != top[j].Count {
+				return top[i].Count > top[j].Count
+			}
+			return top[i].Term < top[j].Term
+		})
+		byID[id].Text.TopTerms = top[:min(len(top), TopTermsLimit)]
+	}
+
+	finishAnalytics(a)
+	return a, nil
+}
+
+func (s memoryAnalytics) ResponseSeries(ctx context.Context, ownerID, formID uuid.UUID, q SeriesQuery) (*model.ResponseSeries, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	from := truncateBucket(q.From, q.Interval, q.Location)
+	prevFrom, prevTo := q.previousPeriod()
+	counts := make(map[int64]int)
+	previous := 0
+	for _, sub := range s.m.submissions {
+		f, ok := s.m.forms[sub.FormID]
+		if !ok || f.OwnerID != ownerID || (formID != uuid.Nil && sub.FormID != formID) {
+			continue
+		}
+		switch t := sub.CreatedAt; {
+		case !t.Before(from) && t.Before(q.To):
+			counts[truncateBucket(t, q.Interval, q.Location).Unix()]++
+		case !t.Before(prevFrom) && t.Before(prevTo):
+			previous++
+		}
+	}
+	return buildSeries(q, counts, previous), nil
+}
+
+func (s memoryAnalytics) RecordEvent(ctx context.Context, e *model.FormEvent) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.forms[e.FormID]; !ok {
+		return ErrNotFound
+	}
+	for _, prev := range s.m.events {
+		if prev.FormID == e.FormID && prev.SessionID == e.SessionID && prev.Kind == e.Kind &&
+			(prev.QuestionID == nil) == (e.QuestionID == nil) && (prev.QuestionID == nil || *prev.QuestionID == *e.QuestionID) {
+			return nil
+		}
+	}
+
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	e.CreatedAt = time.Now()
+	s.m.events = append(s.m.events, *e)
+	return nil
+}
+
+func (s memoryAnalytics) Funnel(ctx context.Context, f *model.Form) (*model.FormFunnel, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	fn, byID := newFormFunnel(f)
+	positions := make(map[uuid.UUID]int)
+	for _, q := range s.m.forms[f.ID].Questions {
+		positions[q.ID] = q.Position
+	}
+
+	viewed := make(map[string]bool)
+	started := make(map[string]bool)
+	furthest := make(map[string]uuid.UUID)
+	for _, e := range s.m.events {
+		if e.FormID != f.ID {
+			continue
+		}
+		viewed[e.SessionID] = true
+		if e.Kind == model.FormEventView {
+			continue
+		}
+		started[e.SessionID] = true
+		if e.Kind == model.FormEventProgress {
+			if q, ok := byID[*e.QuestionID]; ok {
+				q.Reached++
+			}
+			if cur, ok := furthest[e.SessionID]; !ok || positions[*e.QuestionID] > positions[cur] {
+				furthest[e.SessionID] = *e.QuestionID
+			}
+		}
+	}
+
+	submitted := make(map[string]bool)
+	for _, sub := range s.m.submissions {
+		if sub.FormID == f.ID && sub.DeviceID != nil {
+			submitted[*sub.DeviceID] = true
+		}
+	}
+	for session := range viewed {
+		if submitted[session] {
+			fn.Completions++
+			started[session] = true
+		}
+	}
+	fn.Views, fn.Starts = len(viewed), len(started)
+	for session, questionID := range furthest {
+		if q, ok := byID[questionID]; ok && !submitted[session] {
+			q.DroppedOff++
+		}
+	}
+
+	finishFunnel(fn)
+	return fn, nil
+}
+
+type memoryWebhooks struct{ m *Memory }
+
+func (s memoryWebhooks) List(ctx context.Context, formID uuid.UUID) ([]model.Webhook, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	hooks := []model.Webhook{}
+	for _, w := range s.m.webhooks {
+		if w.FormID == formID {
+			w.Secret = ""
+			w.Events = slices.Clone(w.Events)
+			hooks = append(hooks, w)
+		}
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].CreatedAt.Before(hooks[j].CreatedAt) })
+	return hooks, nil
+}
+
+func (s memoryWebhooks) Get(ctx context.Context, formID, webhookID uuid.UUID) (*model.Webhook, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	w, ok := s.m.webhooks[webhookID]
+	if !ok || w.FormID != formID {
+		return nil, ErrNotFound
+	}
+	w.Secret = ""
+	w.Events = slices.Clone(w.Events)
+	return &w, nil
+}
+
+func (s memoryWebhooks) Create(ctx context.Context, w *model.Webhook) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.forms[w.FormID]; !ok {
+		return ErrNotFound
+	}
+	now := time.Now()
+	w.ID = uuid.New()
+	w.CreatedAt = now
+	w.UpdatedAt = now
+	if w.Events == nil {
+		w.Events = []string{model.WebhookEventSubmissionCreated}
+	}
+	stored := *w
+	stored.Events = slices.Clone(w.Events)
+	s.m.webhooks[w.ID] = stored
+	return nil
+}
+
+func (s memoryWebhooks) Update(ctx context.Context, w *model.Webhook) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	stored, ok := s.m.webhooks[w.ID]
+	if !ok || stored.FormID != w.FormID {
+		return ErrNotFound
+	}
+	stored.URL = w.URL
+	stored.Events = slices.Clone(w.Events)
+	stored.Active = w.Active
+	if w.Secret != "" {
+		stored.Secret = w.Secret
+	}
+	stored.UpdatedAt = time.Now()
+	s.m.webhooks[w.ID] = stored
+
+	w.CreatedAt = stored.CreatedAt
+	w.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+func (s memoryWebhooks) Delete(ctx context.Context, formID, webhookID uuid.UUID) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if w, ok := s.m.webhooks[webhookID]; !ok || w.FormID != formID {
+		return ErrNotFound
+	}
+	s.m.deleteWebhookLocked(webhookID)
+	return nil
+}
+
+func (m *Memory) deleteWebhookLocked(webhookID uuid.UUID) {
+	delete(m.webhooks, webhookID)
+	m.deliveries = slices.DeleteFunc(m.deliveries, func(d model.WebhookDelivery) bool {
+		return d.WebhookID == webhookID
+	})
+}
+
+func (m *Memory) queueDeliveryLocked(webhookID uuid.UUID, event string, payload json.RawMessage) model.WebhookDelivery {
+	now := time.Now()
+	d := model.WebhookDelivery{
+		ID:            uuid.New(),
+		WebhookID:     webhookID,
+		Event:         event,
+		Payload:       slices.Clone(payload),
+		Status:        model.DeliveryStatusPending,
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+	}
+	m.deliveries = append(m.deliveries, d)
+	return d
+}
+
+func (s memoryWebhooks) Enqueue(ctx context.Context, formID uuid.UUID, event string, payload json.RawMessage) ([]uuid.UUID, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	var ids []uuid.UUID
+	for _, w := range s.m.webhooks {
+		if w.FormID == formID && w.Active && w.Subscribes(event) {
+			ids = append(ids, s.m.queueDeliveryLocked(w.ID, event, payload).ID)
+		}
+	}
+	return ids, nil
+}
+
+func (s memoryWebhooks) ListDeliveries(ctx context.Context, formID, webhookID uuid.UUID, limit int) ([]model.WebhookDelivery, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	deliveries := []model.WebhookDelivery{}
+	if w, ok := s.m.webhooks[webhookID]; !ok || w.FormID != formID {
+		return deliveries, nil
+	}
+	for i := len(s.m.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		if d := s.m.deliveries[i]; d.WebhookID == webhookID {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries, nil
+}
+
+func (s memoryWebhooks) Redeliver(ctx context.Context, formID, webhookID, deliveryID uuid.UUID) (*model.WebhookDelivery, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if w, ok := s.m.webhooks[webhookID]; !ok || w.FormID != formID {
+		return nil, ErrNotFound
+	}
+	i := slices.IndexFunc(s.m.deliveries, func(d model.WebhookDelivery) bool {
+		return d.ID == deliveryID && d.WebhookID == webhookID
+	})
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	d := s.m.queueDeliveryLocked(webhookID, s.m.deliveries[i].Event, s.m.deliveries[i].Payload)
+	return &d, nil
+}
+
+func (s memoryWebhooks) Delivery(ctx context.Context, deliveryID uuid.UUID) (*OutgoingDelivery, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	i := slices.IndexFunc(s.m.deliveries, func(d model.WebhookDelivery) bool { return d.ID == deliveryID })
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	d := s.m.deliveries[i]
+	return &OutgoingDelivery{Delivery: d, Webhook: s.m.webhooks[d.WebhookID]}, nil
+}
+
+func (s memoryWebhooks) RecordAttempt(ctx context.Context, deliveryID uuid.UUID, a DeliveryAttempt) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	i := slices.IndexFunc(s.m.deliveries, func(d model.WebhookDelivery) bool { return d.ID == deliveryID })
+	if i < 0 {
+		return nil
+	}
+	d := &s.m.deliveries[i]
+	d.Attempts++
+	d.Status = a.status()
+	d.NextAttemptAt = a.RetryAt
+	d.LastStatusCode, d.LastError, d.DeliveredAt = nil, nil, nil
+	if a.StatusCode != 0 {
+		code := a.StatusCode
+		d.LastStatusCode = &code
+	}
+	if a.Error != "" {
+		msg := a.Error
+		d.LastError = &msg
+	}
+	if a.Succeeded {
+		now := time.Now()
+		d.DeliveredAt = &now
+	}
+	return nil
+}
+
+type memoryJobs struct{ m *Memory }
+
+func (s memoryJobs) Enqueue(ctx context.Context, j *model.Job) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if j.UniqueKey != nil && slices.ContainsFunc(s.m.jobs, func(o model.Job) bool {
+		return o.Status == model.JobStatusPending && o.UniqueKey != nil && *o.UniqueKey == *j.UniqueKey
+	}) {
+		return nil
+	}
+
+	now := time.Now()
+	j.ID = uuid.New()
+	j.Status = model.JobStatusPending
+	j.Attempts = 0
+	if j.RunAt.IsZero() {
+		j.RunAt = now
+	}
+	j.LockedUntil, j.LastError = nil, nil
+	j.CreatedAt = now
+	j.UpdatedAt = now
+
+	stored := *j
+	stored.Payload = slices.Clone(j.Payload)
+	s.m.jobs = append(s.m.jobs, stored)
+	return nil
+}
+
+func (s memoryJobs) Claim(ctx context.Context, kinds []string, limit int, lease time.Duration) ([]model.Job, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	now := time.Now()
+	var due []int
+	for i, j := range s.m.jobs {
+		if !slices.Contains(kinds, j.Kind) {
+			continue
+		}
+		if (j.Status == model.JobStatusPending && !j.RunAt.After(now)) ||
+			(j.Status == model.JobStatusRunning && !j.LockedUntil.After(now)) {
+			due = append(due, i)
+		}
+	}
+	sort.SliceStable(due, func(a, b int) bool { return s.m.jobs[due[a]].RunAt.Before(s.m.jobs[due[b]].RunAt) })
+
+	claimed := []model.Job{}
+	lockedUntil := now.Add(lease)
+	for _, i := range due[:min(len(due), limit)] {
+		j := &s.m.jobs[i]
+		j.Status = model.JobStatusRunning
+		j.UniqueKey = nil
+		j.Attempts++
+		j.LockedUntil = &lockedUntil
+		j.UpdatedAt = now
+		claimed = append(claimed, *j)
+	}
+	return claimed, nil
+}
+
+// runningAttempt matches the job jobID while it runs the given attempt.
+func runningAttempt(jobID uuid.UUID, attempt int) func(model.Job) bool {
+	return func(j model.Job) bool {
+		return j.ID == jobID && j.Status == model.JobStatusRunning && j.Attempts == attempt
+	}
+}
+
+func (s memoryJobs) Complete(ctx context.Context, jobID uuid.UUID, attempt int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	s.m.jobs = slices.DeleteFunc(s.m.jobs, runningAttempt(jobID, attempt))
+	return nil
+}
+
+func (s memoryJobs) Fail(ctx context.Context, jobID uuid.UUID, attempt int, message string, retryAt *time.Time) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	i := slices.IndexFunc(s.m.jobs, runningAttempt(jobID, attempt))
+	if i < 0 {
+		return nil
+	}
+	j := &s.m.jobs[i]
+	j.Status = model.JobStatusDead
+	if retryAt != nil {
+		j.Status = model.JobStatusPending
+		j.RunAt = *retryAt
+	}
+	j.LockedUntil = nil
+	j.LastError = &message
+	j.UpdatedAt = time.Now()
+	return nil
+}
+
+func (s memoryJobs) ListDead(ctx context.Context, limit int) ([]model.Job, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	dead := []model.Job{}
+	for _, j := range s.m.jobs {
+		if j.Status == model.JobStatusDead {
+			dead = append(dead, j)
+		}
+	}
+	sort.SliceStable(dead, func(a, b int) bool { return dead[a].UpdatedAt.After(dead[b].UpdatedAt) })
+	return dead[:min(len(dead), limit)], nil
+}
+
+func (s memoryJobs) Retry(ctx context.Context, jobID uuid.UUID) (*model.Job, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	i := slices.IndexFunc(s.m.jobs, func(j model.Job) bool { return j.ID == jobID && j.Status == model.JobStatusDead })
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	now := time.Now()
+	j := &s.m.jobs[i]
+	j.Status = model.JobStatusPending
+	j.Attempts = 0
+	j.RunAt = now
+	j.UpdatedAt = now
+	retried := *j
+	return &retried, nil
+}
+
+type memoryNotifications struct{ m *Memory }
+
+func (s memoryNotifications) Watermark(ctx context.Context, key string) (*SubmissionCursor, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	c, ok := s.m.watermarks[key]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+func (s memoryNotifications) SetWatermark(ctx context.Context, key string, c SubmissionCursor) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	s.m.watermarks[key] = c
+	return nil
+}
+
+type throttle struct {
+	count     int
+	expiresAt time.Time
+}
+
+func (s memoryNotifications) CountSend(ctx context.Context, key string, window time.Duration) (int, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	now := time.Now()
+	t := s.m.throttles[key]
+	if !t.expiresAt.After(now) {
+		t = throttle{expiresAt: now.Add(window)}
+	}
+	t.count++
+	s.m.throttles[key] = t
+	return t.count, nil
+}
+
+type memoryAutoresponders struct{ m *Memory }
+
+func (s memoryAutoresponders) Get(ctx context.Context, formID uuid.UUID) (*model.Autoresponder, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	a, ok := s.m.autoresponders[formID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &a, nil
+}
+
+func (s memoryAutoresponders) Put(ctx context.Context, a *model.Autoresponder) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.forms[a.FormID]; !ok {
+		return ErrNotFound
+	}
+	now := time.Now()
+	a.CreatedAt = now
+	if stored, ok := s.m.autoresponders[a.FormID]; ok {
+		a.CreatedAt = stored.CreatedAt
+	}
+	a.UpdatedAt = now
+	s.m.autoresponders[a.FormID] = *a
+	return nil
+}
+
+func (s memoryAutoresponders) Delete(ctx context.Context, formID uuid.UUID) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.autoresponders[formID]; !ok {
+		return ErrNotFound
+	}
+	delete(s.m.autoresponders, formID)
+	return nil
+}
+
+type memoryDigests struct{ m *Memory }
+
+func (s memoryDigests) Preference(ctx context.Context, userID uuid.UUID) (*model.DigestPreference, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	p, ok := s.m.digests[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &p, nil
+}
+
+func (s memoryDigests) SetPreference(ctx context.Context, p *model.DigestPreference) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.users[p.UserID]; !ok {
+		return ErrNotFound
+	}
+	now := time.Now()
+	stored, ok := s.m.digests[p.UserID]
+	if !ok || stored.Frequency == model.DigestOff {
+		stored.SentThrough = nil
+		if p.Frequency != model.DigestOff {
+			stored.SentThrough = &now
+		}
+	}
+	p.SentThrough = stored.SentThrough
+	p.UpdatedAt = now
+	s.m.digests[p.UserID] = *p
+	return nil
+}
+
+func (s memoryDigests) ListEnabled(ctx context.Context) ([]model.DigestPreference, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	prefs := []model.DigestPreference{}
+	for _, p := range s.m.digests {
+		if p.Frequency != model.DigestOff {
+			prefs = append(prefs, p)
+		}
+	}
+	return prefs, nil
+}
+
+func (s memoryDigests) MarkSent(ctx context.Context, userID uuid.UUID, through time.Time) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	p, ok := s.m.digests[userID]
+	if !ok {
+		return nil
+	}
+	if p.SentThrough == nil || p.SentThrough.Before(through) {
+		p.SentThrough = &through
+		p.UpdatedAt = time.Now()
+		s.m.digests[userID] = p
+	}
+	return nil
+}
+
+type memoryUploads struct{ m *Memory }
+
+func (s memoryUploads) Create(ctx context.Context, u *model.Upload) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	u.CreatedAt = time.Now()
+	s.m.uploads[u.ID] = *u
+	return nil
+}
+
+func (s memoryUploads) Get(ctx context.Context, uploadID uuid.UUID) (*model.Upload, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	u, ok := s.m.uploads[uploadID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &u, nil
+}
+
+func (s memoryUploads) List(ctx context.Context, uploadIDs []uuid.UUID) ([]model.Upload, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	uploads := []model.Upload{}
+	for _, id := range uploadIDs {
+		if u, ok := s.m.uploads[id]; ok {
+			uploads = append(uploads, u)
+		}
+	}
+	return uploads, nil
+}
+
+func (s memoryUploads) Attach(ctx context.Context, submissionID uuid.UUID, uploadIDs []uuid.UUID) (int, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	n := 0
+	for _, id := range uploadIDs {
+		if u, ok := s.m.uploads[id]; ok && u.SubmissionID == nil {
+			u.SubmissionID = &submissionID
+			s.m.uploads[id] = u
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s memoryUploads) ListBySubmissions(ctx context.Context, submissionIDs []uuid.UUID) ([]model.Upload, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	uploads := []model.Upload{}
+	for _, u := range s.m.uploads {
+		if u.SubmissionID != nil && slices.Contains(submissionIDs, *u.SubmissionID) {
+			uploads = append(uploads, u)
+		}
+	}
+	sort.Slice(uploads, func(i, j int) bool { return uploads[i].CreatedAt.Before(uploads[j].CreatedAt) })
+	return uploads, nil
+}
+
+func (s memoryUploads) ListUnattached(ctx context.Context, before time.Time, limit int) ([]model.Upload, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	uploads := []model.Upload{}
+	for _, u := range s.m.uploads {
+		if u.SubmissionID == nil && u.CreatedAt.Before(before) && !s.m.inPartialLocked(u) {
+			uploads = append(uploads, u)
+		}
+	}
+	sort.Slice(uploads, func(i, j int) bool { return uploads[i].CreatedAt.Before(uploads[j].CreatedAt) })
+	return uploads[:min(len(uploads), limit)], nil
+}
+
+func (s memoryUploads) Delete(ctx context.Context, uploadID uuid.UUID) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	delete(s.m.uploads, uploadID)
+	return nil
+}
+
+// inPartialLocked reports whether an unexpired partial response names u.
+func (m *Memory) inPartialLocked(u model.Upload) bool {
+	now := time.Now()
+	for _, p := range m.partials {
+		if p.FormID != u.FormID || !p.ExpiresAt.After(now) {
+			continue
+		}
+		for _, a := range p.Answers {
+			if a.QuestionID != u.QuestionID {
+				continue
+			}
+			var ids []uuid.UUID
+			if json.Unmarshal(a.Value, &ids) != nil {
+				var id uuid.UUID
+				if json.Unmarshal(a.Value, &id) == nil {
+					ids = []uuid.UUID{id}
+				}
+			}
+			if slices.Contains(ids, u.ID) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type memoryPartials struct{ m *Memory }
+
+func copyPartial(p model.PartialResponse) model.PartialResponse {
+	p.Answers = slices.Clone(p.Answers)
+	return p
+}
+
+func (s memoryPartials) Create(ctx context.Context, p *model.PartialResponse) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.forms[p.FormID]; !ok {
+		return ErrNotFound
+	}
+	if p.Answers == nil {
+		p.Answers = []model.PartialAnswer{}
+	}
+	p.ID = uuid.New()
+	p.CreatedAt = time.Now()
+	p.UpdatedAt = p.CreatedAt
+	s.m.partials[p.ID] = copyPartial(*p)
+	return nil
+}
+
+func (s memoryPartials) GetByToken(ctx context.Context, formID uuid.UUID, tokenHash string) (*model.PartialResponse, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	now := time.Now()
+	for _, p := range s.m.partials {
+		if p.FormID == formID && p.TokenHash == tokenHash && p.ExpiresAt.After(now) {
+			p = copyPartial(p)
+			return &p, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s memoryPartials) Update(ctx context.Context, p *model.PartialResponse) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	current, ok := s.m.partials[p.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if p.Answers == nil {
+		p.Answers = []model.PartialAnswer{}
+	}
+	current.Answers = slices.Clone(p.Answers)
+	current.RespondentEmail = p.RespondentEmail
+	current.SectionID = p.SectionID
+	current.ExpiresAt = p.ExpiresAt
+	current.UpdatedAt = time.Now()
+	p.UpdatedAt = current.UpdatedAt
+	s.m.partials[p.ID] = current
+	return nil
+}
+
+func (s memoryPartials) Delete(ctx context.Context, partialID uuid.UUID) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.partials[partialID]; !ok {
+		return ErrNotFound
+	}
+	delete(s.m.partials, partialID)
+	return nil
+}
+
+func (s memoryPartials) DeleteExpired(ctx context.Context, before time.Time, limit int) (int, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	n := 0
+	for id, p := range s.m.partials {
+		if n == limit {
+			break
+		}
+		if p.ExpiresAt.Before(before) {
+			delete(s.m.partials, id)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,473 @@
+package store
+
+import (
+	"context"
+	"craft/internal/db"
+	"craft/internal/model"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type PgAnalyticsStore struct {
+	DB *db.Database
+}
+
+func NewPgAnalyticsStore(database *db.Database) *PgAnalyticsStore {
+	return &PgAnalyticsStore{DB: database}
+}
+
+// answeredCondition matches answers that are not empty: JSON null, blank
+// text and empty selections count as skipped.
+const answeredCondition = `
+	a.value IS NOT NULL
+	AND a.value NOT IN ('null'::jsonb, '[]'::jsonb)
+	AND NOT (jsonb_typeof(a.value) = 'string' AND btrim(a.value #>> '{}') = '')`
+
+func (s *PgAnalyticsStore) FormAnalytics(ctx context.Context, f *model.Form) (*model.FormAnalytics, error) {
+	a, byID := newFormAnalytics(f)
+
+	var choiceIDs, textIDs []uuid.UUID
+	for _, q := range f.Questions {
+		if isChoiceQuestion(q) {
+			choiceIDs = append(choiceIDs, q.ID)
+		}
+		if isTextQuestion(q) {
+			textIDs = append(textIDs, q.ID)
+		}
+	}
+
+	if err := s.countResponses(ctx, f, a, byID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.DB.Pool.Query(ctx, `
+		SELECT a.question_id, COUNT(*)
+		FROM answers a
+		JOIN submissions s ON s.id = a.submission_id
+		WHERE s.form_id = $1 AND `+answeredCondition+`
+		GROUP BY a.question_id
+	`, f.ID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var questionID uuid.UUID
+		var n int
+		if err := rows.Scan(&questionID, &n); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if qa, ok := byID[questionID]; ok {
+			qa.Answered = n
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// a single choice is stored as a string and a multi-select as an array;
+	// both are flattened to one row per chosen label or option ID
+	rows, err = s.DB.Pool.Query(ctx, `
+		SELECT a.question_id, c.choice, COUNT(*)
+		FROM answers a
+		JOIN submissions s ON s.id = a.submission_id
+		CROSS JOIN LATERAL jsonb_array_elements_text(
+			CASE jsonb_typeof(a.value) WHEN 'array' THEN a.value ELSE jsonb_build_array(a.value) END
+		) AS c(choice)
+		WHERE s.form_id = $1 AND a.question_id = ANY($2) AND jsonb_typeof(a.value) IN ('string', 'array')
+		GROUP BY a.question_id, c.choice
+	`, f.ID, choiceIDs)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var questionID uuid.UUID
+		var choice string
+		var n int
+		if err := rows.Scan(&questionID, &choice, &n); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if qa, ok := byID[questionID]; ok {
+			countChoice(qa, choice, n)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// numeric stats cover number and rating questions, the only ones whose
+	// answers are JSON numbers
+	rows, err = s.DB.Pool.Query(ctx, `
+		SELECT a.question_id, COUNT(*), AVG(v.n), percentile_cont(0.5) WITHIN GROUP (ORDER BY v.n), MIN(v.n), MAX(v.n)
+		FROM answers a
+		JOIN submissions s ON s.id = a.submission_id
+		CROSS JOIN LATERAL (SELECT (a.value #>> '{}')::float8 AS n) v
+		WHERE s.form_id = $1 AND jsonb_typeof(a.value) = 'number'
+		GROUP BY a.question_id
+	`, f.ID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var questionID uuid.UUID
+		stats := &model.NumericStats{Distribution: []model.ValueCount{}}
+		if err := rows.Scan(&questionID, &stats.Count, &stats.Mean, &stats.Median, &stats.Min, &stats.Max); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if qa, ok := byID[questionID]; ok {
+			qa.Numeric = stats
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.DB.Pool.Query(ctx, `
+		SELECT question_id, n, count FROM (
+			SELECT a.question_id, v.n, COUNT(*) AS count,
+			       row_number() OVER (PARTITION BY a.question_id ORDER BY v.n) AS rank
+			FROM answers a
+			JOIN submissions s ON s.id = a.submission_id
+			CROSS JOIN LATERAL (SELECT (a.value #>> '{}')::float8 AS n) v
+			WHERE s.form_id = $1 AND jsonb_typeof(a.value) = 'number'
+			GROUP BY a.question_id, v.n
+		) d
+		WHERE rank <= $2
+		ORDER BY question_id, n
+	`, f.ID, MaxDistributionValues)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var questionID uuid.UUID
+		var vc model.ValueCount
+		if err := rows.Scan(&questionID, &vc.Value, &vc.Count); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if qa, ok := byID[questionID]; ok && qa.Numeric != nil {
+			qa.Numeric.Distribution = append(qa.Numeric.Distribution, vc)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.DB.Pool.Query(ctx, `
+		SELECT a.question_id, SUM(cardinality(regexp_split_to_array(btrim(a.value #>> '{}'), '\s+')))
+		FROM answers a
+		JOIN submissions s ON s.id = a.submission_id
+		WHERE s.form_id = $1 AND a.question_id = ANY($2)
+		  AND jsonb_typeof(a.value) = 'string' AND btrim(a.value #>> '{}') <> ''
+		GROUP BY a.question_id
+	`, f.ID, textIDs)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var questionID uuid.UUID
+		var words int
+		if err := rows.Scan(&questionID, &words); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if qa, ok := byID[questionID]; ok && qa.Text != nil {
+			qa.Text.TotalWords = words
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.DB.Pool.Query(ctx, `
+		SELECT question_id, term, count FROM (
+			SELECT a.question_id, t.term, COUNT(*) AS count,
+			       row_number() OVER (PARTITION BY a.question_id ORDER BY COUNT(*) DESC, t.term) AS rank
+			FROM answers a
+			JOIN submissions s ON s.id = a.submission_id
+			CROSS JOIN LATERAL regexp_split_to_table(lower(a.value #>> '{}'), '[^[:alnum:]]+') AS t(term)
+			WHERE s.form_id = $1 AND a.question_id = ANY($2) AND jsonb_typeof(a.value) = 'string'
+			  AND length(t.term) >= $3 AND NOT t.term = ANY($4)
+			GROUP BY a.question_id, t.term
+		) ranked
+		WHERE rank <= $5
+		ORDER BY question_id, rank
+	`, f.ID, textIDs, minTermLength, stopWords, TopTermsLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var questionID uuid.UUID
+		var tc model.TermCount
+		if err := rows.Scan(&questionID, &tc.Term, &tc.Count); err != nil {
+			return nil, err
+		}
+		if qa, ok := byID[questionID]; ok && qa.Text != nil {
+			qa.Text.TopTerms = append(qa.Text.TopTerms, tc)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	finishAnalytics(a)
+	return a, nil
+}
+
+// countResponses counts the responses to f and, per question, those shown
+// it. Responses are counted per revision, whose questions are the ones its
+// respondents had, except where the revision shows questions on conditions
+// or has page jumps: those responses are walked one by one with their
+// answers.
+func (s *PgAnalyticsStore) countResponses(ctx context.Context, f *model.Form, a *model.FormAnalytics, byID map[uuid.UUID]*model.QuestionAnalytics) error {
+	rows, err := s.DB.Pool.Query(ctx, `
+		SELECT c.revision_id, r.questions, r.sections, c.n
+		FROM (
+			SELECT revision_id, COUNT(*) AS n
+			FROM submissions
+			WHERE form_id = $1
+			GROUP BY revision_id
+		) c
+		LEFT JOIN form_revisions r ON r.id = c.revision_id
+	`, f.ID)
+	if err != nil {
+		return err
+	}
+	conditional := make(map[uuid.UUID]*model.Form)
+	var conditionalIDs []uuid.UUID
+	for rows.Next() {
+		var revisionID *uuid.UUID
+		var definition, sections []byte
+		var n int
+		if err := rows.Scan(&revisionID, &definition, &sections, &n); err != nil {
+			rows.Close()
+			return err
+		}
+		a.Responses += n
+		if definition == nil {
+			countShown(byID, f.Questions, n)
+			continue
+		}
+		rev := &model.Form{}
+		if err := json.Unmarshal(definition, &rev.Questions); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := json.Unmarshal(sections, &rev.Sections); err != nil {
+			rows.Close()
+			return err
+		}
+		if !showsConditionally(rev) {
+			countShown(byID, rev.Questions, n)
+			continue
+		}
+		conditional[*revisionID] = rev
+		conditionalIDs = append(conditionalIDs, *revisionID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(conditionalIDs) == 0 {
+		return nil
+	}
+
+	rows, err = s.DB.Pool.Query(ctx, `
+		SELECT s.id, s.revision_id, a.question_id, a.value
+		FROM submissions s
+		LEFT JOIN answers a ON a.submission_id = s.id
+		WHERE s.form_id = $1 AND s.revision_id = ANY($2)
+		ORDER BY s.id
+	`, f.ID, conditionalIDs)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var current uuid.UUID
+	var rev *model.Form
+	var answers []model.Answer
+	for rows.Next() {
+		var submissionID, revisionID uuid.UUID
+		var questionID *uuid.UUID
+		var value []byte
+		if err := rows.Scan(&submissionID, &revisionID, &questionID, &value); err != nil {
+			return err
+		}
+		if submissionID != current {
+			if rev != nil {
+				countShown(byID, shownTo(rev, answers), 1)
+			}
+			current, rev, answers = submissionID, conditional[revisionID], answers[:0]
+		}
+		if questionID != nil {
+			answers = append(answers, model.Answer{QuestionID: *questionID, Value: value})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if rev != nil {
+		countShown(byID, shownTo(rev, answers), 1)
+	}
+	return nil
+}
+
+func (s *PgAnalyticsStore) ResponseSeries(ctx context.Context, ownerID, formID uuid.UUID, q SeriesQuery) (*model.ResponseSeries, error) {
+	const scope = `
+		FROM submissions s
+		JOIN forms f ON f.id = s.form_id
+		WHERE f.owner_id = $1 AND ($2 = '00000000-0000-0000-0000-000000000000'::uuid OR s.form_id = $2)`
+
+	from := truncateBucket(q.From, q.Interval, q.Location)
+	rows, err := s.DB.Pool.Query(ctx, `
+		SELECT date_trunc($3, s.created_at, $4) AS bucket, COUNT(*)
+		`+scope+` AND s.created_at >= $5 AND s.created_at < $6
+		GROUP BY bucket
+	`, ownerID, formID, q.Interval, q.Location.String(), from, q.To)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int64]int)
+	for rows.Next() {
+		var bucket time.Time
+		var n int
+		if err := rows.Scan(&bucket, &n); err != nil {
+			return nil, err
+		}
+		counts[bucket.Unix()] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	prevFrom, prevTo := q.previousPeriod()
+	var previous int
+	err = s.DB.Pool.QueryRow(ctx, `
+		SELECT COUNT(*)
+		`+scope+` AND s.created_at >= $3 AND s.created_at < $4
+	`, ownerID, formID, prevFrom, prevTo).Scan(&previous)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildSeries(q, counts, previous), nil
+}
+
+func (s *PgAnalyticsStore) RecordEvent(ctx context.Context, e *model.FormEvent) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+
+	_, err := s.DB.Pool.Exec(ctx, `
+		INSERT INTO form_events (id, form_id, session_id, kind, question_id)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT DO NOTHING
+	`, e.ID, e.FormID, e.SessionID, e.Kind, e.QuestionID)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *PgAnalyticsStore) Funnel(ctx context.Context, f *model.Form) (*model.FormFunnel, error) {
+	fn, byID := newFormFunnel(f)
+
+	// every step implies the ones before it, so views count any event and a
+	// session that submitted started even if its start event was lost.
+	// Submissions are matched to sessions by device; those of devices never
+	// tracked are left out, or the rates could pass 100%.
+	err := s.DB.Pool.QueryRow(ctx, `
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE started OR completed),
+		       COUNT(*) FILTER (WHERE completed)
+		FROM (
+			SELECT e.session_id,
+			       bool_or(e.kind IN ('start', 'progress')) AS started,
+			       EXISTS (SELECT 1 FROM submissions s WHERE s.form_id = $1 AND s.device_id = e.session_id) AS completed
+			FROM form_events e
+			WHERE e.form_id = $1
+			GROUP BY e.session_id
+		) sessions
+	`, f.ID).Scan(&fn.Views, &fn.Starts, &fn.Completions)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.DB.Pool.Query(ctx, `
+		SELECT question_id, COUNT(DISTINCT session_id)
+		FROM form_events
+		WHERE form_id = $1 AND kind = 'progress'
+		GROUP BY question_id
+	`, f.ID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var questionID uuid.UUID
+		var n int
+		if err := rows.Scan(&questionID, &n); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if q, ok := byID[questionID]; ok {
+			q.Reached = n
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// a session dropped off at the furthest question it reached, unless its
+	// device went on to submit
+	rows, err = s.DB.Pool.Query(ctx, `
+		SELECT furthest.question_id, COUNT(*)
+		FROM (
+			SELECT DISTINCT ON (e.session_id) e.session_id, e.question_id
+			FROM form_events e
+			JOIN questions q ON q.id = e.question_id
+			WHERE e.form_id = $1 AND e.kind = 'progress'
+			ORDER BY e.session_id, q.position DESC
+		) furthest
+		WHERE NOT EXISTS (
+			SELECT 1 FROM submissions s WHERE s.form_id = $1 AND s.device_id = furthest.session_id
+		)
+		GROUP BY furthest.question_id
+	`, f.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var questionID uuid.UUID
+		var n int
+		if err := rows.Scan(&questionID, &n); err != nil {
+			return nil, err
+		}
+		if q, ok := byID[questionID]; ok {
+			q.DroppedOff = n
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	finishFunnel(fn)
+	return fn, nil
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"craft/internal/db"
+	"craft/internal/model"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type PgAutoresponderStore struct {
+	DB *db.Database
+}
+
+func NewPgAutoresponderStore(database *db.Database) *PgAutoresponderStore {
+	return &PgAutoresponderStore{DB: database}
+}
+
+func (s *PgAutoresponderStore) Get(ctx context.Context, formID uuid.UUID) (*model.Autoresponder, error) {
+	var a model.Autoresponder
+	err := s.DB.Pool.QueryRow(ctx, `
+		SELECT form_id, enabled, question_id, subject, body, created_at, updated_at
+		FROM form_autoresponders
+		WHERE form_id = $1
+	`, formID).Scan(&a.FormID, &a.Enabled, &a.QuestionID, &a.Subject, &a.Body, &a.CreatedAt, &a.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (s *PgAutoresponderStore) Put(ctx context.Context, a *model.Autoresponder) error {
+	err := s.DB.Pool.QueryRow(ctx, `
+		INSERT INTO form_autoresponders (form_id, enabled, question_id, subject, body)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (form_id) DO UPDATE
+		SET enabled = EXCLUDED.enabled, question_id = EXCLUDED.question_id,
+			subject = EXCLUDED.subject, body = EXCLUDED.body, updated_at = now()
+		RETURNING created_at, updated_at
+	`, a.FormID, a.Enabled, a.QuestionID, a.Subject, a.Body).Scan(&a.CreatedAt, &a.UpdatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *PgAutoresponderStore) Delete(ctx context.Context, formID uuid.UUID) error {
+	res, err := s.DB.Pool.Exec(ctx, `DELETE FROM form_autoresponders WHERE form_id = $1`, formID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"craft/internal/db"
+	"craft/internal/model"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type PgDigestStore struct {
+	DB *db.Database
+}
+
+func NewPgDigestStore(database *db.Database) *PgDigestStore {
+	return &PgDigestStore{DB: database}
+}
+
+const digestColumns = `user_id, frequency, timezone, sent_through, updated_at`
+
+func scanDigestPreference(row pgx.Row, p *model.DigestPreference) error {
+	return row.Scan(&p.UserID, &p.Frequency, &p.Timezone, &p.SentThrough, &p.UpdatedAt)
+}
+
+func (s *PgDigestStore) Preference(ctx context.Context, userID uuid.UUID) (*model.DigestPreference, error) {
+	var p model.DigestPreference
+	err := scanDigestPreference(s.DB.Pool.QueryRow(ctx, `
+		SELECT `+digestColumns+`
+		FROM digest_preferences
+		WHERE user_id = $1
+	`, userID), &p)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *PgDigestStore) SetPreference(ctx context.Context, p *model.DigestPreference) error {
+	err := scanDigestPreference(s.DB.Pool.QueryRow(ctx, `
+		INSERT INTO digest_preferences (user_id, frequency, timezone, sent_through)
+		VALUES ($1, $2::text, $3, CASE WHEN $2::text <> 'off' THEN now() END)
+		ON CONFLICT (user_id) DO UPDATE
+		SET frequency = EXCLUDED.frequency,
+			timezone = EXCLUDED.timezone,
+			sent_through = CASE WHEN digest_preferences.frequency = 'off'
+				THEN EXCLUDED.sent_through ELSE digest_preferences.sent_through END,
+			updated_at = now()
+		RETURNING `+digestColumns+`
+	`, p.UserID, p.Frequency, p.Timezone), p)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *PgDigestStore) ListEnabled(ctx context.Context) ([]model.DigestPreference, error) {
+	rows, err := s.DB.Pool.Query(ctx, `
+		SELECT `+digestColumns+`
+		FROM digest_preferences
+		WHERE frequency <> 'off'
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prefs := []model.DigestPreference{}
+	for rows.Next() {
+		var p model.DigestPreference
+		if err := scanDigestPreference(rows, &p); err != nil {
+			return nil, err
+		}
+		prefs = append(prefs, p)
+	}
+	return prefs, rows.Err()
+}
+
+func (s *PgDigestStore) MarkSent(ctx context.Context, userID uuid.UUID, through time.Time) error {
+	_, err := s.DB.Pool.Exec(ctx, `
+		UPDATE digest_preferences
+		SET sent_through = GREATEST(sent_through, $2), updated_at = now()
+		WHERE user_id = $1
+	`, userID, through)
+	return err
+}
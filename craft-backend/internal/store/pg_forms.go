@@ -0,0 +1,717 @@
+package store
+
+import (
+	"context"
+	"craft/internal/db"
+	"craft/internal/model"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const formColumns = `id, owner_id, title, description, status, is_public, allow_multiple_submissions, close_date, thank_you_message, redirect_url, notify_owner, created_at, updated_at, version`
+
+// querier is satisfied by both *pgxpool.Pool and pgx.Tx.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type PgFormStore struct {
+	DB *db.Database
+}
+
+func NewPgFormStore(database *db.Database) *PgFormStore {
+	return &PgFormStore{DB: database}
+}
+
+func scanForm(row pgx.Row, f *model.Form, extra ...any) error {
+	dest := []any{
+		&f.ID, &f.OwnerID, &f.Title, &f.Description, &f.Status,
+		&f.IsPublic, &f.AllowMultipleSubmissions, &f.CloseDate,
+		&f.ThankYouMessage, &f.RedirectURL, &f.NotifyOwner, &f.CreatedAt, &f.UpdatedAt, &f.Version,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// loadQuestions fetches the live questions of a form, or its archived ones,
+// ordered by position. Archived questions come with every option they had,
+// so old answers can still be shown by label.
+func loadQuestions(ctx context.Context, q querier, formID uuid.UUID, archived bool) ([]model.Question, error) {
+	rows, err := q.Query(ctx, `
+		SELECT id, form_id, type, title, description, emoji, position, required, number, rating, file_upload, visibility, section_id, archived_at
+		FROM questions
+		WHERE form_id = $1 AND (archived_at IS NOT NULL) = $2
+		ORDER BY position ASC
+	`, formID, archived)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var questions []model.Question
+	questionIDs := []uuid.UUID{}
+	for rows.Next() {
+		var q model.Question
+		if err := rows.Scan(&q.ID, &q.FormID, &q.Type, &q.Title, &q.Description, &q.Emoji, &q.Position, &q.Required, &q.Number, &q.Rating, &q.FileUpload, &q.Visibility, &q.SectionID, &q.ArchivedAt); err != nil {
+			return nil, err
+		}
+		questions = append(questions, q)
+		questionIDs = append(questionIDs, q.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(questionIDs) == 0 {
+		return questions, nil
+	}
+
+	optRows, err := q.Query(ctx, `
+		SELECT id, question_id, label, position
+		FROM question_options
+		WHERE question_id = ANY($1) AND ($2 OR archived_at IS NULL)
+		ORDER BY question_id, position ASC
+	`, questionIDs, archived)
+	if err != nil {
+		return nil, err
+	}
+	defer optRows.Close()
+
+	optionsMap := make(map[uuid.UUID][]model.Option)
+	for optRows.Next() {
+		var opt model.Option
+		if err := optRows.Scan(&opt.ID, &opt.QuestionID, &opt.Label, &opt.Position); err != nil {
+			return nil, err
+		}
+		optionsMap[opt.QuestionID] = append(optionsMap[opt.QuestionID], opt)
+	}
+	if err := optRows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range questions {
+		if opts, ok := optionsMap[questions[i].ID]; ok {
+			questions[i].Options = opts
+		}
+	}
+
+	return questions, nil
+}
+
+// loadSections fetches the pages of a form ordered by position.
+func loadSections(ctx context.Context, q querier, formID uuid.UUID) ([]model.Section, error) {
+	rows, err := q.Query(ctx, `
+		SELECT id, form_id, title, description, position, jumps
+		FROM form_sections
+		WHERE form_id = $1
+		ORDER BY position ASC
+	`, formID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sections []model.Section
+	for rows.Next() {
+		var sec model.Section
+		if err := rows.Scan(&sec.ID, &sec.FormID, &sec.Title, &sec.Description, &sec.Position, &sec.Jumps); err != nil {
+			return nil, err
+		}
+		sections = append(sections, sec)
+	}
+	return sections, rows.Err()
+}
+
+// loadDefinition fills in the questions and pages of f.
+func loadDefinition(ctx context.Context, q querier, f *model.Form) error {
+	var err error
+	if f.Questions, err = loadQuestions(ctx, q, f.ID, false); err != nil {
+		return err
+	}
+	f.Sections, err = loadSections(ctx, q, f.ID)
+	return err
+}
+
+// versionMismatch explains why a versioned UPDATE of a form matched no row:
+// either the form is not the owner's, or its version has moved on.
+func versionMismatch(ctx context.Context, q querier, formID, ownerID uuid.UUID) error {
+	if err := ownsForm(ctx, q, formID, ownerID); err != nil {
+		return err
+	}
+	return ErrVersionConflict
+}
+
+func (s *PgFormStore) Create(ctx context.Context, ownerID uuid.UUID, title string, description *string) (*model.Form, error) {
+	var f model.Form
+	err := scanForm(s.DB.Pool.QueryRow(ctx, `
+		INSERT INTO forms (owner_id, title, description, status, is_public, allow_multiple_submissions)
+		VALUES ($1, $2, $3, 'draft', false, false)
+		RETURNING `+formColumns,
+		ownerID, title, description), &f)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (s *PgFormStore) Get(ctx context.Context, formID uuid.UUID) (*model.Form, error) {
+	var f model.Form
+	err := scanForm(s.DB.Pool.QueryRow(ctx, `
+		SELECT `+formColumns+`
+		FROM forms
+		WHERE id = $1
+	`, formID), &f)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := loadDefinition(ctx, s.DB.Pool, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (s *PgFormStore) GetByOwner(ctx context.Context, formID, ownerID uuid.UUID) (*model.Form, error) {
+	var f model.Form
+	err := scanForm(s.DB.Pool.QueryRow(ctx, `
+		SELECT `+formColumns+`
+		FROM forms
+		WHERE id = $1 AND owner_id = $2
+	`, formID, ownerID), &f)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := loadDefinition(ctx, s.DB.Pool, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (s *PgFormStore) GetPublished(ctx context.Context, username, slug string) (*model.PublicForm, error) {
+	var f model.Form
+
+	// replace spaces with underscores; only what respondents may see is read
+	err := s.DB.Pool.QueryRow(ctx, `
+		SELECT f.id, f.title, f.description, f.allow_multiple_submissions, f.close_date,
+		       f.thank_you_message, f.redirect_url
+		FROM forms f
+		JOIN users u ON f.owner_id = u.id
+		WHERE LOWER(REPLACE(TRIM(u.first_name), ' ', '_')) = LOWER($1)
+		  AND LOWER(REPLACE(TRIM(f.title), ' ', '_')) = LOWER($2)
+		  AND f.status = 'published' AND f.is_public = true
+		LIMIT 1
+	`, username, slug).Scan(&f.ID, &f.Title, &f.Description, &f.AllowMultipleSubmissions, &f.CloseDate,
+		&f.ThankYouMessage, &f.RedirectURL)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := loadDefinition(ctx, s.DB.Pool, &f); err != nil {
+		return nil, err
+	}
+	return f.Public(), nil
+}
+
+func (s *PgFormStore) ArchivedQuestions(ctx context.Context, formID uuid.UUID) ([]model.Question, error) {
+	return loadQuestions(ctx, s.DB.Pool, formID, true)
+}
+
+func (s *PgFormStore) ListByOwner(ctx context.Context, ownerID uuid.UUID) ([]model.Form, error) {
+	rows, err := s.DB.Pool.Query(ctx, `
+		SELECT f.id, f.owner_id, f.title, f.description, f.status, f.is_public,
+		       f.allow_multiple_submissions, f.close_date, f.thank_you_message, f.redirect_url,
+		       f.notify_owner, f.created_at, f.updated_at, f.version,
+		       (SELECT COUNT(*) FROM submissions s WHERE s.form_id = f.id) as response_count
+		FROM forms f
+		WHERE f.owner_id = $1
+		ORDER BY f.updated_at DESC
+	`, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var forms []model.Form
+	for rows.Next() {
+		var f model.Form
+		if err := scanForm(rows, &f, &f.Responses); err != nil {
+			return nil, err
+		}
+		forms = append(forms, f)
+	}
+	return forms, rows.Err()
+}
+
+func (s *PgFormStore) ListAll(ctx context.Context) ([]model.Form, error) {
+	rows, err := s.DB.Pool.Query(ctx, `
+		SELECT `+formColumns+`
+		FROM forms
+		ORDER BY created_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var forms []model.Form
+	for rows.Next() {
+		var f model.Form
+		if err := scanForm(rows, &f); err != nil {
+			return nil, err
+		}
+		forms = append(forms, f)
+	}
+	return forms, rows.Err()
+}
+
+func (s *PgFormStore) Update(ctx context.Context, ownerID uuid.UUID, f *model.Form) error {
+	tx, err := s.DB.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var saved model.Form
+	err = scanForm(tx.QueryRow(ctx, `
+		UPDATE forms
+		SET title = $1, description = $2, updated_at = NOW(), version = version + 1
+		WHERE id = $3 AND owner_id = $4 AND ($5 = 0 OR version = $5)
+		RETURNING `+formColumns+`
+	`, f.Title, f.Description, f.ID, ownerID, f.Version), &saved)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return versionMismatch(ctx, tx, f.ID, ownerID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := freshenQuestionIDs(ctx, tx, f.ID, f.Sections, f.Questions); err != nil {
+		return err
+	}
+	if err := syncSections(ctx, tx, f.ID, f.Sections, f.Questions); err != nil {
+		return err
+	}
+	if err := syncQuestions(ctx, tx, f.ID, f.Questions); err != nil {
+		return err
+	}
+
+	if _, err := snapshotRevision(ctx, tx, f.ID, model.RevisionReasonSave); err != nil {
+		return err
+	}
+	if err := loadDefinition(ctx, tx, &saved); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	*f = saved
+	return nil
+}
+
+// syncQuestions makes the live questions of a form match questions, in order.
+// Questions and options are matched by ID: known ones are updated in place
+// (restoring them if archived), unknown ones are inserted, and live ones
+// missing from the list are archived so existing answers keep their question.
+func syncQuestions(ctx context.Context, tx pgx.Tx, formID uuid.UUID, questions []model.Question) error {
+	rows, err := tx.Query(ctx, `SELECT id FROM questions WHERE form_id = $1`, formID)
+	if err != nil {
+		return err
+	}
+	existing, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return err
+	}
+	known := make(map[uuid.UUID]bool, len(existing))
+	for _, id := range existing {
+		known[id] = true
+	}
+
+	kept := make([]uuid.UUID, 0, len(questions))
+	for i, q := range questions {
+		if q.ID == uuid.Nil {
+			q.ID = uuid.New()
+		}
+
+		if known[q.ID] {
+			_, err = tx.Exec(ctx, `
+				UPDATE questions
+				SET type = $1, title = $2, description = $3, emoji = $4, position = $5, required = $6, number = $9, rating = $10, file_upload = $11, visibility = $12, section_id = $13, archived_at = NULL
+				WHERE id = $7 AND form_id = $8
+			`, q.Type, q.Title, q.Description, q.Emoji, i, q.Required, q.ID, formID, q.Number, q.Rating, q.FileUpload, q.Visibility, q.SectionID)
+		} else {
+			q.ID, err = insertWithFreshID(q.ID, func(id uuid.UUID) (pgconn.CommandTag, error) {
+				return tx.Exec(ctx, `
+					INSERT INTO questions (id, form_id, type, title, description, emoji, position, required, number, rating, file_upload, visibility, section_id)
+					VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+					ON CONFLICT (id) DO NOTHING
+				`, id, formID, q.Type, q.Title, q.Description, q.Emoji, i, q.Required, q.Number, q.Rating, q.FileUpload, q.Visibility, q.SectionID)
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := syncOptions(ctx, tx, q.ID, q.Options); err != nil {
+			return err
+		}
+		kept = append(kept, q.ID)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE questions
+		SET archived_at = NOW()
+		WHERE form_id = $1 AND archived_at IS NULL AND NOT (id = ANY($2))
+	`, formID, kept)
+	return err
+}
+
+// freshenQuestionIDs replaces the IDs of questions and options that belong to
+// another form, as syncSections does for pages, and points visibility rules
+// and jumps at the replacements before anything refers to them, so this may
+// change sections and questions in place.
+func freshenQuestionIDs(ctx context.Context, tx pgx.Tx, formID uuid.UUID, sections []model.Section, questions []model.Question) error {
+	var ids []uuid.UUID
+	for _, q := range questions {
+		ids = append(ids, q.ID)
+		for _, opt := range q.Options {
+			ids = append(ids, opt.ID)
+		}
+	}
+	rows, err := tx.Query(ctx, `
+		SELECT id FROM questions WHERE id = ANY($1) AND form_id <> $2
+		UNION ALL
+		SELECT o.id FROM question_options o JOIN questions q ON q.id = o.question_id
+		WHERE o.id = ANY($1) AND q.form_id <> $2
+	`, ids, formID)
+	if err != nil {
+		return err
+	}
+	taken, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil || len(taken) == 0 {
+		return err
+	}
+
+	fresh := make(map[uuid.UUID]uuid.UUID, len(taken))
+	for _, id := range taken {
+		fresh[id] = uuid.New()
+	}
+	for i := range questions {
+		q := &questions[i]
+		if id, ok := fresh[q.ID]; ok {
+			q.ID = id
+		}
+		for j := range q.Options {
+			if id, ok := fresh[q.Options[j].ID]; ok {
+				q.Options[j].ID = id
+			}
+		}
+		q.Visibility = q.Visibility.Remap(fresh)
+	}
+	for i := range sections {
+		sections[i] = sections[i].Remap(fresh)
+	}
+	return nil
+}
+
+// syncSections makes the pages of a form match sections, in order. Pages
+// are matched by ID and those left out are deleted; their questions keep no
+// page. IDs that belong to another form's pages are replaced, and questions
+// and jumps are pointed at the replacements, so this may change sections
+// and questions in place.
+func syncSections(ctx context.Context, tx pgx.Tx, formID uuid.UUID, sections []model.Section, questions []model.Question) error {
+	ids := make([]uuid.UUID, len(sections))
+	for i, sec := range sections {
+		ids[i] = sec.ID
+	}
+	rows, err := tx.Query(ctx, `
+		SELECT id FROM form_sections WHERE id = ANY($1) AND form_id <> $2
+	`, ids, formID)
+	if err != nil {
+		return err
+	}
+	taken, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return err
+	}
+	if len(taken) > 0 {
+		fresh := make(map[uuid.UUID]uuid.UUID, len(taken))
+		for _, id := range taken {
+			fresh[id] = uuid.New()
+		}
+		for i := range sections {
+			sections[i] = sections[i].Remap(fresh)
+			ids[i] = sections[i].ID
+		}
+		for i, q := range questions {
+			if q.SectionID != nil {
+				if id, ok := fresh[*q.SectionID]; ok {
+					questions[i].SectionID = &id
+				}
+			}
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM form_sections WHERE form_id = $1 AND NOT (id = ANY($2))
+	`, formID, ids)
+	if err != nil {
+		return err
+	}
+	for i, sec := range sections {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO form_sections (id, form_id, title, description, position, jumps)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (id) DO UPDATE
+			SET title = EXCLUDED.title, description = EXCLUDED.description,
+			    position = EXCLUDED.position, jumps = EXCLUDED.jumps
+		`, sec.ID, formID, sec.Title, sec.Description, i, sec.Jumps)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertWithFreshID runs an ON CONFLICT DO NOTHING insert with id and, if the
+// ID is already taken by another form's row, once more with a new one. It
+// returns the ID the row was stored under.
+func insertWithFreshID(id uuid.UUID, insert func(id uuid.UUID) (pgconn.CommandTag, error)) (uuid.UUID, error) {
+	tag, err := insert(id)
+	if err != nil || tag.RowsAffected() == 1 {
+		return id, err
+	}
+	id = uuid.New()
+	_, err = insert(id)
+	return id, err
+}
+
+func syncOptions(ctx context.Context, tx pgx.Tx, questionID uuid.UUID, options []model.Option) error {
+	rows, err := tx.Query(ctx, `SELECT id FROM question_options WHERE question_id = $1`, questionID)
+	if err != nil {
+		return err
+	}
+	existing, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return err
+	}
+	known := make(map[uuid.UUID]bool, len(existing))
+	for _, id := range existing {
+		known[id] = true
+	}
+
+	kept := make([]uuid.UUID, 0, len(options))
+	for j, opt := range options {
+		if opt.ID == uuid.Nil {
+			opt.ID = uuid.New()
+		}
+
+		if known[opt.ID] {
+			_, err = tx.Exec(ctx, `
+				UPDATE question_options
+				SET label = $1, position = $2, archived_at = NULL
+				WHERE id = $3 AND question_id = $4
+			`, opt.Label, j, opt.ID, questionID)
+		} else {
+			opt.ID, err = insertWithFreshID(opt.ID, func(id uuid.UUID) (pgconn.CommandTag, error) {
+				return tx.Exec(ctx, `
+					INSERT INTO question_options (id, question_id, label, position)
+					VALUES ($1, $2, $3, $4)
+					ON CONFLICT (id) DO NOTHING
+				`, id, questionID, opt.Label, j)
+			})
+		}
+		if err != nil {
+			return err
+		}
+		kept = append(kept, opt.ID)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE question_options
+		SET archived_at = NOW()
+		WHERE question_id = $1 AND archived_at IS NULL AND NOT (id = ANY($2))
+	`, questionID, kept)
+	return err
+}
+
+func (s *PgFormStore) UpdateSettings(ctx context.Context, ownerID uuid.UUID, f *model.Form) error {
+	err := s.DB.Pool.QueryRow(ctx, `
+		UPDATE forms
+		SET is_public = $1, allow_multiple_submissions = $2, close_date = $3,
+		    thank_you_message = $4, redirect_url = $5, notify_owner = $9, updated_at = NOW(),
+		    version = version + 1
+		WHERE id = $6 AND owner_id = $7 AND ($8 = 0 OR version = $8)
+		RETURNING updated_at, version
+	`, f.IsPublic, f.AllowMultipleSubmissions, f.CloseDate, f.ThankYouMessage, f.RedirectURL, f.ID, ownerID, f.Version, f.NotifyOwner).Scan(&f.UpdatedAt, &f.Version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return versionMismatch(ctx, s.DB.Pool, f.ID, ownerID)
+	}
+	return err
+}
+
+func (s *PgFormStore) Duplicate(ctx context.Context, formID, ownerID uuid.UUID) (*model.Form, error) {
+	tx, err := s.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var original model.Form
+	err = tx.QueryRow(ctx, `
+		SELECT title, description
+		FROM forms
+		WHERE id = $1 AND owner_id = $2
+	`, formID, ownerID).Scan(&original.Title, &original.Description)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var newForm model.Form
+	err = scanForm(tx.QueryRow(ctx, `
+		INSERT INTO forms (owner_id, title, description, status, is_public, allow_multiple_submissions)
+		VALUES ($1, $2, $3, 'draft', false, false)
+		RETURNING `+formColumns,
+		ownerID, "Copy of "+original.Title, original.Description), &newForm)
+	if err != nil {
+		return nil, err
+	}
+
+	var source model.Form
+	source.ID = formID
+	if err := loadDefinition(ctx, tx, &source); err != nil {
+		return nil, err
+	}
+	questions := source.Questions
+
+	// visibility rules and jumps refer to questions, options and pages by
+	// ID, so every copy gets its ID up front
+	ids := make(map[uuid.UUID]uuid.UUID)
+	for _, sec := range source.Sections {
+		ids[sec.ID] = uuid.New()
+	}
+	for _, q := range questions {
+		ids[q.ID] = uuid.New()
+		for _, opt := range q.Options {
+			ids[opt.ID] = uuid.New()
+		}
+	}
+
+	for _, sec := range source.Sections {
+		sec = sec.Remap(ids)
+		_, err = tx.Exec(ctx, `
+			INSERT INTO form_sections (id, form_id, title, description, position, jumps)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, sec.ID, newForm.ID, sec.Title, sec.Description, sec.Position, sec.Jumps)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, q := range questions {
+		newQuestionID := ids[q.ID]
+		var sectionID *uuid.UUID
+		if q.SectionID != nil {
+			if id, ok := ids[*q.SectionID]; ok {
+				sectionID = &id
+			}
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO questions (id, form_id, type, title, description, emoji, position, required, number, rating, file_upload, visibility, section_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		`, newQuestionID, newForm.ID, q.Type, q.Title, q.Description, q.Emoji, q.Position, q.Required, q.Number, q.Rating, q.FileUpload, q.Visibility.Remap(ids), sectionID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, opt := range q.Options {
+			_, err = tx.Exec(ctx, `
+				INSERT INTO question_options (id, question_id, label, position)
+				VALUES ($1, $2, $3, $4)
+			`, ids[opt.ID], newQuestionID, opt.Label, opt.Position)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &newForm, nil
+}
+
+func (s *PgFormStore) SetStatus(ctx context.Context, formID, ownerID uuid.UUID, status string, isPublic bool, version int) (int, error) {
+	tx, err := s.DB.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var newVersion int
+	err = tx.QueryRow(ctx, `
+		UPDATE forms
+		SET status = $1, is_public = $2, updated_at = NOW(), version = version + 1
+		WHERE id = $3 AND owner_id = $4 AND ($5 = 0 OR version = $5)
+		RETURNING version
+	`, status, isPublic, formID, ownerID, version).Scan(&newVersion)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, versionMismatch(ctx, tx, formID, ownerID)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if status == model.FormStatusPublished {
+		if _, err := snapshotRevision(ctx, tx, formID, model.RevisionReasonPublish); err != nil {
+			return 0, err
+		}
+	}
+
+	return newVersion, tx.Commit(ctx)
+}
+
+func (s *PgFormStore) Delete(ctx context.Context, formID, ownerID uuid.UUID) error {
+	res, err := s.DB.Pool.Exec(ctx, `
+		DELETE FROM forms
+		WHERE id = $1 AND owner_id = $2
+	`, formID, ownerID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PgFormStore) DeleteAny(ctx context.Context, formID uuid.UUID) error {
+	_, err := s.DB.Pool.Exec(ctx, `DELETE FROM forms WHERE id = $1`, formID)
+	return err
+}
+
+func (s *PgFormStore) CountPublished(ctx context.Context) (int, error) {
+	var count int
+	err := s.DB.Pool.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM forms
+		WHERE status = 'published'
+	`).Scan(&count)
+	return count, err
+}
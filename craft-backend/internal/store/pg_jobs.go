@@ -0,0 +1,137 @@
+package store
+
+import (
+	"context"
+	"craft/internal/db"
+	"craft/internal/model"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type PgJobStore struct {
+	DB *db.Database
+}
+
+func NewPgJobStore(database *db.Database) *PgJobStore {
+	return &PgJobStore{DB: database}
+}
+
+const jobColumns = `id, kind, payload, unique_key, status, attempts, run_at, locked_until, last_error, created_at, updated_at`
+
+func scanJob(row pgx.Row, j *model.Job) error {
+	return row.Scan(&j.ID, &j.Kind, &j.Payload, &j.UniqueKey, &j.Status, &j.Attempts, &j.RunAt, &j.LockedUntil, &j.LastError, &j.CreatedAt, &j.UpdatedAt)
+}
+
+func collectJobs(rows pgx.Rows) ([]model.Job, error) {
+	defer rows.Close()
+
+	jobs := []model.Job{}
+	for rows.Next() {
+		var j model.Job
+		if err := scanJob(rows, &j); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+func (s *PgJobStore) Enqueue(ctx context.Context, j *model.Job) error {
+	var runAt *time.Time
+	if !j.RunAt.IsZero() {
+		runAt = &j.RunAt
+	}
+	err := scanJob(s.DB.Pool.QueryRow(ctx, `
+		INSERT INTO jobs (kind, payload, run_at, unique_key)
+		VALUES ($1, $2, COALESCE($3, now()), $4)
+		ON CONFLICT (unique_key) WHERE status = 'pending' DO NOTHING
+		RETURNING `+jobColumns+`
+	`, j.Kind, j.Payload, runAt, j.UniqueKey), j)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	return err
+}
+
+func (s *PgJobStore) Claim(ctx context.Context, kinds []string, limit int, lease time.Duration) ([]model.Job, error) {
+	rows, err := s.DB.Pool.Query(ctx, `
+		WITH due AS (
+			SELECT id AS job_id
+			FROM jobs
+			WHERE kind = ANY($1)
+				AND ((status = 'pending' AND run_at <= now())
+					OR (status = 'running' AND locked_until <= now()))
+			ORDER BY run_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE jobs j
+		SET status = 'running',
+			unique_key = NULL,
+			attempts = j.attempts + 1,
+			locked_until = now() + make_interval(secs => $3),
+			updated_at = now()
+		FROM due
+		WHERE j.id = due.job_id
+		RETURNING `+jobColumns+`
+	`, kinds, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	return collectJobs(rows)
+}
+
+func (s *PgJobStore) Complete(ctx context.Context, jobID uuid.UUID, attempt int) error {
+	_, err := s.DB.Pool.Exec(ctx, `
+		DELETE FROM jobs
+		WHERE id = $1 AND status = 'running' AND attempts = $2
+	`, jobID, attempt)
+	return err
+}
+
+func (s *PgJobStore) Fail(ctx context.Context, jobID uuid.UUID, attempt int, message string, retryAt *time.Time) error {
+	_, err := s.DB.Pool.Exec(ctx, `
+		UPDATE jobs
+		SET status = CASE WHEN $4::timestamptz IS NULL THEN 'dead' ELSE 'pending' END,
+			run_at = COALESCE($4, run_at),
+			locked_until = NULL,
+			last_error = $3,
+			updated_at = now()
+		WHERE id = $1 AND status = 'running' AND attempts = $2
+	`, jobID, attempt, message, retryAt)
+	return err
+}
+
+func (s *PgJobStore) ListDead(ctx context.Context, limit int) ([]model.Job, error) {
+	rows, err := s.DB.Pool.Query(ctx, `
+		SELECT `+jobColumns+`
+		FROM jobs
+		WHERE status = 'dead'
+		ORDER BY updated_at DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectJobs(rows)
+}
+
+func (s *PgJobStore) Retry(ctx context.Context, jobID uuid.UUID) (*model.Job, error) {
+	var j model.Job
+	err := scanJob(s.DB.Pool.QueryRow(ctx, `
+		UPDATE jobs
+		SET status = 'pending', attempts = 0, run_at = now(), updated_at = now()
+		WHERE id = $1 AND status = 'dead'
+		RETURNING `+jobColumns+`
+	`, jobID), &j)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
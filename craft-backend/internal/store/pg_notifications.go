@@ -0,0 +1,62 @@
+package store
+
+import (
+	"context"
+	"craft/internal/db"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type PgNotificationStore struct {
+	DB *db.Database
+}
+
+func NewPgNotificationStore(database *db.Database) *PgNotificationStore {
+	return &PgNotificationStore{DB: database}
+}
+
+func (s *PgNotificationStore) Watermark(ctx context.Context, key string) (*SubmissionCursor, error) {
+	var c SubmissionCursor
+	err := s.DB.Pool.QueryRow(ctx, `
+		SELECT created_at, submission_id
+		FROM notification_watermarks
+		WHERE key = $1
+	`, key).Scan(&c.CreatedAt, &c.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (s *PgNotificationStore) SetWatermark(ctx context.Context, key string, c SubmissionCursor) error {
+	_, err := s.DB.Pool.Exec(ctx, `
+		INSERT INTO notification_watermarks (key, created_at, submission_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE
+		SET created_at = EXCLUDED.created_at, submission_id = EXCLUDED.submission_id, updated_at = now()
+	`, key, c.CreatedAt, c.ID)
+	return err
+}
+
+func (s *PgNotificationStore) CountSend(ctx context.Context, key string, window time.Duration) (int, error) {
+	// ended windows are dropped as they go, keeping the table small
+	if _, err := s.DB.Pool.Exec(ctx, `DELETE FROM notification_throttles WHERE expires_at <= now()`); err != nil {
+		return 0, err
+	}
+
+	var n int
+	err := s.DB.Pool.QueryRow(ctx, `
+		INSERT INTO notification_throttles (key, count, expires_at)
+		VALUES ($1, 1, now() + make_interval(secs => $2))
+		ON CONFLICT (key) DO UPDATE
+		SET count = CASE WHEN notification_throttles.expires_at <= now() THEN 1 ELSE notification_throttles.count + 1 END,
+		    expires_at = CASE WHEN notification_throttles.expires_at <= now() THEN EXCLUDED.expires_at ELSE notification_throttles.expires_at END
+		RETURNING count
+	`, key, window.Seconds()).Scan(&n)
+	return n, err
+}
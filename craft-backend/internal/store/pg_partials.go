@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"craft/internal/db"
+	"craft/internal/model"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type PgPartialStore struct {
+	DB *db.Database
+}
+
+func NewPgPartialStore(database *db.Database) *PgPartialStore {
+	return &PgPartialStore{DB: database}
+}
+
+func (s *PgPartialStore) Create(ctx context.Context, p *model.PartialResponse) error {
+	if p.Answers == nil {
+		p.Answers = []model.PartialAnswer{}
+	}
+	err := s.DB.Pool.QueryRow(ctx, `
+		INSERT INTO partial_responses (form_id, token_hash, answers, respondent_email, section_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`, p.FormID, p.TokenHash, p.Answers, p.RespondentEmail, p.SectionID, p.ExpiresAt).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *PgPartialStore) GetByToken(ctx context.Context, formID uuid.UUID, tokenHash string) (*model.PartialResponse, error) {
+	var p model.PartialResponse
+	err := s.DB.Pool.QueryRow(ctx, `
+		SELECT id, form_id, token_hash, answers, respondent_email, section_id, created_at, updated_at, expires_at
+		FROM partial_responses
+		WHERE form_id = $1 AND token_hash = $2 AND expires_at > NOW()
+	`, formID, tokenHash).Scan(&p.ID, &p.FormID, &p.TokenHash, &p.Answers, &p.RespondentEmail, &p.SectionID, &p.CreatedAt, &p.UpdatedAt, &p.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *PgPartialStore) Update(ctx context.Context, p *model.PartialResponse) error {
+	if p.Answers == nil {
+		p.Answers = []model.PartialAnswer{}
+	}
+	err := s.DB.Pool.QueryRow(ctx, `
+		UPDATE partial_responses
+		SET answers = $1, respondent_email = $2, section_id = $3, expires_at = $4, updated_at = NOW()
+		WHERE id = $5
+		RETURNING updated_at
+	`, p.Answers, p.RespondentEmail, p.SectionID, p.ExpiresAt, p.ID).Scan(&p.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *PgPartialStore) Delete(ctx context.Context, partialID uuid.UUID) error {
+	res, err := s.DB.Pool.Exec(ctx, `DELETE FROM partial_responses WHERE id = $1`, partialID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PgPartialStore) DeleteExpired(ctx context.Context, before time.Time, limit int) (int, error) {
+	res, err := s.DB.Pool.Exec(ctx, `
+		DELETE FROM partial_responses
+		WHERE id IN (
+			SELECT id FROM partial_responses
+			WHERE expires_at < $1
+			ORDER BY expires_at
+			LIMIT $2
+		)
+	`, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.RowsAffected()), nil
+}
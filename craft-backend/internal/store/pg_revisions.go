@@ -0,0 +1,191 @@
+package store
+
+import (
+	"context"
+	"craft/internal/db"
+	"craft/internal/model"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type PgRevisionStore struct {
+	DB *db.Database
+}
+
+func NewPgRevisionStore(database *db.Database) *PgRevisionStore {
+	return &PgRevisionStore{DB: database}
+}
+
+// snapshotRevision records the form's current title, description and live
+// questions as its next revision. Callers must already hold the form row lock
+// (any UPDATE of the row in tx does) so revision numbers stay sequential.
+func snapshotRevision(ctx context.Context, tx pgx.Tx, formID uuid.UUID, reason string) (*model.FormRevision, error) {
+	rev := model.FormRevision{FormID: formID, Reason: reason}
+	err := tx.QueryRow(ctx, `
+		SELECT title, description FROM forms WHERE id = $1
+	`, formID).Scan(&rev.Title, &rev.Description)
+	if err != nil {
+		return nil, err
+	}
+
+	rev.Questions, err = loadQuestions(ctx, tx, formID, false)
+	if err != nil {
+		return nil, err
+	}
+	if rev.Questions == nil {
+		rev.Questions = []model.Question{}
+	}
+	definition, err := json.Marshal(rev.Questions)
+	if err != nil {
+		return nil, err
+	}
+	rev.Sections, err = loadSections(ctx, tx, formID)
+	if err != nil {
+		return nil, err
+	}
+	if rev.Sections == nil {
+		rev.Sections = []model.Section{}
+	}
+	sections, err := json.Marshal(rev.Sections)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO form_revisions (form_id, revision, reason, title, description, questions, sections)
+		VALUES ($1, (SELECT COALESCE(MAX(revision), 0) + 1 FROM form_revisions WHERE form_id = $1), $2, $3, $4, $5, $6)
+		RETURNING id, revision, created_at
+	`, formID, reason, rev.Title, rev.Description, definition, sections).Scan(&rev.ID, &rev.Revision, &rev.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+func ownsForm(ctx context.Context, q querier, formID, ownerID uuid.UUID) error {
+	var exists bool
+	err := q.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM forms WHERE id = $1 AND owner_id = $2)
+	`, formID, ownerID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PgRevisionStore) List(ctx context.Context, formID, ownerID uuid.UUID) ([]model.FormRevision, error) {
+	if err := ownsForm(ctx, s.DB.Pool, formID, ownerID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.DB.Pool.Query(ctx, `
+		SELECT r.id, r.form_id, r.revision, r.reason, r.title, r.description, r.created_at,
+		       (SELECT COUNT(*) FROM submissions s WHERE s.revision_id = r.id) as response_count
+		FROM form_revisions r
+		WHERE r.form_id = $1
+		ORDER BY r.revision DESC
+	`, formID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []model.FormRevision{}
+	for rows.Next() {
+		var r model.FormRevision
+		if err := rows.Scan(&r.ID, &r.FormID, &r.Revision, &r.Reason, &r.Title, &r.Description, &r.CreatedAt, &r.Responses); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+	return revisions, rows.Err()
+}
+
+func (s *PgRevisionStore) Get(ctx context.Context, formID, ownerID uuid.UUID, revision int) (*model.FormRevision, error) {
+	return getRevision(ctx, s.DB.Pool, formID, ownerID, revision)
+}
+
+func getRevision(ctx context.Context, q querier, formID, ownerID uuid.UUID, revision int) (*model.FormRevision, error) {
+	var r model.FormRevision
+	var definition, sections []byte
+	err := q.QueryRow(ctx, `
+		SELECT r.id, r.form_id, r.revision, r.reason, r.title, r.description, r.questions, r.sections, r.created_at,
+		       (SELECT COUNT(*) FROM submissions s WHERE s.revision_id = r.id) as response_count
+		FROM form_revisions r
+		JOIN forms f ON f.id = r.form_id
+		WHERE r.form_id = $1 AND f.owner_id = $2 AND r.revision = $3
+	`, formID, ownerID, revision).Scan(&r.ID, &r.FormID, &r.Revision, &r.Reason, &r.Title, &r.Description, &definition, &sections, &r.CreatedAt, &r.Responses)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(definition, &r.Questions); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(sections, &r.Sections); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (s *PgRevisionStore) Restore(ctx context.Context, formID, ownerID uuid.UUID, revision, version int) (*model.FormRevision, error) {
+	tx, err := s.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// lock the form first so the snapshot below gets the next revision number
+	res, err := tx.Exec(ctx, `
+		UPDATE forms
+		SET updated_at = NOW(), version = version + 1
+		WHERE id = $1 AND owner_id = $2 AND ($3 = 0 OR version = $3)
+	`, formID, ownerID, version)
+	if err != nil {
+		return nil, err
+	}
+	if res.RowsAffected() == 0 {
+		return nil, versionMismatch(ctx, tx, formID, ownerID)
+	}
+
+	old, err := getRevision(ctx, tx, formID, ownerID, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE forms SET title = $1, description = $2 WHERE id = $3
+	`, old.Title, old.Description, formID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := freshenQuestionIDs(ctx, tx, formID, old.Sections, old.Questions); err != nil {
+		return nil, err
+	}
+	if err := syncSections(ctx, tx, formID, old.Sections, old.Questions); err != nil {
+		return nil, err
+	}
+	if err := syncQuestions(ctx, tx, formID, old.Questions); err != nil {
+		return nil, err
+	}
+
+	restored, err := snapshotRevision(ctx, tx, formID, model.RevisionReasonRestore)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
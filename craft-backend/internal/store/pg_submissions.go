@@ -0,0 +1,315 @@
+package store
+
+import (
+	"context"
+	"craft/internal/db"
+	"craft/internal/model"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type PgSubmissionStore struct {
+	DB *db.Database
+}
+
+func NewPgSubmissionStore(database *db.Database) *PgSubmissionStore {
+	return &PgSubmissionStore{DB: database}
+}
+
+func (s *PgSubmissionStore) Create(ctx context.Context, sub *model.Submission, answers []model.Answer, partialID *uuid.UUID) error {
+	return pgx.BeginFunc(ctx, s.DB.Pool, func(tx pgx.Tx) error {
+		return insertSubmission(ctx, tx, sub, answers, partialID)
+	})
+}
+
+func (s *PgSubmissionStore) CreateUnique(ctx context.Context, sub *model.Submission, answers []model.Answer, partialID *uuid.UUID, match RespondentMatch) error {
+	return pgx.BeginFunc(ctx, s.DB.Pool, func(tx pgx.Tx) error {
+		// serialize single-response submissions per form so two concurrent
+		// requests from the same respondent cannot both pass the check
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1::text))`, sub.FormID); err != nil {
+			return err
+		}
+
+		var exists bool
+		err := tx.QueryRow(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM submissions
+				WHERE form_id = $1
+				  AND (respondent_user_id = $2
+				    OR LOWER(respondent_email) = LOWER($3)
+				    OR device_id = $4
+				    OR fingerprint = $5)
+			)
+		`, sub.FormID, match.UserID, match.Email, match.DeviceID, match.Fingerprint).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrAlreadySubmitted
+		}
+
+		return insertSubmission(ctx, tx, sub, answers, partialID)
+	})
+}
+
+func insertSubmission(ctx context.Context, tx pgx.Tx, sub *model.Submission, answers []model.Answer, partialID *uuid.UUID) error {
+	// claiming the partial first makes a concurrent submission of it wait on
+	// the row lock, then find it gone once this one commits
+	if partialID != nil {
+		err := tx.QueryRow(ctx, `DELETE FROM partial_responses WHERE id = $1 RETURNING id`, *partialID).Scan(new(uuid.UUID))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrPartialGone
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if sub.ID == uuid.Nil {
+		sub.ID = uuid.New()
+	}
+
+	// answers are given against the latest revision, which is always the
+	// form's live definition since every save records one. created_at is the
+	// time of the insert rather than of the transaction's start, keeping the
+	// gap to the commit within SubmissionSettleTime.
+	err := tx.QueryRow(ctx, `
+		INSERT INTO submissions (id, form_id, revision_id, respondent_email, respondent_user_id, ip_address, user_agent, device_id, fingerprint, created_at)
+		VALUES ($1, $2, (SELECT id FROM form_revisions WHERE form_id = $2 ORDER BY revision DESC LIMIT 1), $3, $4, $5, $6, $7, $8, clock_timestamp())
+		RETURNING created_at, revision_id
+	`, sub.ID, sub.FormID, sub.RespondentEmail, sub.RespondentUserID, sub.IPAddress, sub.UserAgent, sub.DeviceID, sub.Fingerprint).Scan(&sub.CreatedAt, &sub.RevisionID)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" && pgErr.ConstraintName == "submissions_form_id_fkey" {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	for i := range answers {
+		a := &answers[i]
+		if a.ID == uuid.Nil {
+			a.ID = uuid.New()
+		}
+		a.SubmissionID = sub.ID
+
+		err = tx.QueryRow(ctx, `
+			INSERT INTO answers (id, submission_id, question_id, value)
+			VALUES ($1, $2, $3, $4)
+			RETURNING created_at
+		`, a.ID, a.SubmissionID, a.QuestionID, a.Value).Scan(&a.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// submissionFilters builds the WHERE clause shared by the page and count
+// queries of ListByForm, appending its parameters to args.
+func submissionFilters(formID uuid.UUID, q SubmissionQuery, args *[]any) string {
+	arg := func(v any) string {
+		*args = append(*args, v)
+		return "$" + strconv.Itoa(len(*args))
+	}
+
+	where := []string{"s.form_id = " + arg(formID)}
+	if q.CreatedFrom != nil {
+		where = append(where, "s.created_at >= "+arg(*q.CreatedFrom))
+	}
+	if q.CreatedTo != nil {
+		where = append(where, "s.created_at < "+arg(*q.CreatedTo))
+	}
+	for _, f := range q.Answers {
+		where = append(where, `EXISTS (
+			SELECT 1 FROM answers a
+			WHERE a.submission_id = s.id AND a.question_id = `+arg(f.QuestionID)+` AND a.value @> `+arg(f.Value)+`::jsonb
+		)`)
+	}
+	if q.Search != "" {
+		// matches the expression of the answers_value_fts index
+		where = append(where, `EXISTS (
+			SELECT 1 FROM answers a
+			WHERE a.submission_id = s.id
+			  AND jsonb_typeof(a.value) = 'string'
+			  AND to_tsvector('simple', a.value #>> '{}') @@ plainto_tsquery('simple', `+arg(q.Search)+`)
+		)`)
+	}
+	return strings.Join(where, " AND ")
+}
+
+func (s *PgSubmissionStore) ListByForm(ctx context.Context, formID uuid.UUID, q SubmissionQuery) (*SubmissionPage, error) {
+	var countArgs []any
+	page := &SubmissionPage{Submissions: []model.SubmissionWithAnswers{}}
+	err := s.DB.Pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM submissions s WHERE `+submissionFilters(formID, q, &countArgs),
+		countArgs...).Scan(&page.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	var args []any
+	where := submissionFilters(formID, q, &args)
+	if q.After != nil {
+		args = append(args, q.After.CreatedAt, q.After.ID)
+		where += fmt.Sprintf(" AND (s.created_at, s.id) < ($%d, $%d)", len(args)-1, len(args))
+	}
+	limit := q.limit()
+	// one extra row tells whether another page follows
+	args = append(args, limit+1)
+
+	subRows, err := s.DB.Pool.Query(ctx, `
+		SELECT s.id, s.form_id, s.revision_id, s.respondent_email, s.respondent_user_id, s.ip_address::text, s.user_agent, s.created_at
+		FROM submissions s
+		WHERE `+where+`
+		ORDER BY s.created_at DESC, s.id DESC
+		LIMIT $`+strconv.Itoa(len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer subRows.Close()
+
+	var submissionIDs []uuid.UUID
+	index := make(map[uuid.UUID]int)
+
+	for subRows.Next() {
+		var sub model.SubmissionWithAnswers
+		if err := subRows.Scan(&sub.ID, &sub.FormID, &sub.RevisionID, &sub.RespondentEmail, &sub.RespondentUserID, &sub.IPAddress, &sub.UserAgent, &sub.CreatedAt); err != nil {
+			return nil, err
+		}
+		sub.Answers = []model.Answer{}
+		index[sub.ID] = len(page.Submissions)
+		submissionIDs = append(submissionIDs, sub.ID)
+		page.Submissions = append(page.Submissions, sub)
+	}
+	if err := subRows.Err(); err != nil {
+		return nil, err
+	}
+	subRows.Close()
+
+	if len(page.Submissions) > limit {
+		page.Submissions = page.Submissions[:limit]
+		submissionIDs = submissionIDs[:limit]
+		last := page.Submissions[limit-1]
+		page.Next = &SubmissionCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+
+	if len(submissionIDs) == 0 {
+		return page, nil
+	}
+
+	ansRows, err := s.DB.Pool.Query(ctx, `
+		SELECT a.id, a.submission_id, a.question_id, a.value, a.created_at,
+		       q.title, q.type, q.archived_at IS NOT NULL
+		FROM answers a
+		JOIN questions q ON q.id = a.question_id
+		WHERE a.submission_id = ANY($1)
+		ORDER BY q.position ASC
+	`, submissionIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer ansRows.Close()
+
+	for ansRows.Next() {
+		var a model.Answer
+		if err := ansRows.Scan(&a.ID, &a.SubmissionID, &a.QuestionID, &a.Value, &a.CreatedAt, &a.QuestionTitle, &a.QuestionType, &a.QuestionArchived); err != nil {
+			return nil, err
+		}
+		i := index[a.SubmissionID]
+		page.Submissions[i].Answers = append(page.Submissions[i].Answers, a)
+	}
+
+	return page, ansRows.Err()
+}
+
+func (s *PgSubmissionStore) Each(ctx context.Context, formID uuid.UUID, q SubmissionQuery, fn func(*model.SubmissionWithAnswers) error) error {
+	var args []any
+	where := submissionFilters(formID, q, &args)
+	if q.Since != nil {
+		args = append(args, q.Since.CreatedAt, q.Since.ID)
+		where += fmt.Sprintf(" AND (s.created_at, s.id) > ($%d, $%d)", len(args)-1, len(args))
+	}
+	if q.Settled {
+		args = append(args, SubmissionSettleTime.Seconds())
+		where += fmt.Sprintf(" AND s.created_at < now() - make_interval(secs => $%d)", len(args))
+	}
+
+	rows, err := s.DB.Pool.Query(ctx, `
+		SELECT s.id, s.form_id, s.revision_id, s.respondent_email, s.respondent_user_id, s.ip_address::text, s.user_agent, s.created_at,
+		       a.id, a.question_id, a.value, a.created_at, q.title, q.type, q.archived_at IS NOT NULL
+		FROM submissions s
+		LEFT JOIN answers a ON a.submission_id = s.id
+		LEFT JOIN questions q ON q.id = a.question_id
+		WHERE `+where+`
+		ORDER BY s.created_at ASC, s.id ASC, q.position ASC
+	`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// rows of one submission are adjacent, so only the current one is held
+	var cur *model.SubmissionWithAnswers
+	for rows.Next() {
+		var sub model.SubmissionWithAnswers
+		var answerID, questionID *uuid.UUID
+		var value []byte
+		var answeredAt *time.Time
+		var title, questionType *string
+		var archived *bool
+		if err := rows.Scan(&sub.ID, &sub.FormID, &sub.RevisionID, &sub.RespondentEmail, &sub.RespondentUserID, &sub.IPAddress, &sub.UserAgent, &sub.CreatedAt,
+			&answerID, &questionID, &value, &answeredAt, &title, &questionType, &archived); err != nil {
+			return err
+		}
+
+		if cur == nil || cur.ID != sub.ID {
+			if cur != nil {
+				if err := fn(cur); err != nil {
+					return err
+				}
+			}
+			sub.Answers = []model.Answer{}
+			cur = &sub
+		}
+		if answerID != nil {
+			cur.Answers = append(cur.Answers, model.Answer{
+				ID:               *answerID,
+				SubmissionID:     cur.ID,
+				QuestionID:       *questionID,
+				Value:            value,
+				CreatedAt:        *answeredAt,
+				QuestionTitle:    *title,
+				QuestionType:     *questionType,
+				QuestionArchived: *archived,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if cur != nil {
+		return fn(cur)
+	}
+	return nil
+}
+
+func (s *PgSubmissionStore) CountByOwner(ctx context.Context, ownerID uuid.UUID) (int, error) {
+	var total int
+	err := s.DB.Pool.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM submissions s
+		JOIN forms f ON f.id = s.form_id
+		WHERE f.owner_id = $1
+	`, ownerID).Scan(&total)
+	return total, err
+}
@@ -0,0 +1,122 @@
+package store
+
+import (
+	"context"
+	"craft/internal/db"
+	"craft/internal/model"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type PgUploadStore struct {
+	DB *db.Database
+}
+
+func NewPgUploadStore(database *db.Database) *PgUploadStore {
+	return &PgUploadStore{DB: database}
+}
+
+const uploadColumns = `id, form_id, question_id, submission_id, blob_key, filename, content_type, size, created_at`
+
+func scanUpload(row pgx.Row, u *model.Upload) error {
+	return row.Scan(&u.ID, &u.FormID, &u.QuestionID, &u.SubmissionID, &u.BlobKey, &u.Filename, &u.ContentType, &u.Size, &u.CreatedAt)
+}
+
+func collectUploads(rows pgx.Rows) ([]model.Upload, error) {
+	defer rows.Close()
+
+	uploads := []model.Upload{}
+	for rows.Next() {
+		var u model.Upload
+		if err := scanUpload(rows, &u); err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, u)
+	}
+	return uploads, rows.Err()
+}
+
+func (s *PgUploadStore) Create(ctx context.Context, u *model.Upload) error {
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	return s.DB.Pool.QueryRow(ctx, `
+		INSERT INTO uploads (id, form_id, question_id, blob_key, filename, content_type, size)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at
+	`, u.ID, u.FormID, u.QuestionID, u.BlobKey, u.Filename, u.ContentType, u.Size).Scan(&u.CreatedAt)
+}
+
+func (s *PgUploadStore) Get(ctx context.Context, uploadID uuid.UUID) (*model.Upload, error) {
+	var u model.Upload
+	err := scanUpload(s.DB.Pool.QueryRow(ctx, `SELECT `+uploadColumns+` FROM uploads WHERE id = $1`, uploadID), &u)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (s *PgUploadStore) List(ctx context.Context, uploadIDs []uuid.UUID) ([]model.Upload, error) {
+	rows, err := s.DB.Pool.Query(ctx, `SELECT `+uploadColumns+` FROM uploads WHERE id = ANY($1)`, uploadIDs)
+	if err != nil {
+		return nil, err
+	}
+	return collectUploads(rows)
+}
+
+func (s *PgUploadStore) Attach(ctx context.Context, submissionID uuid.UUID, uploadIDs []uuid.UUID) (int, error) {
+	res, err := s.DB.Pool.Exec(ctx, `
+		UPDATE uploads
+		SET submission_id = $1
+		WHERE id = ANY($2) AND submission_id IS NULL
+	`, submissionID, uploadIDs)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.RowsAffected()), nil
+}
+
+func (s *PgUploadStore) ListBySubmissions(ctx context.Context, submissionIDs []uuid.UUID) ([]model.Upload, error) {
+	rows, err := s.DB.Pool.Query(ctx, `
+		SELECT `+uploadColumns+`
+		FROM uploads
+		WHERE submission_id = ANY($1)
+		ORDER BY created_at
+	`, submissionIDs)
+	if err != nil {
+		return nil, err
+	}
+	return collectUploads(rows)
+}
+
+func (s *PgUploadStore) ListUnattached(ctx context.Context, before time.Time, limit int) ([]model.Upload, error) {
+	rows, err := s.DB.Pool.Query(ctx, `
+		SELECT `+uploadColumns+`
+		FROM uploads u
+		WHERE submission_id IS NULL AND created_at < $1
+		  AND NOT EXISTS (
+			SELECT 1
+			FROM partial_responses p, jsonb_array_elements(p.answers) a
+			WHERE p.form_id = u.form_id AND p.expires_at > NOW()
+			  AND a->>'question_id' = u.question_id::text
+			  AND (a->'value' = to_jsonb(u.id::text) OR a->'value' @> jsonb_build_array(u.id::text))
+		  )
+		ORDER BY created_at
+		LIMIT $2
+	`, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectUploads(rows)
+}
+
+func (s *PgUploadStore) Delete(ctx context.Context, uploadID uuid.UUID) error {
+	_, err := s.DB.Pool.Exec(ctx, `DELETE FROM uploads WHERE id = $1`, uploadID)
+	return err
+}
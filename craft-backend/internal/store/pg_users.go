@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"craft/internal/db"
+	"craft/internal/model"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type PgUserStore struct {
+	DB *db.Database
+}
+
+func NewPgUserStore(database *db.Database) *PgUserStore {
+	return &PgUserStore{DB: database}
+}
+
+func (s *PgUserStore) Get(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	var u model.User
+	err := s.DB.Pool.QueryRow(ctx, `
+		SELECT id, first_name, last_name, email, role, is_verified, created_at, updated_at
+		FROM public.users
+		WHERE id = $1
+	`, id).Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email, &u.Role, &u.IsVerified, &u.CreatedAt, &u.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (s *PgUserStore) List(ctx context.Context) ([]model.User, error) {
+	rows, err := s.DB.Pool.Query(ctx, `
+		SELECT id, first_name, last_name, email, role, is_verified, created_at, updated_at
+		FROM public.users
+		ORDER BY created_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []model.User
+	for rows.Next() {
+		var u model.User
+		err := rows.Scan(
+			&u.ID, &u.FirstName, &u.LastName, &u.Email, &u.Role, &u.IsVerified, &u.CreatedAt, &u.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (s *PgUserStore) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := s.DB.Pool.Exec(ctx, `DELETE FROM public.users WHERE id = $1`, id)
+	return err
+}
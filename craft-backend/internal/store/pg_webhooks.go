@@ -0,0 +1,210 @@
+package store
+
+import (
+	"context"
+	"craft/internal/db"
+	"craft/internal/model"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type PgWebhookStore struct {
+	DB *db.Database
+}
+
+func NewPgWebhookStore(database *db.Database) *PgWebhookStore {
+	return &PgWebhookStore{DB: database}
+}
+
+const webhookColumns = `w.id, w.form_id, w.url, w.events, w.active, w.created_at, w.updated_at`
+
+func scanWebhook(row pgx.Row, w *model.Webhook) error {
+	return row.Scan(&w.ID, &w.FormID, &w.URL, &w.Events, &w.Active, &w.CreatedAt, &w.UpdatedAt)
+}
+
+const deliveryColumns = `
+	d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.next_attempt_at,
+	d.last_status_code, d.last_error, d.delivered_at, d.created_at`
+
+func deliveryDest(d *model.WebhookDelivery) []any {
+	return []any{
+		&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastStatusCode, &d.LastError, &d.DeliveredAt, &d.CreatedAt,
+	}
+}
+
+func (s *PgWebhookStore) List(ctx context.Context, formID uuid.UUID) ([]model.Webhook, error) {
+	rows, err := s.DB.Pool.Query(ctx, `
+		SELECT `+webhookColumns+`
+		FROM webhooks w
+		WHERE w.form_id = $1
+		ORDER BY w.created_at
+	`, formID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := []model.Webhook{}
+	for rows.Next() {
+		var w model.Webhook
+		if err := scanWebhook(rows, &w); err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, w)
+	}
+	return hooks, rows.Err()
+}
+
+func (s *PgWebhookStore) Get(ctx context.Context, formID, webhookID uuid.UUID) (*model.Webhook, error) {
+	var w model.Webhook
+	err := scanWebhook(s.DB.Pool.QueryRow(ctx, `
+		SELECT `+webhookColumns+`
+		FROM webhooks w
+		WHERE w.id = $1 AND w.form_id = $2
+	`, webhookID, formID), &w)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (s *PgWebhookStore) Create(ctx context.Context, w *model.Webhook) error {
+	err := s.DB.Pool.QueryRow(ctx, `
+		INSERT INTO webhooks (form_id, url, secret, events, active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`, w.FormID, w.URL, w.Secret, w.Events, w.Active).Scan(&w.ID, &w.CreatedAt, &w.UpdatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *PgWebhookStore) Update(ctx context.Context, w *model.Webhook) error {
+	err := s.DB.Pool.QueryRow(ctx, `
+		UPDATE webhooks
+		SET url = $3, events = $4, active = $5, secret = COALESCE(NULLIF($6, ''), secret), updated_at = now()
+		WHERE id = $1 AND form_id = $2
+		RETURNING created_at, updated_at
+	`, w.ID, w.FormID, w.URL, w.Events, w.Active, w.Secret).Scan(&w.CreatedAt, &w.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *PgWebhookStore) Delete(ctx context.Context, formID, webhookID uuid.UUID) error {
+	res, err := s.DB.Pool.Exec(ctx, `DELETE FROM webhooks WHERE id = $1 AND form_id = $2`, webhookID, formID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PgWebhookStore) Enqueue(ctx context.Context, formID uuid.UUID, event string, payload json.RawMessage) ([]uuid.UUID, error) {
+	rows, err := s.DB.Pool.Query(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload)
+		SELECT id, $2::text, $3::jsonb
+		FROM webhooks
+		WHERE form_id = $1 AND active AND $2 = ANY(events)
+		RETURNING id
+	`, formID, event, payload)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+}
+
+func (s *PgWebhookStore) ListDeliveries(ctx context.Context, formID, webhookID uuid.UUID, limit int) ([]model.WebhookDelivery, error) {
+	rows, err := s.DB.Pool.Query(ctx, `
+		SELECT `+deliveryColumns+`
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.webhook_id = $1 AND w.form_id = $2
+		ORDER BY d.created_at DESC, d.id DESC
+		LIMIT $3
+	`, webhookID, formID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []model.WebhookDelivery{}
+	for rows.Next() {
+		var d model.WebhookDelivery
+		if err := rows.Scan(deliveryDest(&d)...); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (s *PgWebhookStore) Redeliver(ctx context.Context, formID, webhookID, deliveryID uuid.UUID) (*model.WebhookDelivery, error) {
+	var d model.WebhookDelivery
+	err := s.DB.Pool.QueryRow(ctx, `
+		WITH copy AS (
+			INSERT INTO webhook_deliveries (webhook_id, event, payload)
+			SELECT d.webhook_id, d.event, d.payload
+			FROM webhook_deliveries d
+			JOIN webhooks w ON w.id = d.webhook_id
+			WHERE d.id = $1 AND d.webhook_id = $2 AND w.form_id = $3
+			RETURNING *
+		)
+		SELECT `+deliveryColumns+`
+		FROM copy d
+	`, deliveryID, webhookID, formID).Scan(deliveryDest(&d)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (s *PgWebhookStore) Delivery(ctx context.Context, deliveryID uuid.UUID) (*OutgoingDelivery, error) {
+	var out OutgoingDelivery
+	w := &out.Webhook
+	dest := append(deliveryDest(&out.Delivery),
+		&w.ID, &w.FormID, &w.URL, &w.Events, &w.Active, &w.CreatedAt, &w.UpdatedAt, &w.Secret)
+	err := s.DB.Pool.QueryRow(ctx, `
+		SELECT `+deliveryColumns+`, `+webhookColumns+`, w.secret
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.id = $1
+	`, deliveryID).Scan(dest...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (s *PgWebhookStore) RecordAttempt(ctx context.Context, deliveryID uuid.UUID, a DeliveryAttempt) error {
+	_, err := s.DB.Pool.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET attempts = attempts + 1,
+			status = $2,
+			next_attempt_at = $3,
+			last_status_code = NULLIF($4, 0),
+			last_error = NULLIF($5, ''),
+			delivered_at = CASE WHEN $2 = 'succeeded' THEN now() END
+		WHERE id = $1
+	`, deliveryID, a.status(), a.RetryAt, a.StatusCode, a.Error)
+	return err
+}
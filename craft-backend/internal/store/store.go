@@ -0,0 +1,334 @@
+package store
+
+import (
+	"context"
+	"craft/internal/db"
+	"craft/internal/model"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned when the requested row does not exist or is not
+// visible to the caller (e.g. a form owned by someone else).
+var ErrNotFound = errors.New("store: not found")
+
+// ErrVersionConflict is returned by versioned form writes when the form's
+// version no longer matches the one the caller read.
+var ErrVersionConflict = errors.New("store: form version conflict")
+
+// ErrAlreadySubmitted is returned by SubmissionStore.CreateUnique when the
+// respondent already has a submission for the form.
+var ErrAlreadySubmitted = errors.New("store: respondent already submitted")
+
+// ErrPartialGone is returned by SubmissionStore.Create and CreateUnique when
+// the partial response being submitted was submitted, deleted or swept away
+// meanwhile.
+var ErrPartialGone = errors.New("store: partial response gone")
+
+type FormStore interface {
+	Create(ctx context.Context, ownerID uuid.UUID, title string, description *string) (*model.Form, error)
+	// Get returns any form with its questions and options, regardless of owner.
+	Get(ctx context.Context, formID uuid.UUID) (*model.Form, error)
+	// GetByOwner returns the form with its questions and options.
+	GetByOwner(ctx context.Context, formID, ownerID uuid.UUID) (*model.Form, error)
+	// GetPublished resolves a public form by its owner's first name and title
+	// slug and returns the respondents' view of it, with its questions and
+	// options.
+	GetPublished(ctx context.Context, username, slug string) (*model.PublicForm, error)
+	// ArchivedQuestions returns the questions removed from a form, which
+	// Get leaves out, ordered by position and with all their options.
+	ArchivedQuestions(ctx context.Context, formID uuid.UUID) ([]model.Question, error)
+	// ListByOwner returns the owner's forms, newest edit first, with Responses filled in.
+	ListByOwner(ctx context.Context, ownerID uuid.UUID) ([]model.Form, error)
+	ListAll(ctx context.Context) ([]model.Form, error)
+	// Update writes the title, description, pages and question set of f and
+	// records the result as a new revision. On success f is replaced by the
+	// form as saved, with IDs assigned to new questions, options and pages.
+	//
+	// Update, UpdateSettings, SetStatus and RevisionStore.Restore are
+	// versioned: they fail with ErrVersionConflict unless the expected version
+	// (f.Version, or the version argument) is 0 or equals the stored one, and
+	// bump the stored version on success. f.Version is set to the new value.
+	Update(ctx context.Context, ownerID uuid.UUID, f *model.Form) error
+	// UpdateSettings writes the submission settings of f: IsPublic,
+	// AllowMultipleSubmissions, CloseDate, ThankYouMessage, RedirectURL and
+	// NotifyOwner.
+	UpdateSettings(ctx context.Context, ownerID uuid.UUID, f *model.Form) error
+	Duplicate(ctx context.Context, formID, ownerID uuid.UUID) (*model.Form, error)
+	// SetStatus changes the form's status and returns the new version.
+	// Publishing also records a revision.
+	SetStatus(ctx context.Context, formID, ownerID uuid.UUID, status string, isPublic bool, version int) (int, error)
+	Delete(ctx context.Context, formID, ownerID uuid.UUID) error
+	// DeleteAny deletes a form regardless of owner. Admin only.
+	DeleteAny(ctx context.Context, formID uuid.UUID) error
+	CountPublished(ctx context.Context) (int, error)
+}
+
+// RespondentMatch identifies a respondent for single-response forms. Nil
+// fields are ignored.
+type RespondentMatch struct {
+	UserID      *uuid.UUID
+	Email       *string
+	DeviceID    *string
+	Fingerprint *string
+}
+
+type SubmissionStore interface {
+	// Create stores the submission and its answers atomically. Zero IDs are
+	// assigned before insert. When partialID is not nil the submission
+	// finishes that partial response, which is deleted along with the insert
+	// so it can only be submitted once; ErrPartialGone is returned if it is
+	// already gone.
+	Create(ctx context.Context, s *model.Submission, answers []model.Answer, partialID *uuid.UUID) error
+	// CreateUnique is Create for forms that allow a single response. It fails
+	// with ErrAlreadySubmitted if an earlier submission of the form matches
+	// any non-nil field of match.
+	CreateUnique(ctx context.Context, s *model.Submission, answers []model.Answer, partialID *uuid.UUID, match RespondentMatch) error
+	// ListByForm returns one page of a form's submissions with their
+	// answers, newest first.
+	ListByForm(ctx context.Context, formID uuid.UUID, q SubmissionQuery) (*SubmissionPage, error)
+	// Each calls fn for every submission of a form matching the filters of q,
+	// oldest first and with answer metadata filled in, without loading them
+	// all into memory. After and Limit are ignored. Iteration stops at the
+	// first error fn returns.
+	Each(ctx context.Context, formID uuid.UUID, q SubmissionQuery, fn func(*model.SubmissionWithAnswers) error) error
+	CountByOwner(ctx context.Context, ownerID uuid.UUID) (int, error)
+}
+
+type RevisionStore interface {
+	// List returns the form's revisions, newest first, without their questions.
+	List(ctx context.Context, formID, ownerID uuid.UUID) ([]model.FormRevision, error)
+	Get(ctx context.Context, formID, ownerID uuid.UUID, revision int) (*model.FormRevision, error)
+	// Restore makes an earlier revision the form's current definition and
+	// records that as a new revision, which it returns.
+	Restore(ctx context.Context, formID, ownerID uuid.UUID, revision, version int) (*model.FormRevision, error)
+}
+
+type AnalyticsStore interface {
+	// FormAnalytics aggregates the answers to each live question of f, which
+	// must carry its questions and options as returned by FormStore.Get.
+	FormAnalytics(ctx context.Context, f *model.Form) (*model.FormAnalytics, error)
+	// ResponseSeries counts submissions per bucket for one form, or for all
+	// of the owner's forms when formID is uuid.Nil.
+	ResponseSeries(ctx context.Context, ownerID, formID uuid.UUID, q SeriesQuery) (*model.ResponseSeries, error)
+	// RecordEvent stores a respondent event. Repeats of a step by the same
+	// session are ignored. A missing form yields ErrNotFound.
+	RecordEvent(ctx context.Context, e *model.FormEvent) error
+	// Funnel combines the events of f with its submissions. Like
+	// FormAnalytics, f must carry its questions.
+	Funnel(ctx context.Context, f *model.Form) (*model.FormFunnel, error)
+}
+
+// OutgoingDelivery is a webhook delivery with the webhook it goes to,
+// including its secret.
+type OutgoingDelivery struct {
+	Delivery model.WebhookDelivery
+	Webhook  model.Webhook
+}
+
+// DeliveryAttempt is the outcome of sending a delivery once.
+type DeliveryAttempt struct {
+	StatusCode int    // 0 when no response was received
+	Error      string // empty on success
+	Succeeded  bool
+	// RetryAt schedules the next attempt of a failed delivery; nil gives up.
+	RetryAt *time.Time
+}
+
+func (a DeliveryAttempt) status() string {
+	switch {
+	case a.Succeeded:
+		return model.DeliveryStatusSucceeded
+	case a.RetryAt != nil:
+		return model.DeliveryStatusPending
+	}
+	return model.DeliveryStatusFailed
+}
+
+type WebhookStore interface {
+	// List returns the form's webhooks without their secrets.
+	List(ctx context.Context, formID uuid.UUID) ([]model.Webhook, error)
+	Get(ctx context.Context, formID, webhookID uuid.UUID) (*model.Webhook, error)
+	// Create assigns the ID and timestamps of w.
+	Create(ctx context.Context, w *model.Webhook) error
+	// Update writes the URL, events and active flag of w, and its secret
+	// when not empty.
+	Update(ctx context.Context, w *model.Webhook) error
+	Delete(ctx context.Context, formID, webhookID uuid.UUID) error
+
+	// Enqueue records a pending delivery of payload to every active webhook
+	// of the form subscribed to event and returns their IDs.
+	Enqueue(ctx context.Context, formID uuid.UUID, event string, payload json.RawMessage) ([]uuid.UUID, error)
+	// ListDeliveries returns the webhook's most recent deliveries, newest first.
+	ListDeliveries(ctx context.Context, formID, webhookID uuid.UUID, limit int) ([]model.WebhookDelivery, error)
+	// Redeliver records a pending copy of an earlier delivery and returns it.
+	Redeliver(ctx context.Context, formID, webhookID, deliveryID uuid.UUID) (*model.WebhookDelivery, error)
+	// Delivery loads a delivery of any form with its webhook.
+	Delivery(ctx context.Context, deliveryID uuid.UUID) (*OutgoingDelivery, error)
+	RecordAttempt(ctx context.Context, deliveryID uuid.UUID, a DeliveryAttempt) error
+}
+
+type JobStore interface {
+	// Enqueue assigns the ID, status and timestamps of j. A zero RunAt runs
+	// the job as soon as possible. If j.UniqueKey is set and a pending job
+	// has the same key, Enqueue does nothing and leaves j.ID zero.
+	Enqueue(ctx context.Context, j *model.Job) error
+	// Claim marks up to limit due jobs of the given kinds as running for
+	// lease, counts an attempt on each and releases their unique keys. Due
+	// jobs are pending ones whose RunAt has passed and running ones whose
+	// lease ran out.
+	Claim(ctx context.Context, kinds []string, limit int, lease time.Duration) ([]model.Job, error)
+	// Complete deletes a job that finished the given attempt.
+	Complete(ctx context.Context, jobID uuid.UUID, attempt int) error
+	// Fail records a failed attempt and schedules the job again at retryAt,
+	// or makes it a dead letter when retryAt is nil.
+	//
+	// Complete and Fail do nothing unless the job is still running that
+	// attempt: once its lease runs out, another worker may have claimed it,
+	// and the outcome of the stale run must not overwrite the new one's.
+	Fail(ctx context.Context, jobID uuid.UUID, attempt int, message string, retryAt *time.Time) error
+	// ListDead returns dead letters, most recently failed first.
+	ListDead(ctx context.Context, limit int) ([]model.Job, error)
+	// Retry queues a dead letter again with its attempts reset.
+	Retry(ctx context.Context, jobID uuid.UUID) (*model.Job, error)
+}
+
+// NotificationStore remembers how far each stream of notification emails
+// has got through the submissions it reports on, and how many emails went
+// out recently.
+type NotificationStore interface {
+	// Watermark returns the last submission reported under key, or nil if
+	// nothing was reported yet.
+	Watermark(ctx context.Context, key string) (*SubmissionCursor, error)
+	SetWatermark(ctx context.Context, key string, c SubmissionCursor) error
+	// CountSend counts an email under key and returns how many were counted
+	// under it in the current window, this one included. A window begins
+	// with the first email after the last one ended and lasts window.
+	CountSend(ctx context.Context, key string, window time.Duration) (int, error)
+}
+
+type AutoresponderStore interface {
+	// Get returns the form's autoresponder, or ErrNotFound if it has none.
+	Get(ctx context.Context, formID uuid.UUID) (*model.Autoresponder, error)
+	// Put creates or replaces the autoresponder of a.FormID and sets its
+	// timestamps.
+	Put(ctx context.Context, a *model.Autoresponder) error
+	Delete(ctx context.Context, formID uuid.UUID) error
+}
+
+type DigestStore interface {
+	// Preference returns the user's digest preference, or ErrNotFound if
+	// they never set one.
+	Preference(ctx context.Context, userID uuid.UUID) (*model.DigestPreference, error)
+	// SetPreference writes the frequency and timezone of p and fills in the
+	// rest. Turning digests on restarts SentThrough from now.
+	SetPreference(ctx context.Context, p *model.DigestPreference) error
+	// ListEnabled returns the preferences of every user with digests on.
+	ListEnabled(ctx context.Context) ([]model.DigestPreference, error)
+	// MarkSent moves the user's SentThrough forward to through, never back.
+	MarkSent(ctx context.Context, userID uuid.UUID, through time.Time) error
+}
+
+type UploadStore interface {
+	// Create records an upload that belongs to no submission yet. It assigns
+	// the ID, unless set, and CreatedAt.
+	Create(ctx context.Context, u *model.Upload) error
+	Get(ctx context.Context, uploadID uuid.UUID) (*model.Upload, error)
+	// List returns the uploads with the given IDs that exist.
+	List(ctx context.Context, uploadIDs []uuid.UUID) ([]model.Upload, error)
+	// Attach links the given uploads to a submission, skipping those that
+	// already belong to one, and returns how many it linked.
+	Attach(ctx context.Context, submissionID uuid.UUID, uploadIDs []uuid.UUID) (int, error)
+	// ListBySubmissions returns the uploads of the given submissions.
+	ListBySubmissions(ctx context.Context, submissionIDs []uuid.UUID) ([]model.Upload, error)
+	// ListUnattached returns up to limit uploads created before the given
+	// time that belong to no submission, oldest first. Uploads of deleted
+	// submissions are among them; uploads named by the answers of an
+	// unexpired partial response are not.
+	ListUnattached(ctx context.Context, before time.Time, limit int) ([]model.Upload, error)
+	Delete(ctx context.Context, uploadID uuid.UUID) error
+}
+
+type PartialStore interface {
+	// Create stores a new partial response. It assigns the ID, CreatedAt
+	// and UpdatedAt.
+	Create(ctx context.Context, p *model.PartialResponse) error
+	// GetByToken returns the unexpired partial response of a form with the
+	// given token hash.
+	GetByToken(ctx context.Context, formID uuid.UUID, tokenHash string) (*model.PartialResponse, error)
+	// Update saves the answers, email, page and expiry of p.
+	Update(ctx context.Context, p *model.PartialResponse) error
+	Delete(ctx context.Context, partialID uuid.UUID) error
+	// DeleteExpired removes up to limit partial responses that expired
+	// before the given time and returns how many it removed.
+	DeleteExpired(ctx context.Context, before time.Time, limit int) (int, error)
+}
+
+type UserStore interface {
+	Get(ctx context.Context, id uuid.UUID) (*model.User, error)
+	List(ctx context.Context) ([]model.User, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// Stores bundles one implementation of every store for wiring into handlers.
+type Stores struct {
+	Forms          FormStore
+	Submissions    SubmissionStore
+	Revisions      RevisionStore
+	Analytics      AnalyticsStore
+	Webhooks       WebhookStore
+	Jobs           JobStore
+	Notifications  NotificationStore
+	Autoresponders AutoresponderStore
+	Digests        DigestStore
+	Uploads        UploadStore
+	Partials       PartialStore
+	Users          UserStore
+}
+
+func NewPgStores(database *db.Database) Stores {
+	return Stores{
+		Forms:          NewPgFormStore(database),
+		Submissions:    NewPgSubmissionStore(database),
+		Revisions:      NewPgRevisionStore(database),
+		Analytics:      NewPgAnalyticsStore(database),
+		Webhooks:       NewPgWebhookStore(database),
+		Jobs:           NewPgJobStore(database),
+		Notifications:  NewPgNotificationStore(database),
+		Autoresponders: NewPgAutoresponderStore(database),
+		Digests:        NewPgDigestStore(database),
+		Uploads:        NewPgUploadStore(database),
+		Partials:       NewPgPartialStore(database),
+		Users:          NewPgUserStore(database),
+	}
+}
+
+var (
+	_ FormStore          = (*PgFormStore)(nil)
+	_ SubmissionStore    = (*PgSubmissionStore)(nil)
+	_ RevisionStore      = (*PgRevisionStore)(nil)
+	_ AnalyticsStore     = (*PgAnalyticsStore)(nil)
+	_ WebhookStore       = (*PgWebhookStore)(nil)
+	_ JobStore           = (*PgJobStore)(nil)
+	_ NotificationStore  = (*PgNotificationStore)(nil)
+	_ AutoresponderStore = (*PgAutoresponderStore)(nil)
+	_ DigestStore        = (*PgDigestStore)(nil)
+	_ UploadStore        = (*PgUploadStore)(nil)
+	_ PartialStore       = (*PgPartialStore)(nil)
+	_ UserStore          = (*PgUserStore)(nil)
+	_ FormStore          = memoryForms{}
+	_ SubmissionStore    = memorySubmissions{}
+	_ RevisionStore      = memoryRevisions{}
+	_ AnalyticsStore     = memoryAnalytics{}
+	_ WebhookStore       = memoryWebhooks{}
+	_ JobStore           = memoryJobs{}
+	_ NotificationStore  = memoryNotifications{}
+	_ AutoresponderStore = memoryAutoresponders{}
+	_ DigestStore        = memoryDigests{}
+	_ UploadStore        = memoryUploads{}
+	_ PartialStore       = memoryPartials{}
+	_ UserStore          = memoryUsers{}
+)
@@ -0,0 +1,121 @@
+package store
+
+import (
+	"bytes"
+	"craft/internal/model"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded.
+var ErrInvalidCursor = errors.New("store: invalid cursor")
+
+const (
+	DefaultSubmissionPageSize = 50
+	MaxSubmissionPageSize     = 200
+)
+
+// SubmissionSettleTime is how long a submission is held back from Settled
+// queries. Submissions are stamped when inserted but seen when committed, so
+// one can appear behind a newer one already read; the wait must outlast any
+// insert transaction for a cursor on (created_at, id) to never skip it.
+const SubmissionSettleTime = 5 * time.Second
+
+// SubmissionCursor marks a position in a form's submissions, which are
+// ordered by created_at and then id, both descending.
+type SubmissionCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// String encodes the cursor as an opaque URL-safe token.
+func (c SubmissionCursor) String() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func cursorOf(sub model.SubmissionWithAnswers) SubmissionCursor {
+	return SubmissionCursor{CreatedAt: sub.CreatedAt, ID: sub.ID}
+}
+
+// before reports whether c sorts before o in the oldest-first order, i.e.
+// (c.CreatedAt, c.ID) < (o.CreatedAt, o.ID) as Postgres compares them.
+func (c SubmissionCursor) before(o SubmissionCursor) bool {
+	if !c.CreatedAt.Equal(o.CreatedAt) {
+		return c.CreatedAt.Before(o.CreatedAt)
+	}
+	return bytes.Compare(c.ID[:], o.ID[:]) < 0
+}
+
+func ParseSubmissionCursor(s string) (SubmissionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return SubmissionCursor{}, ErrInvalidCursor
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return SubmissionCursor{}, ErrInvalidCursor
+	}
+
+	var c SubmissionCursor
+	if c.CreatedAt, err = time.Parse(time.RFC3339Nano, ts); err != nil {
+		return SubmissionCursor{}, ErrInvalidCursor
+	}
+	if c.ID, err = uuid.Parse(id); err != nil {
+		return SubmissionCursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// AnswerFilter matches submissions whose answer to QuestionID contains Value
+// in the JSONB sense: a string matches an equal string answer or a
+// multi-select answer that includes it.
+type AnswerFilter struct {
+	QuestionID uuid.UUID
+	Value      json.RawMessage
+}
+
+// SubmissionQuery selects one page of a form's submissions. Zero fields do not
+// filter.
+type SubmissionQuery struct {
+	// After continues a previous page; it is not applied to Total.
+	After *SubmissionCursor
+	// Since makes SubmissionStore.Each resume after the given submission,
+	// in its oldest-first order.
+	Since *SubmissionCursor
+	// Settled leaves out submissions made in the last SubmissionSettleTime,
+	// so that Since can be relied on to see every submission exactly once.
+	Settled bool
+	// Limit defaults to DefaultSubmissionPageSize and is capped at
+	// MaxSubmissionPageSize.
+	Limit int
+
+	CreatedFrom *time.Time // inclusive
+	CreatedTo   *time.Time // exclusive
+	Answers     []AnswerFilter
+	// Search is matched as full text against text answers.
+	Search string
+}
+
+func (q SubmissionQuery) limit() int {
+	switch {
+	case q.Limit <= 0:
+		return DefaultSubmissionPageSize
+	case q.Limit > MaxSubmissionPageSize:
+		return MaxSubmissionPageSize
+	}
+	return q.Limit
+}
+
+type SubmissionPage struct {
+	Submissions []model.SubmissionWithAnswers
+	// Total counts every submission matching the filters, across all pages.
+	Total int
+	// Next is nil on the last page.
+	Next *SubmissionCursor
+}
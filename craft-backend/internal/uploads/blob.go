@@ -0,0 +1,39 @@
+// Package uploads stores the files respondents attach to file_upload
+// questions.
+package uploads
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ErrBlobNotFound is returned when no blob is stored under a key.
+var ErrBlobNotFound = errors.New("uploads: blob not found")
+
+type BlobInfo struct {
+	Size int64
+}
+
+// BlobStore keeps file contents by key. Keys are slash-separated paths made
+// up by the caller, never by respondents.
+type BlobStore interface {
+	// Put stores exactly size bytes read from r under key.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Stat(ctx context.Context, key string) (BlobInfo, error)
+	// Delete removes the blob under key; a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Presigner is implemented by blob stores that clients can upload to and
+// download from directly, with URLs that carry their own authorization.
+type Presigner interface {
+	// PresignPut returns a URL a client can PUT size bytes of contentType
+	// to, and the headers it must send with them.
+	PresignPut(ctx context.Context, key, contentType string, size int64, expires time.Duration) (string, http.Header, error)
+	// PresignGet returns a URL that downloads the blob as filename.
+	PresignGet(ctx context.Context, key, filename string, expires time.Duration) (string, error)
+}
@@ -0,0 +1,66 @@
+package uploads
+
+import (
+	"context"
+	"craft/internal/jobs"
+	"craft/internal/store"
+	"time"
+)
+
+const (
+	// UnattachedTTL is how long an upload waits for a submission to name
+	// it before it is deleted.
+	UnattachedTTL = 24 * time.Hour
+
+	cleanupBatchSize = 100
+	cleanupKey       = "uploads-cleanup"
+)
+
+// CleanupKind deletes uploads that belong to no submission, with their
+// blobs, and schedules the next cleanup an hour later.
+const CleanupKind jobs.Kind[CleanupPayload] = "uploads.cleanup"
+
+type CleanupPayload struct{}
+
+// ScheduleCleanup queues a cleanup now, unless one is already waiting. Call
+// it at startup; every cleanup schedules the next one.
+func ScheduleCleanup(ctx context.Context, q store.JobStore) error {
+	_, err := jobs.EnqueueOnce(ctx, q, CleanupKind, cleanupKey, CleanupPayload{}, time.Now())
+	return err
+}
+
+type Cleaner struct {
+	Uploads store.UploadStore
+	Blobs   BlobStore
+	Jobs    store.JobStore
+}
+
+func (c *Cleaner) Register(r *jobs.Registry) {
+	jobs.Handle(r, CleanupKind, c.cleanup, jobs.Options{})
+}
+
+func (c *Cleaner) cleanup(ctx context.Context, job jobs.Job[CleanupPayload]) error {
+	before := time.Now().Add(-UnattachedTTL)
+	for {
+		batch, err := c.Uploads.ListUnattached(ctx, before, cleanupBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, u := range batch {
+			// the row goes last, so a failed blob delete is retried
+			if err := c.Blobs.Delete(ctx, u.BlobKey); err != nil {
+				return err
+			}
+			if err := c.Uploads.Delete(ctx, u.ID); err != nil {
+				return err
+			}
+		}
+		if len(batch) < cleanupBatchSize {
+			break
+		}
+	}
+
+	next := time.Now().Truncate(time.Hour).Add(time.Hour)
+	_, err := jobs.EnqueueOnce(ctx, c.Jobs, CleanupKind, cleanupKey, CleanupPayload{}, next)
+	return err
+}
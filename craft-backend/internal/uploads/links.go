@@ -0,0 +1,50 @@
+package uploads
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DownloadPath is where the API serves the files behind download links.
+const DownloadPath = "/api/v1/public/uploads/"
+
+// LinkTTL is how long download links work.
+const LinkTTL = time.Hour
+
+// Links signs download links, so they work without the caller's
+// credentials until they expire.
+type Links struct {
+	key []byte
+}
+
+// NewLinks derives the signing key from the server secret.
+func NewLinks(secret string) *Links {
+	return &Links{key: hmacSHA256([]byte(secret), "craft upload links")}
+}
+
+func (l *Links) sign(uploadID uuid.UUID, expires string) string {
+	return hex.EncodeToString(hmacSHA256(l.key, uploadID.String()+"."+expires))
+}
+
+// URL returns a path under DownloadPath that serves the upload until
+// expires.
+func (l *Links) URL(uploadID uuid.UUID, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	q := url.Values{"expires": {exp}, "signature": {l.sign(uploadID, exp)}}
+	return DownloadPath + uploadID.String() + "?" + q.Encode()
+}
+
+// Verify reports whether expires and signature, as given in a link, were
+// issued for uploadID and are still valid at now.
+func (l *Links) Verify(uploadID uuid.UUID, expires, signature string, now time.Time) bool {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.Unix() > exp {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(l.sign(uploadID, expires)))
+}
@@ -0,0 +1,133 @@
+package uploads
+
+import (
+	"context"
+	"craft/internal/model"
+	"craft/internal/model/payload"
+	"craft/internal/store"
+	"craft/internal/validation"
+	"errors"
+	"mime"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// PresignTTL is how long a client has to start a direct upload.
+const PresignTTL = 15 * time.Minute
+
+// BlobKey is where an upload's contents are stored. It is made of IDs only,
+// so respondent-chosen file names never reach the storage layer.
+func BlobKey(formID, uploadID uuid.UUID) string {
+	return "forms/" + formID.String() + "/" + uploadID.String()
+}
+
+// ContentType reduces a Content-Type header to its bare, lowercase MIME
+// type, or "application/octet-stream" if it has none.
+func ContentType(header string) string {
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil || !strings.Contains(mediaType, "/") {
+		return "application/octet-stream"
+	}
+	return mediaType
+}
+
+// CheckFile returns why a file does not fit its question, or "" if it does.
+func CheckFile(q model.Question, contentType string, size int64) string {
+	limits := q.FileLimits()
+	switch {
+	case q.Type != model.QuestionTypeFileUpload:
+		return "question does not accept files"
+	case size <= 0:
+		return "file is empty"
+	case size > limits.MaxSize || size > validation.MaxFileSize:
+		return "file is too large"
+	case !limits.Allows(contentType):
+		return "files of type " + contentType + " are not accepted"
+	}
+	return ""
+}
+
+// CheckAnswers verifies the uploads named by the file_upload answers of a
+// submission to f: each must have been uploaded to its question, in full,
+// and not be part of another submission. It returns the uploads to attach
+// once the submission is saved and the rejected answers by question ID.
+func CheckAnswers(ctx context.Context, uploads store.UploadStore, blobs BlobStore, f *model.Form, answers []payload.AnswerInput) ([]uuid.UUID, validation.FieldErrors, error) {
+	questions := make(map[uuid.UUID]model.Question, len(f.Questions))
+	for _, q := range f.Questions {
+		questions[q.ID] = q
+	}
+
+	var ids []uuid.UUID
+	byQuestion := make(map[uuid.UUID]uuid.UUID)
+	for _, a := range answers {
+		if q, ok := questions[a.QuestionID]; ok && q.Type == model.QuestionTypeFileUpload {
+			for _, id := range validation.FileIDs(a.Value) {
+				ids = append(ids, id)
+				byQuestion[id] = q.ID
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil, nil
+	}
+
+	found, err := uploads.List(ctx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	usable := make(map[uuid.UUID]bool, len(found))
+	for _, u := range found {
+		if u.FormID != f.ID || u.QuestionID != byQuestion[u.ID] || u.SubmissionID != nil {
+			continue
+		}
+		info, err := blobs.Stat(ctx, u.BlobKey)
+		if errors.Is(err, ErrBlobNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		usable[u.ID] = info.Size == u.Size
+	}
+
+	errs := validation.FieldErrors{}
+	for _, id := range ids {
+		if !usable[id] {
+			errs[byQuestion[id].String()] = "file " + id.String() + " was not uploaded or is already used"
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs, nil
+	}
+	return ids, nil, nil
+}
+
+// MaxFilenameLength caps stored file names, in bytes.
+const MaxFilenameLength = 255
+
+// CleanFilename reduces a client-supplied file name to its last path
+// element, without control characters, so it is safe to offer back in a
+// Content-Disposition header.
+func CleanFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." {
+		return "file"
+	}
+	for len(name) > MaxFilenameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
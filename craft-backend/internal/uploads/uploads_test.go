@@ -0,0 +1,239 @@
+package uploads
+
+import (
+	"craft/internal/model"
+	"craft/internal/model/payload"
+	"craft/internal/store"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCheckFile(t *testing.T) {
+	images := model.Question{Type: model.QuestionTypeFileUpload, FileUpload: &model.FileUploadSettings{
+		MaxSize:      1 << 20,
+		AllowedTypes: []string{"image/*", "application/pdf"},
+	}}
+	anything := model.Question{Type: model.QuestionTypeFileUpload}
+
+	tests := []struct {
+		name        string
+		q           model.Question
+		contentType string
+		size        int64
+		ok          bool
+	}{
+		{"allowed type", images, "application/pdf", 1 << 20, true},
+		{"allowed family", images, "image/png", 100, true},
+		{"other type", images, "text/html", 100, false},
+		{"over the question's limit", images, "image/png", 1<<20 + 1, false},
+		{"empty", images, "image/png", 0, false},
+		{"any type by default", anything, "text/html", 100, true},
+		{"over the default limit", anything, "text/plain", model.DefaultMaxFileSize + 1, false},
+		{"not a file question", model.Question{Type: model.QuestionTypeShortText}, "image/png", 100, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if msg := CheckFile(tt.q, tt.contentType, tt.size); (msg == "") != tt.ok {
+				t.Fatalf("CheckFile = %q, want ok %v", msg, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCheckAnswers(t *testing.T) {
+	ctx := t.Context()
+	uploadStore := store.NewMemory().Stores().Uploads
+	blobs, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cv, photo := uuid.New(), uuid.New()
+	f := &model.Form{ID: uuid.New(), Questions: []model.Question{
+		{ID: cv, Type: model.QuestionTypeFileUpload, Title: "CV"},
+		{ID: photo, Type: model.QuestionTypeFileUpload, Title: "Photo"},
+	}}
+
+	// upload stores an upload to a question of formID, with size bytes of
+	// its content in the blob store
+	upload := func(formID, questionID uuid.UUID, size, stored int) model.Upload {
+		t.Helper()
+		u := model.Upload{ID: uuid.New(), FormID: formID, QuestionID: questionID, Filename: "cv.pdf", Size: int64(size)}
+		u.BlobKey = BlobKey(formID, u.ID)
+		if err := uploadStore.Create(ctx, &u); err != nil {
+			t.Fatal(err)
+		}
+		if err := blobs.Put(ctx, u.BlobKey, strings.NewReader(strings.Repeat("x", stored)), int64(stored), "application/pdf"); err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+	answer := func(questionID uuid.UUID, ids ...uuid.UUID) []payload.AnswerInput {
+		raw, _ := json.Marshal(ids)
+		return []payload.AnswerInput{{QuestionID: questionID, Value: raw}}
+	}
+
+	good := upload(f.ID, cv, 10, 10)
+	used := upload(f.ID, cv, 10, 10)
+	if _, err := uploadStore.Attach(ctx, uuid.New(), []uuid.UUID{used.ID}); err != nil {
+		t.Fatal(err)
+	}
+	otherForm := upload(uuid.New(), cv, 10, 10)
+	otherQuestion := upload(f.ID, photo, 10, 10)
+	incomplete := upload(f.ID, cv, 10, 4)
+
+	ids, errs, err := CheckAnswers(ctx, uploadStore, blobs, f, answer(cv, good.ID))
+	if err != nil || len(errs) != 0 || len(ids) != 1 || ids[0] != good.ID {
+		t.Fatalf("good upload: ids %v, errs %v, err %v", ids, errs, err)
+	}
+
+	tests := []struct {
+		name string
+		id   uuid.UUID
+	}{
+		{"already in a submission", used.ID},
+		{"uploaded to another form", otherForm.ID},
+		{"uploaded to another question", otherQuestion.ID},
+		{"blob smaller than recorded", incomplete.ID},
+		{"never uploaded", uuid.New()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, errs, err := CheckAnswers(ctx, uploadStore, blobs, f, answer(cv, good.ID, tt.id))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ids) != 0 || errs[cv.String()] == "" {
+				t.Fatalf("ids %v, errs %v; want the answer rejected", ids, errs)
+			}
+		})
+	}
+}
+
+func TestCleanFilename(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"cv.pdf", "cv.pdf"},
+		{"../../etc/passwd", "passwd"},
+		{`C:\Users\ada\cv.pdf`, "cv.pdf"},
+		{"cv\r\n\x00.pdf", "cv.pdf"},
+		{"  résumé.pdf  ", "résumé.pdf"},
+		{"", "file"},
+		{"..", "file"},
+		{"dir/", "file"},
+		{strings.Repeat("é", 200), strings.Repeat("é", MaxFilenameLength/2)},
+	}
+	for _, tt := range tests {
+		if got := CleanFilename(tt.name); got != tt.want {
+			t.Errorf("CleanFilename(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	s, err := NewLocalStore(filepath.Join(dir, "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := BlobKey(uuid.New(), uuid.New())
+
+	if err := s.Put(ctx, key, strings.NewReader("hello"), 5, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := s.Stat(ctx, key); err != nil || info.Size != 5 {
+		t.Fatalf("stat %+v, err %v", info, err)
+	}
+	r, err := s.Open(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(r)
+	r.Close()
+	if err != nil || string(body) != "hello" {
+		t.Fatalf("read %q, err %v", body, err)
+	}
+
+	// a body of the wrong size stores nothing and leaves no temporary file
+	other := BlobKey(uuid.New(), uuid.New())
+	for _, content := range []string{"hell", "hello!"} {
+		if err := s.Put(ctx, other, strings.NewReader(content), 5, "text/plain"); err == nil {
+			t.Fatalf("put %q as 5 bytes succeeded", content)
+		}
+	}
+	if _, err := s.Stat(ctx, other); !errors.Is(err, ErrBlobNotFound) {
+		t.Fatalf("stat after a failed put: %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Dir(s.path(other)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("left behind %v", entries)
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Open(ctx, key); !errors.Is(err, ErrBlobNotFound) {
+		t.Fatalf("open after delete: %v", err)
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("deleting a missing blob: %v", err)
+	}
+}
+
+func TestLinksVerify(t *testing.T) {
+	links := NewLinks("server secret")
+	uploadID := uuid.New()
+	now := time.Now()
+	expires := now.Add(LinkTTL)
+
+	link, err := url.Parse(links.URL(uploadID, expires))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := DownloadPath + uploadID.String(); link.Path != want {
+		t.Fatalf("path %q, want %q", link.Path, want)
+	}
+	exp, sig := link.Query().Get("expires"), link.Query().Get("signature")
+
+	tampered := []byte(sig)
+	tampered[0] ^= 1
+	later := strings.TrimSuffix(exp, "0") + "9"
+
+	tests := []struct {
+		name         string
+		uploadID     uuid.UUID
+		expires, sig string
+		now          time.Time
+		links        *Links
+		want         bool
+	}{
+		{"as issued", uploadID, exp, sig, now, links, true},
+		{"at expiry", uploadID, exp, sig, expires, links, true},
+		{"expired", uploadID, exp, sig, expires.Add(time.Second), links, false},
+		{"tampered signature", uploadID, exp, string(tampered), now, links, false},
+		{"extended expiry", uploadID, later, sig, now, links, false},
+		{"other upload", uuid.New(), exp, sig, now, links, false},
+		{"other secret", uploadID, exp, sig, now, NewLinks("another secret"), false},
+		{"malformed expiry", uploadID, "soon", sig, now, links, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.links.Verify(tt.uploadID, tt.expires, tt.sig, tt.now); got != tt.want {
+				t.Fatalf("Verify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package validation
+
+import (
+	"bytes"
+	"craft/internal/model"
+	"craft/internal/model/payload"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MaxShortTextLength = 500
+	MaxLongTextLength  = 10000
+	MaxEmailLength     = 254
+)
+
+// FieldErrors maps a question ID to the reason its answer was rejected.
+type FieldErrors map[string]string
+
+// AnswerValidator checks a decoded, non-empty answer value against its question.
+type AnswerValidator func(q model.Question, value any) error
+
+var answerValidators = map[string]AnswerValidator{
+	model.QuestionTypeShortText:    textValidator(MaxShortTextLength),
+	model.QuestionTypeLongText:     textValidator(MaxLongTextLength),
+	model.QuestionTypeSingleSelect: singleChoiceValidator,
+	model.QuestionTypeDropdown:     singleChoiceValidator,
+	model.QuestionTypeMultiSelect:  multiChoiceValidator,
+	model.QuestionTypeEmail:        emailValidator,
+	model.QuestionTypeNumber:       numberValidator,
+	model.QuestionTypeRating:       ratingValidator,
+	model.QuestionTypeFileUpload:   fileValidator,
+}
+
+// RegisterAnswerValidator installs the validator used for questionType,
+// replacing any existing one.
+func RegisterAnswerValidator(questionType string, v AnswerValidator) {
+	answerValidators[questionType] = v
+}
+
+// ValidateAnswers checks a submission against the form's questions: every
+// answer must reference a question of the form at most once, required
+// questions must be answered, and each value must fit its question type.
+func ValidateAnswers(questions []model.Question, answers []payload.AnswerInput) FieldErrors {
+	return validateAnswers(questions, answers, true)
+}
+
+// ValidatePartialAnswers checks answers saved to finish later, like
+// ValidateAnswers but with required questions allowed to be unanswered.
+func ValidatePartialAnswers(questions []model.Question, answers []payload.AnswerInput) FieldErrors {
+	return validateAnswers(questions, answers, false)
+}
+
+func validateAnswers(questions []model.Question, answers []payload.AnswerInput, complete bool) FieldErrors {
+	errs := FieldErrors{}
+
+	byID := make(map[string]model.Question, len(questions))
+	for _, q := range questions {
+		byID[q.ID.String()] = q
+	}
+
+	seen := make(map[string]bool, len(answers))
+	answered := make(map[string]bool, len(answers))
+	for _, a := range answers {
+		id := a.QuestionID.String()
+		q, ok := byID[id]
+		if !ok {
+			errs[id] = "question does not belong to this form"
+			continue
+		}
+		if seen[id] {
+			errs[id] = "question answered more than once"
+			continue
+		}
+		seen[id] = true
+
+		value, err := decodeValue(a.Value)
+		if err != nil {
+			errs[id] = "answer is not valid JSON"
+			continue
+		}
+		if isEmpty(value) {
+			continue
+		}
+		answered[id] = true
+
+		validate, ok := answerValidators[q.Type]
+		if !ok {
+			errs[id] = fmt.Sprintf("unsupported question type %q", q.Type)
+			continue
+		}
+		if err := validate(q, value); err != nil {
+			errs[id] = err.Error()
+		}
+	}
+
+	for _, q := range questions {
+		id := q.ID.String()
+		if complete && q.Required && !answered[id] {
+			if _, exists := errs[id]; !exists {
+				errs[id] = "this question is required"
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func decodeValue(raw json.RawMessage) (any, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func isEmpty(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []any:
+		return len(v) == 0
+	}
+	return false
+}
+
+func textValidator(maxLength int) AnswerValidator {
+	return func(q model.Question, value any) error {
+		s, ok := value.(string)
+		if !ok {
+			return errors.New("answer must be text")
+		}
+		if utf8.RuneCountInString(s) > maxLength {
+			return fmt.Errorf("answer must be at most %d characters", maxLength)
+		}
+		return nil
+	}
+}
+
+// emailValidator accepts a bare address such as "ada@example.com", without
+// a display name or angle brackets.
+func emailValidator(q model.Question, value any) error {
+	s, ok := value.(string)
+	if !ok {
+		return errors.New("answer must be text")
+	}
+	if len(s) > MaxEmailLength {
+		return fmt.Errorf("email address must be at most %d characters", MaxEmailLength)
+	}
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Name != "" || addr.Address != strings.TrimSpace(s) {
+		return errors.New("answer must be an email address")
+	}
+	return nil
+}
+
+// numberValidator accepts a JSON number within the question's bounds. Text
+// holding a number is refused, so that answers can be summed and averaged.
+func numberValidator(q model.Question, value any) error {
+	n, ok := jsonNumber(value)
+	if !ok {
+		return errors.New("answer must be a number")
+	}
+	s := model.NumberSettings{}
+	if q.Number != nil {
+		s = *q.Number
+	}
+	if s.Integer && n != math.Trunc(n) {
+		return errors.New("answer must be a whole number")
+	}
+	if s.Min != nil && n < *s.Min {
+		return fmt.Errorf("answer must be at least %g", *s.Min)
+	}
+	if s.Max != nil && n > *s.Max {
+		return fmt.Errorf("answer must be at most %g", *s.Max)
+	}
+	return nil
+}
+
+// ratingValidator accepts a whole number from 1 to the question's scale.
+func ratingValidator(q model.Question, value any) error {
+	n, ok := jsonNumber(value)
+	if scale := q.RatingScale(); !ok || n != math.Trunc(n) || n < 1 || n > float64(scale) {
+		return fmt.Errorf("answer must be a whole number from 1 to %d", scale)
+	}
+	return nil
+}
+
+func jsonNumber(value any) (float64, bool) {
+	v, ok := value.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	n, err := v.Float64()
+	return n, err == nil && !math.IsInf(n, 0)
+}
+
+// fileValidator accepts the IDs of uploads, as a list or a lone string, up
+// to the question's file limit. Whether the uploads exist is checked when
+// the submission is saved.
+func fileValidator(q model.Question, value any) error {
+	if s, ok := value.(string); ok {
+		value = []any{s}
+	}
+	items, ok := value.([]any)
+	if !ok {
+		return errors.New("answer must be a list of upload IDs")
+	}
+	if limit := q.FileLimits().MaxFiles; len(items) > limit {
+		return fmt.Errorf("at most %d files may be attached", limit)
+	}
+
+	seen := make(map[uuid.UUID]bool, len(items))
+	for _, item := range items {
+		s, _ := item.(string)
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return errors.New("answer must be a list of upload IDs")
+		}
+		if seen[id] {
+			return errors.New("a file is attached more than once")
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// FileIDs returns the upload IDs of a file_upload answer that passed
+// ValidateAnswers.
+func FileIDs(raw json.RawMessage) []uuid.UUID {
+	var ids []uuid.UUID
+	if json.Unmarshal(raw, &ids) == nil {
+		return ids
+	}
+	var id uuid.UUID
+	if json.Unmarshal(raw, &id) == nil {
+		return []uuid.UUID{id}
+	}
+	return nil
+}
+
+// matchOption reports whether choice is the label or the ID of one of the
+// question's options.
+func matchOption(q model.Question, choice string) bool {
+	for _, opt := range q.Options {
+		if opt.Label == choice || opt.ID.String() == choice {
+			return true
+		}
+	}
+	return false
+}
+
+func singleChoiceValidator(q model.Question, value any) error {
+	s, ok := value.(string)
+	if !ok {
+		return errors.New("answer must be a single option")
+	}
+	if !matchOption(q, s) {
+		return fmt.Errorf("%q is not one of the options", s)
+	}
+	return nil
+}
+
+func multiChoiceValidator(q model.Question, value any) error {
+	// a lone string is accepted as a one-element selection
+	if s, ok := value.(string); ok {
+		value = []any{s}
+	}
+	items, ok := value.([]any)
+	if !ok {
+		return errors.New("answer must be a list of options")
+	}
+
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return errors.New("answer must be a list of options")
+		}
+		if !matchOption(q, s) {
+			return fmt.Errorf("%q is not one of the options", s)
+		}
+		if seen[s] {
+			return fmt.Errorf("%q is selected more than once", s)
+		}
+		seen[s] = true
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package validation
+
+import (
+	"craft/internal/model"
+	"craft/internal/model/payload"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MaxAutoresponderSubjectLength = 200
+	MaxAutoresponderBodyLength    = 10000
+)
+
+// ValidateAutoresponder checks an autoresponder against the form it
+// belongs to. The returned map is keyed by JSON field name.
+func ValidateAutoresponder(f *model.Form, req payload.PutAutoresponderRequest) FieldErrors {
+	errs := FieldErrors{}
+
+	found := false
+	for _, q := range f.Questions {
+		if q.ID == req.QuestionID && q.ArchivedAt == nil {
+			found = true
+			if q.Type != model.QuestionTypeEmail {
+				errs["question_id"] = "question must be an email question"
+			}
+		}
+	}
+	if !found {
+		errs["question_id"] = "question does not belong to this form"
+	}
+
+	switch {
+	case strings.TrimSpace(req.Subject) == "":
+		errs["subject"] = "subject is required"
+	case utf8.RuneCountInString(req.Subject) > MaxAutoresponderSubjectLength:
+		errs["subject"] = "subject is too long"
+	}
+
+	switch {
+	case strings.TrimSpace(req.Body) == "":
+		errs["body"] = "body is required"
+	case utf8.RuneCountInString(req.Body) > MaxAutoresponderBodyLength:
+		errs["body"] = "body is too long"
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
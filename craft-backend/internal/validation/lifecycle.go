@@ -0,0 +1,48 @@
+package validation
+
+import (
+	"craft/internal/model"
+	"time"
+)
+
+// LifecycleError explains why a form is not accepting a submission. Code is
+// stable and meant for clients to branch on.
+type LifecycleError struct {
+	Code    string
+	Message string
+}
+
+func (e *LifecycleError) Error() string {
+	return e.Message
+}
+
+var (
+	ErrFormNotPublished  = &LifecycleError{"form_not_published", "This form is not accepting responses yet"}
+	ErrFormNotPublic     = &LifecycleError{"form_not_public", "This form is not open to the public"}
+	ErrFormClosed        = &LifecycleError{"form_closed", "This form is no longer accepting responses"}
+	ErrFormPastCloseDate = &LifecycleError{"form_close_date_passed", "The deadline for this form has passed"}
+	ErrAlreadySubmitted  = &LifecycleError{"already_submitted", "You have already responded to this form"}
+)
+
+// CheckAcceptingSubmissions reports whether f can take a new submission at now.
+// It does not consider AllowMultipleSubmissions; that needs the respondent's
+// history and is enforced by the submission store.
+func CheckAcceptingSubmissions(f *model.Form, now time.Time) error {
+	switch f.Status {
+	case model.FormStatusPublished:
+	case model.FormStatusClosed:
+		return ErrFormClosed
+	default:
+		return ErrFormNotPublished
+	}
+
+	if !f.IsPublic {
+		return ErrFormNotPublic
+	}
+
+	if f.CloseDate != nil && !now.Before(*f.CloseDate) {
+		return ErrFormPastCloseDate
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package validation
+
+import (
+	"craft/internal/model"
+	"fmt"
+	"math"
+	"mime"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxRatingScale caps the points of a rating question.
+	MaxRatingScale = 10
+	// MaxFileSize caps the files of every file_upload question.
+	MaxFileSize = 25 << 20
+	// MaxFilesPerQuestion caps the files one answer may attach.
+	MaxFilesPerQuestion = 10
+)
+
+// ValidateQuestions checks the settings of a form's questions. The returned
+// map is keyed by question ID, or by position for questions without one.
+func ValidateQuestions(questions []model.Question) FieldErrors {
+	errs := FieldErrors{}
+
+	for i, q := range questions {
+		key := q.ID.String()
+		if q.ID == uuid.Nil {
+			key = fmt.Sprint(i)
+		}
+		if msg := checkQuestion(q, questions[:i]); msg != "" {
+			errs[key] = msg
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// checkQuestion returns what is wrong with the settings of q, given the
+// questions before it, or "".
+func checkQuestion(q model.Question, earlier []model.Question) string {
+	if _, ok := answerValidators[q.Type]; !ok {
+		return fmt.Sprintf("unsupported question type %q", q.Type)
+	}
+	if q.Number != nil {
+		if q.Type != model.QuestionTypeNumber {
+			return "number settings only apply to number questions"
+		}
+		if msg := checkNumberSettings(*q.Number); msg != "" {
+			return msg
+		}
+	}
+	if q.Rating != nil {
+		if q.Type != model.QuestionTypeRating {
+			return "rating settings only apply to rating questions"
+		}
+		if s := q.Rating.Scale; s != 0 && (s < 2 || s > MaxRatingScale) {
+			return fmt.Sprintf("scale must be between 2 and %d", MaxRatingScale)
+		}
+	}
+	if q.FileUpload != nil {
+		if q.Type != model.QuestionTypeFileUpload {
+			return "file upload settings only apply to file_upload questions"
+		}
+		if msg := checkFileUploadSettings(*q.FileUpload); msg != "" {
+			return msg
+		}
+	}
+	if q.Visibility != nil {
+		return checkVisibilityRule(*q.Visibility, earlier)
+	}
+	return ""
+}
+
+func checkNumberSettings(s model.NumberSettings) string {
+	if s.Min != nil && s.Max != nil && *s.Min > *s.Max {
+		return "min must not be greater than max"
+	}
+	if s.Integer && s.Min != nil && s.Max != nil && math.Ceil(*s.Min) > math.Floor(*s.Max) {
+		return "no whole number lies between min and max"
+	}
+	return ""
+}
+
+func checkFileUploadSettings(s model.FileUploadSettings) string {
+	if s.MaxFiles < 0 || s.MaxFiles > MaxFilesPerQuestion {
+		return fmt.Sprintf("max_files must be between 1 and %d", MaxFilesPerQuestion)
+	}
+	if s.MaxSize < 0 || s.MaxSize > MaxFileSize {
+		return fmt.Sprintf("max_size must be at most %d bytes", MaxFileSize)
+	}
+	for _, t := range s.AllowedTypes {
+		mediaType, params, err := mime.ParseMediaType(t)
+		if err != nil || len(params) > 0 || !strings.Contains(mediaType, "/") {
+			return fmt.Sprintf("%q is not a MIME type", t)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+package validation
+
+import (
+	"craft/internal/model"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// MaxSections caps the pages of a form.
+const MaxSections = 50
+
+// ValidateSections checks a form's pages against its questions: pages need
+// distinct IDs, every question of a form with pages must be on one, and
+// questions must come in the order of their pages. Jumps may only test
+// questions up to the end of their page and only lead to later pages. The
+// returned map is keyed by section or question ID, or by "sections.<index>"
+// for sections without one.
+func ValidateSections(sections []model.Section, questions []model.Question) FieldErrors {
+	errs := FieldErrors{}
+	if len(sections) > MaxSections {
+		errs["sections"] = fmt.Sprintf("a form can have at most %d pages", MaxSections)
+		return errs
+	}
+
+	pageOf := make(map[uuid.UUID]int, len(sections))
+	for i, sec := range sections {
+		if sec.ID == uuid.Nil {
+			errs[fmt.Sprintf("sections.%d", i)] = "page needs an ID"
+			continue
+		}
+		if _, dup := pageOf[sec.ID]; dup {
+			errs[sec.ID.String()] = "page ID is used more than once"
+			continue
+		}
+		pageOf[sec.ID] = i
+	}
+
+	// onPage[p] holds the questions on pages up to p, which jumps from p
+	// may test
+	onPage := make([][]model.Question, len(sections))
+	last := 0
+	for i, q := range questions {
+		key := q.ID.String()
+		if q.ID == uuid.Nil {
+			key = fmt.Sprint(i)
+		}
+		if len(sections) == 0 {
+			if q.SectionID != nil {
+				errs[key] = "question is on a page but the form has none"
+			}
+			continue
+		}
+		if q.SectionID == nil {
+			errs[key] = "question must be on a page"
+			continue
+		}
+		page, ok := pageOf[*q.SectionID]
+		if !ok {
+			errs[key] = "question is on an unknown page"
+			continue
+		}
+		if page < last {
+			errs[key] = "questions must be in the order of their pages"
+			continue
+		}
+		last = page
+		onPage[page] = append(onPage[page], q)
+	}
+
+	var earlier []model.Question
+	for i, sec := range sections {
+		earlier = append(earlier, onPage[i]...)
+		if msg := checkJumps(sec, i, earlier, pageOf); msg != "" && sec.ID != uuid.Nil {
+			errs[sec.ID.String()] = msg
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func checkJumps(sec model.Section, page int, earlier []model.Question, pageOf map[uuid.UUID]int) string {
+	if len(sec.Jumps) > MaxConditions {
+		return fmt.Sprintf("a page can have at most %d jumps", MaxConditions)
+	}
+
+	byID := make(map[uuid.UUID]model.Question, len(earlier))
+	for _, q := range earlier {
+		if q.ID != uuid.Nil {
+			byID[q.ID] = q
+		}
+	}
+	for i, j := range sec.Jumps {
+		q, ok := byID[j.When.QuestionID]
+		if !ok {
+			return fmt.Sprintf("jump %d must test a question on this page or an earlier one", i+1)
+		}
+		if msg := checkCondition(j.When, q); msg != "" {
+			return fmt.Sprintf("jump %d: %s", i+1, msg)
+		}
+		if j.To != nil {
+			if to, ok := pageOf[*j.To]; !ok || to <= page {
+				return fmt.Sprintf("jump %d must lead to a later page", i+1)
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,152 @@
+package validation
+
+import (
+	"craft/internal/model"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// onPage returns q placed on sec.
+func onPage(q model.Question, sec model.Section) model.Question {
+	q.SectionID = &sec.ID
+	return q
+}
+
+// jump returns a jump taken when c holds, to the page to or, if to is nil,
+// to the end of the form.
+func jump(c model.Condition, to *model.Section) model.PageJump {
+	j := model.PageJump{When: c}
+	if to != nil {
+		j.To = &to.ID
+	}
+	return j
+}
+
+func TestVisibleQuestionsPages(t *testing.T) {
+	about := model.Section{ID: uuid.New(), Title: "About you"}
+	food := model.Section{ID: uuid.New(), Title: "Food"}
+	extra := model.Section{ID: uuid.New(), Title: "Anything else"}
+	coming := onPage(model.Question{ID: uuid.New(), Type: model.QuestionTypeSingleSelect, Title: "Coming", Options: []model.Option{
+		{ID: uuid.New(), Label: "Yes"}, {ID: uuid.New(), Label: "No"}, {ID: uuid.New(), Label: "Maybe"},
+	}}, about)
+	diet := onPage(model.Question{ID: uuid.New(), Type: model.QuestionTypeShortText, Title: "Diet", Required: true}, food)
+	notes := onPage(model.Question{ID: uuid.New(), Type: model.QuestionTypeLongText, Title: "Notes"}, extra)
+	about.Jumps = []model.PageJump{
+		jump(when(coming, model.ConditionEquals, `"No"`), nil),
+		jump(when(coming, model.ConditionEquals, `"Maybe"`), &extra),
+	}
+	f := &model.Form{Sections: []model.Section{about, food, extra}, Questions: []model.Question{coming, diet, notes}}
+
+	tests := []struct {
+		name    string
+		answers map[*model.Question]string
+		shown   []string
+		kept    []string
+		invalid []string
+	}{
+		{
+			name:    "no jump applies",
+			answers: map[*model.Question]string{&coming: `"Yes"`},
+			shown:   []string{"Coming", "Diet", "Notes"},
+			kept:    []string{"Coming"},
+			invalid: []string{"Diet"},
+		},
+		{
+			name:    "a jump skips a required page",
+			answers: map[*model.Question]string{&coming: `"Maybe"`, &notes: `"Late"`},
+			shown:   []string{"Coming", "Notes"},
+			kept:    []string{"Coming", "Notes"},
+		},
+		{
+			name:    "an end form jump skips every later page",
+			answers: map[*model.Question]string{&coming: `"No"`, &diet: `"Vegan"`, &notes: `"Sorry"`},
+			shown:   []string{"Coming"},
+			kept:    []string{"Coming"},
+		},
+		{
+			name:    "unanswered, no jump applies",
+			answers: map[*model.Question]string{&diet: `"Vegan"`},
+			shown:   []string{"Coming", "Diet", "Notes"},
+			kept:    []string{"Diet"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shown, kept := VisibleQuestions(f, answersTo(tt.answers))
+			if got := titles(shown); !slices.Equal(got, tt.shown) {
+				t.Fatalf("shown %q, want %q", got, tt.shown)
+			}
+			if got := answered(f, kept); !slices.Equal(got, tt.kept) {
+				t.Fatalf("kept answers to %q, want %q", got, tt.kept)
+			}
+			var invalid []string
+			errs := ValidateAnswers(shown, kept)
+			for _, q := range f.Questions {
+				if _, ok := errs[q.ID.String()]; ok {
+					invalid = append(invalid, q.Title)
+				}
+			}
+			if !slices.Equal(invalid, tt.invalid) {
+				t.Fatalf("invalid %q (%v), want %q", invalid, errs, tt.invalid)
+			}
+		})
+	}
+}
+
+func TestValidateSections(t *testing.T) {
+	first := model.Section{ID: uuid.New(), Title: "First"}
+	second := model.Section{ID: uuid.New(), Title: "Second"}
+	third := model.Section{ID: uuid.New(), Title: "Third"}
+	name := onPage(model.Question{ID: uuid.New(), Type: model.QuestionTypeShortText, Title: "Name"}, first)
+	age := onPage(model.Question{ID: uuid.New(), Type: model.QuestionTypeNumber, Title: "Age"}, second)
+	notes := onPage(model.Question{ID: uuid.New(), Type: model.QuestionTypeLongText, Title: "Notes"}, third)
+
+	// withJumps returns the first two pages with jumps on the first, and
+	// the third as is
+	withJumps := func(jumps ...model.PageJump) []model.Section {
+		sec := first
+		sec.Jumps = jumps
+		return []model.Section{sec, second, third}
+	}
+	pages := []model.Section{first, second, third}
+	questions := []model.Question{name, age, notes}
+
+	tests := []struct {
+		name      string
+		sections  []model.Section
+		questions []model.Question
+		invalid   string // key of the error, "" for none
+	}{
+		{"no pages", nil, []model.Question{{ID: name.ID, Type: name.Type}}, ""},
+		{"pages in order", pages, questions, ""},
+		{"jump to a later page", withJumps(jump(when(name, model.ConditionAnswered, ""), &third)), questions, ""},
+		{"end form jump", withJumps(jump(when(name, model.ConditionNotAnswered, ""), nil)), questions, ""},
+		{"jump to this page", withJumps(jump(when(name, model.ConditionAnswered, ""), &first)), questions, first.ID.String()},
+		{"jump to an unknown page", withJumps(jump(when(name, model.ConditionAnswered, ""), &model.Section{ID: uuid.New()})), questions, first.ID.String()},
+		{"jump on a later question", withJumps(jump(when(age, model.ConditionGreater, "18"), &third)), questions, first.ID.String()},
+		{"jump on an unknown question", withJumps(jump(model.Condition{QuestionID: uuid.New(), Operator: model.ConditionAnswered}, &third)), questions, first.ID.String()},
+		{"jump with a bad condition", withJumps(jump(when(name, model.ConditionGreater, `"x"`), &third)), questions, first.ID.String()},
+		{"pages in the wrong order", pages, []model.Question{name, notes, age}, age.ID.String()},
+		{"question without a page", pages, []model.Question{name, {ID: age.ID, Type: age.Type}, notes}, age.ID.String()},
+		{"question on an unknown page", pages, []model.Question{name, onPage(age, model.Section{ID: uuid.New()}), notes}, age.ID.String()},
+		{"question on a page of a form without pages", nil, questions, name.ID.String()},
+		{"page ID used twice", []model.Section{first, second, first}, questions, first.ID.String()},
+		{"page without an ID", []model.Section{first, {Title: "Second"}}, []model.Question{name}, "sections.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateSections(tt.sections, tt.questions)
+			if tt.invalid == "" {
+				if errs != nil {
+					t.Fatalf("unexpected errors %v", errs)
+				}
+				return
+			}
+			if errs[tt.invalid] == "" {
+				t.Fatalf("errors %v, want one for %s", errs, tt.invalid)
+			}
+		})
+	}
+}
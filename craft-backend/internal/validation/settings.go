@@ -0,0 +1,55 @@
+package validation
+
+import (
+	"craft/internal/model/payload"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const MaxThankYouMessageLength = 2000
+
+// AllowedRedirectSchemes lists the URL schemes a form may redirect
+// respondents to after submitting.
+var AllowedRedirectSchemes = []string{"https", "http"}
+
+// ValidateFormSettings checks the fields present in a settings patch. The
+// returned map is keyed by JSON field name.
+func ValidateFormSettings(req payload.UpdateFormSettingsRequest, now time.Time) FieldErrors {
+	errs := FieldErrors{}
+
+	if req.CloseDate.Value != nil && !req.CloseDate.Value.After(now) {
+		errs["close_date"] = "close date must be in the future"
+	}
+
+	if req.ThankYouMessage.Value != nil && utf8.RuneCountInString(*req.ThankYouMessage.Value) > MaxThankYouMessageLength {
+		errs["thank_you_message"] = "thank you message is too long"
+	}
+
+	if req.RedirectURL.Value != nil {
+		if msg := checkRedirectURL(*req.RedirectURL.Value); msg != "" {
+			errs["redirect_url"] = msg
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func checkRedirectURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return "redirect URL must be an absolute URL"
+	}
+	if !slices.Contains(AllowedRedirectSchemes, strings.ToLower(u.Scheme)) {
+		return "redirect URL must use one of: " + strings.Join(AllowedRedirectSchemes, ", ")
+	}
+	if u.User != nil {
+		return "redirect URL must not contain credentials"
+	}
+	return ""
+}
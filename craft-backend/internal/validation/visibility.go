@@ -0,0 +1,307 @@
+package validation
+
+import (
+	"craft/internal/model"
+	"craft/internal/model/payload"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// MaxConditions caps the conditions of one visibility rule.
+const MaxConditions = 20
+
+// VisibleQuestions walks a submission through the form's pages, following
+// page jumps, and applies the visibility rules of the questions on the pages
+// it reaches, in order. It returns the questions the respondent was shown
+// and the answers to keep: answers to skipped or hidden questions are
+// dropped, as if they were never given, and count as unanswered in later
+// rules and jumps.
+func VisibleQuestions(f *model.Form, answers []payload.AnswerInput) ([]model.Question, []payload.AnswerInput) {
+	w := walk{
+		sections: f.Sections,
+		pageOf:   make(map[uuid.UUID]int, len(f.Sections)),
+		given:    make(map[uuid.UUID]json.RawMessage, len(answers)),
+		visible:  make(map[uuid.UUID]model.Question, len(f.Questions)),
+	}
+	for _, a := range answers {
+		w.given[a.QuestionID] = a.Value
+	}
+	for p, sec := range f.Sections {
+		w.pageOf[sec.ID] = p
+	}
+
+	// a question without a known page stays on the page of the one before
+	pages := make([][]model.Question, max(len(f.Sections), 1))
+	page := 0
+	for _, q := range f.Questions {
+		if q.SectionID != nil {
+			if p, ok := w.pageOf[*q.SectionID]; ok {
+				page = p
+			}
+		}
+		pages[page] = append(pages[page], q)
+	}
+
+	var shown []model.Question
+	for page := 0; page < len(pages); page = w.nextPage(page) {
+		for _, q := range pages[page] {
+			if q.Visibility == nil || w.ruleHolds(*q.Visibility) {
+				w.visible[q.ID] = q
+				shown = append(shown, q)
+			}
+		}
+	}
+	if len(shown) == len(f.Questions) {
+		return shown, answers
+	}
+
+	known := make(map[uuid.UUID]bool, len(f.Questions))
+	for _, q := range f.Questions {
+		known[q.ID] = true
+	}
+	kept := make([]payload.AnswerInput, 0, len(answers))
+	for _, a := range answers {
+		if _, ok := w.visible[a.QuestionID]; ok || !known[a.QuestionID] {
+			kept = append(kept, a)
+		}
+	}
+	return shown, kept
+}
+
+// walk is the state of VisibleQuestions: the form's pages, the answers
+// given, and the questions shown so far.
+type walk struct {
+	sections []model.Section
+	pageOf   map[uuid.UUID]int
+	given    map[uuid.UUID]json.RawMessage
+	visible  map[uuid.UUID]model.Question
+}
+
+// holds evaluates c against the answer to a question shown so far; other
+// questions count as unanswered.
+func (w *walk) holds(c model.Condition) bool {
+	q, ok := w.visible[c.QuestionID]
+	if !ok {
+		return conditionHolds(c, model.Question{}, nil)
+	}
+	return conditionHolds(c, q, w.given[q.ID])
+}
+
+func (w *walk) ruleHolds(r model.VisibilityRule) bool {
+	for _, c := range r.Conditions {
+		if met := w.holds(c); met == r.MatchAny() {
+			return met
+		}
+	}
+	return !r.MatchAny()
+}
+
+// nextPage returns the page that follows page: the target of its first jump
+// that applies, or the next page. Jumps only lead forward, and ending the
+// form returns the page count.
+func (w *walk) nextPage(page int) int {
+	if page >= len(w.sections) {
+		return page + 1
+	}
+	for _, j := range w.sections[page].Jumps {
+		if !w.holds(j.When) {
+			continue
+		}
+		if j.To == nil {
+			return len(w.sections)
+		}
+		if p, ok := w.pageOf[*j.To]; ok && p > page {
+			return p
+		}
+	}
+	return page + 1
+}
+
+func conditionHolds(c model.Condition, q model.Question, raw json.RawMessage) bool {
+	value, err := decodeValue(raw)
+	if err != nil || isEmpty(value) {
+		value = nil
+	}
+
+	switch c.Operator {
+	case model.ConditionAnswered:
+		return value != nil
+	case model.ConditionNotAnswered:
+		return value == nil
+	case model.ConditionEquals:
+		return answerEquals(q, value, conditionText(c))
+	case model.ConditionNotEquals:
+		return !answerEquals(q, value, conditionText(c))
+	case model.ConditionContains:
+		return answerContains(q, value, conditionText(c))
+	case model.ConditionNotContains:
+		return !answerContains(q, value, conditionText(c))
+	}
+
+	n, ok := answerNumber(value)
+	limit, err := strconv.ParseFloat(string(c.Value), 64)
+	if !ok || err != nil {
+		return false
+	}
+	switch c.Operator {
+	case model.ConditionGreater:
+		return n > limit
+	case model.ConditionGreaterOrEqual:
+		return n >= limit
+	case model.ConditionLess:
+		return n < limit
+	case model.ConditionLessOrEqual:
+		return n <= limit
+	}
+	return false
+}
+
+func conditionText(c model.Condition) string {
+	var s string
+	json.Unmarshal(c.Value, &s)
+	return strings.TrimSpace(s)
+}
+
+// answerTexts returns the strings of a text or choice answer.
+func answerTexts(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{strings.TrimSpace(v)}
+	case []any:
+		texts := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				texts = append(texts, strings.TrimSpace(s))
+			}
+		}
+		return texts
+	}
+	return nil
+}
+
+// optionKey resolves an option label or ID to the option's ID, so answers
+// and conditions match whichever of the two they use.
+func optionKey(q model.Question, choice string) string {
+	for _, opt := range q.Options {
+		if opt.Label == choice || opt.ID.String() == choice {
+			return opt.ID.String()
+		}
+	}
+	return choice
+}
+
+// answerEquals reports whether the answer is want, or for answers with
+// several options, whether want is among them. Text is compared without
+// regard to case.
+func answerEquals(q model.Question, value any, want string) bool {
+	if len(q.Options) > 0 {
+		want = optionKey(q, want)
+	}
+	for _, s := range answerTexts(value) {
+		if len(q.Options) > 0 {
+			if optionKey(q, s) == want {
+				return true
+			}
+		} else if strings.EqualFold(s, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// answerContains reports whether a text answer contains want, without
+// regard to case, or whether a choice answer includes the option want.
+func answerContains(q model.Question, value any, want string) bool {
+	if len(q.Options) > 0 {
+		return answerEquals(q, value, want)
+	}
+	want = strings.ToLower(want)
+	for _, s := range answerTexts(value) {
+		if strings.Contains(strings.ToLower(s), want) {
+			return true
+		}
+	}
+	return false
+}
+
+// answerNumber reads a number answer, or a text answer holding a number.
+func answerNumber(value any) (float64, bool) {
+	var s string
+	switch v := value.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = strings.TrimSpace(v)
+	default:
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	return n, err == nil
+}
+
+// checkVisibilityRule returns what is wrong with r, given the questions
+// before the one it belongs to, or "". Rules may only refer to earlier
+// questions, so they are evaluated in order and never form a cycle.
+func checkVisibilityRule(r model.VisibilityRule, earlier []model.Question) string {
+	if r.Match != "" && r.Match != model.VisibilityMatchAll && r.Match != model.VisibilityMatchAny {
+		return `visibility match must be "all" or "any"`
+	}
+	if len(r.Conditions) == 0 || len(r.Conditions) > MaxConditions {
+		return fmt.Sprintf("visibility rules need between 1 and %d conditions", MaxConditions)
+	}
+
+	byID := make(map[uuid.UUID]model.Question, len(earlier))
+	for _, q := range earlier {
+		if q.ID != uuid.Nil {
+			byID[q.ID] = q
+		}
+	}
+	for i, c := range r.Conditions {
+		q, ok := byID[c.QuestionID]
+		if !ok {
+			return fmt.Sprintf("condition %d must refer to an earlier question", i+1)
+		}
+		if msg := checkCondition(c, q); msg != "" {
+			return fmt.Sprintf("condition %d: %s", i+1, msg)
+		}
+	}
+	return ""
+}
+
+func checkCondition(c model.Condition, q model.Question) string {
+	hasValue := len(c.Value) > 0 && string(c.Value) != "null"
+
+	switch c.Operator {
+	case model.ConditionAnswered, model.ConditionNotAnswered:
+		if hasValue {
+			return c.Operator + " takes no value"
+		}
+		return ""
+	case model.ConditionEquals, model.ConditionNotEquals, model.ConditionContains, model.ConditionNotContains:
+		if q.Type == model.QuestionTypeNumber || q.Type == model.QuestionTypeRating {
+			return q.Type + " questions are compared with gt, gte, lt and lte"
+		}
+		var s string
+		if json.Unmarshal(c.Value, &s) != nil || strings.TrimSpace(s) == "" {
+			return c.Operator + " needs a text value"
+		}
+		if len(q.Options) > 0 && !matchOption(q, strings.TrimSpace(s)) {
+			return fmt.Sprintf("%q is not one of the options of %q", s, q.Title)
+		}
+	case model.ConditionGreater, model.ConditionGreaterOrEqual, model.ConditionLess, model.ConditionLessOrEqual:
+		if _, err := strconv.ParseFloat(string(c.Value), 64); err != nil {
+			return c.Operator + " needs a number value"
+		}
+	default:
+		return fmt.Sprintf("unknown operator %q", c.Operator)
+	}
+
+	if q.Type == model.QuestionTypeFileUpload {
+		return "file_upload questions only support answered and not_answered"
+	}
+	return ""
+}
@@ -0,0 +1,176 @@
+package validation
+
+import (
+	"craft/internal/model"
+	"craft/internal/model/payload"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// when returns a condition on q.
+func when(q model.Question, operator, value string) model.Condition {
+	c := model.Condition{QuestionID: q.ID, Operator: operator}
+	if value != "" {
+		c.Value = json.RawMessage(value)
+	}
+	return c
+}
+
+// showIf returns q shown only when the conditions hold.
+func showIf(q model.Question, match string, conditions ...model.Condition) model.Question {
+	q.Visibility = &model.VisibilityRule{Match: match, Conditions: conditions}
+	return q
+}
+
+// answersTo pairs questions with raw JSON values.
+func answersTo(values map[*model.Question]string) []payload.AnswerInput {
+	var answers []payload.AnswerInput
+	for q, v := range values {
+		answers = append(answers, payload.AnswerInput{QuestionID: q.ID, Value: json.RawMessage(v)})
+	}
+	return answers
+}
+
+func titles(questions []model.Question) []string {
+	out := make([]string, len(questions))
+	for i, q := range questions {
+		out[i] = q.Title
+	}
+	return out
+}
+
+func answered(f *model.Form, answers []payload.AnswerInput) []string {
+	var out []string
+	for _, q := range f.Questions {
+		if slices.ContainsFunc(answers, func(a payload.AnswerInput) bool { return a.QuestionID == q.ID }) {
+			out = append(out, q.Title)
+		}
+	}
+	return out
+}
+
+func TestVisibleQuestions(t *testing.T) {
+	diet := model.Question{ID: uuid.New(), Type: model.QuestionTypeSingleSelect, Title: "Diet", Options: []model.Option{
+		{ID: uuid.New(), Label: "Vegan"},
+		{ID: uuid.New(), Label: "Other"},
+	}}
+	which := showIf(model.Question{ID: uuid.New(), Type: model.QuestionTypeShortText, Title: "Which", Required: true},
+		"", when(diet, model.ConditionEquals, `"Other"`))
+	allergies := showIf(model.Question{ID: uuid.New(), Type: model.QuestionTypeShortText, Title: "Allergies"},
+		"", when(which, model.ConditionAnswered, ""))
+	age := model.Question{ID: uuid.New(), Type: model.QuestionTypeNumber, Title: "Age"}
+	drinks := showIf(model.Question{ID: uuid.New(), Type: model.QuestionTypeShortText, Title: "Drinks"},
+		model.VisibilityMatchAny, when(age, model.ConditionGreaterOrEqual, "18"), when(diet, model.ConditionEquals, `"Vegan"`))
+	f := &model.Form{Questions: []model.Question{diet, which, allergies, age, drinks}}
+
+	tests := []struct {
+		name    string
+		answers map[*model.Question]string
+		shown   []string
+		kept    []string
+		invalid []string // questions ValidateAnswers rejects on what is shown
+	}{
+		{
+			name:    "hidden required question is not enforced",
+			answers: map[*model.Question]string{&diet: `"Vegan"`},
+			shown:   []string{"Diet", "Age", "Drinks"},
+			kept:    []string{"Diet"},
+		},
+		{
+			name:    "shown required question is enforced",
+			answers: map[*model.Question]string{&diet: `"Other"`},
+			shown:   []string{"Diet", "Which", "Age"},
+			kept:    []string{"Diet"},
+			invalid: []string{"Which"},
+		},
+		{
+			name:    "answers to hidden questions are dropped",
+			answers: map[*model.Question]string{&diet: `"Vegan"`, &which: `"Paleo"`, &allergies: `"Nuts"`},
+			shown:   []string{"Diet", "Age", "Drinks"},
+			kept:    []string{"Diet"},
+		},
+		{
+			name:    "conditions match option IDs",
+			answers: map[*model.Question]string{&diet: `"` + diet.Options[1].ID.String() + `"`, &which: `"Paleo"`},
+			shown:   []string{"Diet", "Which", "Allergies", "Age"},
+			kept:    []string{"Diet", "Which"},
+		},
+		{
+			name:    "any condition is enough",
+			answers: map[*model.Question]string{&diet: `"Other"`, &which: `"Paleo"`, &age: `21`, &drinks: `"Tea"`},
+			shown:   []string{"Diet", "Which", "Allergies", "Age", "Drinks"},
+			kept:    []string{"Diet", "Which", "Age", "Drinks"},
+		},
+		{
+			name:    "no condition holds",
+			answers: map[*model.Question]string{&diet: `"Other"`, &which: `"Paleo"`, &age: `16`, &drinks: `"Beer"`},
+			shown:   []string{"Diet", "Which", "Allergies", "Age"},
+			kept:    []string{"Diet", "Which", "Age"},
+		},
+		{
+			name:    "blank answers count as unanswered",
+			answers: map[*model.Question]string{&diet: `"Other"`, &which: `"  "`},
+			shown:   []string{"Diet", "Which", "Age"},
+			kept:    []string{"Diet", "Which"},
+			invalid: []string{"Which"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shown, kept := VisibleQuestions(f, answersTo(tt.answers))
+			if got := titles(shown); !slices.Equal(got, tt.shown) {
+				t.Fatalf("shown %q, want %q", got, tt.shown)
+			}
+			if got := answered(f, kept); !slices.Equal(got, tt.kept) {
+				t.Fatalf("kept answers to %q, want %q", got, tt.kept)
+			}
+			var invalid []string
+			errs := ValidateAnswers(shown, kept)
+			for _, q := range f.Questions {
+				if _, ok := errs[q.ID.String()]; ok {
+					invalid = append(invalid, q.Title)
+				}
+			}
+			if !slices.Equal(invalid, tt.invalid) {
+				t.Fatalf("invalid %q (%v), want %q", invalid, errs, tt.invalid)
+			}
+		})
+	}
+}
+
+func TestValidateQuestionsVisibility(t *testing.T) {
+	diet := model.Question{ID: uuid.New(), Type: model.QuestionTypeSingleSelect, Title: "Diet", Options: []model.Option{{ID: uuid.New(), Label: "Vegan"}}}
+	age := model.Question{ID: uuid.New(), Type: model.QuestionTypeNumber, Title: "Age"}
+	later := model.Question{ID: uuid.New(), Type: model.QuestionTypeShortText, Title: "Later"}
+	text := model.Question{ID: uuid.New(), Type: model.QuestionTypeShortText, Title: "Comments"}
+
+	tests := []struct {
+		name string
+		rule model.Question
+		ok   bool
+	}{
+		{"earlier question", showIf(text, "", when(diet, model.ConditionEquals, `"Vegan"`)), true},
+		{"number comparison", showIf(text, model.VisibilityMatchAny, when(age, model.ConditionLess, "18"), when(diet, model.ConditionAnswered, "")), true},
+		{"later question", showIf(text, "", when(later, model.ConditionAnswered, "")), false},
+		{"unknown question", showIf(text, "", model.Condition{QuestionID: uuid.New(), Operator: model.ConditionAnswered}), false},
+		{"itself", showIf(text, "", when(text, model.ConditionAnswered, "")), false},
+		{"unknown option", showIf(text, "", when(diet, model.ConditionEquals, `"Paleo"`)), false},
+		{"text comparison of a number", showIf(text, "", when(age, model.ConditionEquals, `"18"`)), false},
+		{"number comparison without a number", showIf(text, "", when(age, model.ConditionGreater, `"old"`)), false},
+		{"value on answered", showIf(text, "", when(diet, model.ConditionAnswered, `"Vegan"`)), false},
+		{"unknown operator", showIf(text, "", when(diet, "matches", `"Vegan"`)), false},
+		{"unknown match", showIf(text, "some", when(diet, model.ConditionAnswered, "")), false},
+		{"no conditions", showIf(text, ""), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateQuestions([]model.Question{diet, age, tt.rule, later})
+			if msg := errs[tt.rule.ID.String()]; (msg == "") != tt.ok {
+				t.Fatalf("error %q, want ok %v", msg, tt.ok)
+			}
+		})
+	}
+}
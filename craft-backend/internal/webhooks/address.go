@@ -0,0 +1,92 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+)
+
+// ErrPrivateAddress is returned for webhook URLs, and connections, that
+// would reach the server's own network rather than the public internet.
+var ErrPrivateAddress = errors.New("webhooks: address is not public")
+
+// blockedHosts are names that resolve to cloud metadata services, besides
+// those under .internal.
+var blockedHosts = []string{"metadata", "metadata.goog"}
+
+// blockedPrefixes are ranges outside the net/netip predicates that must not
+// be reached either.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // reserved, and broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64 of any IPv4 address
+	netip.MustParsePrefix("2002::/16"),     // 6to4 of any IPv4 address
+	netip.MustParsePrefix("fec0::/10"),     // deprecated site-local
+}
+
+// PublicAddr reports whether ip may be sent webhooks: it must not be
+// loopback, private, link-local (where 169.254.169.254 serves cloud
+// metadata), multicast or otherwise reserved.
+func PublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsUnspecified() || ip.IsLoopback() || ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL rejects webhook URLs naming a host that is not public: localhost,
+// a metadata service or a non-public IP address. Other names are checked
+// when connecting, as what they resolve to can change.
+func CheckURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("webhooks: %q is not an http(s) URL", raw)
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return fmt.Errorf("webhooks: %q has no host", raw)
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".internal") {
+		return ErrPrivateAddress
+	}
+	if slices.Contains(blockedHosts, host) {
+		return ErrPrivateAddress
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && !PublicAddr(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// dialControl refuses connections to addresses that are not public. It runs
+// after name resolution, for every address tried, so a name that resolves
+// to an internal address, or one re-pointed after the webhook was saved, is
+// refused too.
+func dialControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !PublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, addrPort.Addr())
+	}
+	return nil
+}
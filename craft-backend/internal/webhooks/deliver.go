@@ -0,0 +1,171 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"craft/internal/jobs"
+	"craft/internal/model"
+	"craft/internal/store"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeliverKind sends one webhook delivery. Each attempt is recorded on the
+// delivery, so its log shows every try.
+const DeliverKind jobs.Kind[DeliverPayload] = "webhooks.deliver"
+
+type DeliverPayload struct {
+	DeliveryID uuid.UUID `json:"delivery_id"`
+}
+
+const (
+	// MaxAttempts gives up on a delivery after roughly 15 hours of retries.
+	MaxAttempts = 12
+
+	requestTimeout = 10 * time.Second
+	maxErrorLength = 500
+)
+
+// Backoff returns the delay before retrying a delivery that has failed
+// attempts times: 30s doubling per attempt up to 6h, plus up to 10% jitter.
+var Backoff = jobs.Exponential(30*time.Second, 6*time.Hour)
+
+// Publish records a delivery of payload to every webhook of the form
+// subscribed to event and queues the jobs that send them.
+func Publish(ctx context.Context, hooks store.WebhookStore, q store.JobStore, formID uuid.UUID, event string, payload json.RawMessage) error {
+	ids, err := hooks.Enqueue(ctx, formID, event, payload)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, err := jobs.Enqueue(ctx, q, DeliverKind, DeliverPayload{DeliveryID: id}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Redeliver queues a copy of an earlier delivery and returns it.
+func Redeliver(ctx context.Context, hooks store.WebhookStore, q store.JobStore, formID, webhookID, deliveryID uuid.UUID) (*model.WebhookDelivery, error) {
+	d, err := hooks.Redeliver(ctx, formID, webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := jobs.Enqueue(ctx, q, DeliverKind, DeliverPayload{DeliveryID: d.ID}); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Deliverer runs DeliverKind jobs.
+type Deliverer struct {
+	Store  store.WebhookStore
+	Client *http.Client
+}
+
+// NewDeliverer returns a Deliverer whose client only connects to public
+// addresses, whatever the webhook's host resolves to at the time.
+func NewDeliverer(webhooks store.WebhookStore) *Deliverer {
+	dialer := &net.Dialer{Timeout: requestTimeout, Control: dialControl}
+	return &Deliverer{
+		Store: webhooks,
+		Client: &http.Client{
+			Timeout: requestTimeout,
+			// no proxy from the environment, which would be dialled instead
+			// of the receiver and defeat the address check
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: requestTimeout,
+				MaxIdleConnsPerHost: 2,
+				IdleConnTimeout:     90 * time.Second,
+			},
+			// a redirect is reported as a failed attempt rather than followed
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+}
+
+func (d *Deliverer) Register(r *jobs.Registry) {
+	jobs.Handle(r, DeliverKind, d.deliver, jobs.Options{
+		MaxAttempts: MaxAttempts,
+		Timeout:     requestTimeout + 5*time.Second,
+		Backoff:     Backoff,
+	})
+}
+
+func (d *Deliverer) deliver(ctx context.Context, job jobs.Job[DeliverPayload]) error {
+	out, err := d.Store.Delivery(ctx, job.Payload.DeliveryID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil // the webhook was deleted
+	}
+	if err != nil {
+		return err
+	}
+	if out.Delivery.Status != model.DeliveryStatusPending {
+		return nil
+	}
+
+	attempt := store.DeliveryAttempt{Error: "webhook is disabled"}
+	if out.Webhook.Active {
+		attempt.StatusCode, attempt.Error = d.send(ctx, out)
+		attempt.Succeeded = attempt.Error == ""
+		if !attempt.Succeeded {
+			attempt.RetryAt = job.RetryAt
+		}
+	}
+
+	if err := d.Store.RecordAttempt(ctx, out.Delivery.ID, attempt); err != nil {
+		return err
+	}
+	switch {
+	case attempt.Succeeded:
+		return nil
+	case !out.Webhook.Active:
+		return jobs.Permanent(errors.New(attempt.Error))
+	}
+	return errors.New(attempt.Error)
+}
+
+// send posts the delivery once and returns the response status, if any, and
+// an error message unless the receiver answered with a 2xx status.
+func (d *Deliverer) send(ctx context.Context, out *store.OutgoingDelivery) (int, string) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	body := []byte(out.Delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, out.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, truncate(err.Error())
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "craft-webhooks/1")
+	req.Header.Set(EventHeader, out.Delivery.Event)
+	req.Header.Set(DeliveryHeader, out.Delivery.ID.String())
+	req.Header.Set(SignatureHeader, Sign(out.Webhook.Secret, time.Now(), body))
+
+	res, err := d.Client.Do(req)
+	if err != nil {
+		return 0, truncate(err.Error())
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Sprintf("receiver responded %s", res.Status)
+	}
+	return res.StatusCode, ""
+}
+
+func truncate(s string) string {
+	if len(s) > maxErrorLength {
+		return s[:maxErrorLength]
+	}
+	return s
+}
@@ -0,0 +1,241 @@
+package webhooks
+
+import (
+	"craft/internal/jobs"
+	"craft/internal/model"
+	"craft/internal/store"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// receiver is a webhook endpoint answering with the given statuses in turn,
+// the last one repeating.
+type receiver struct {
+	*httptest.Server
+	calls    atomic.Int32
+	requests chan *http.Request
+	bodies   chan []byte
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	rc := &receiver{requests: make(chan *http.Request, 10), bodies: make(chan []byte, 10)}
+	rc.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(rc.calls.Add(1))
+		body, _ := io.ReadAll(r.Body)
+		rc.requests <- r
+		rc.bodies <- body
+		w.WriteHeader(statuses[min(n, len(statuses))-1])
+	}))
+	t.Cleanup(rc.Close)
+	return rc
+}
+
+// hookFixture stores a form with a webhook to url and one pending delivery.
+func hookFixture(t *testing.T, url string) (store.Stores, *model.Webhook, uuid.UUID) {
+	t.Helper()
+	ctx := t.Context()
+	st := store.NewMemory().Stores()
+	f, err := st.Forms.Create(ctx, uuid.New(), "Team lunch", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook := &model.Webhook{FormID: f.ID, URL: url, Secret: NewSecret(), Events: model.WebhookEvents, Active: true}
+	if err := st.Webhooks.Create(ctx, hook); err != nil {
+		t.Fatal(err)
+	}
+	ids, err := st.Webhooks.Enqueue(ctx, f.ID, model.WebhookEventSubmissionCreated, json.RawMessage(`{"event":"submission.created"}`))
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("enqueued %d deliveries, err %v", len(ids), err)
+	}
+	return st, hook, ids[0]
+}
+
+// localDeliverer delivers to the test receiver, which listens on loopback
+// and so is refused by the client of NewDeliverer.
+func localDeliverer(st store.Stores, rc *receiver) *Deliverer {
+	d := NewDeliverer(st.Webhooks)
+	d.Client = rc.Client()
+	return d
+}
+
+func attempt(id uuid.UUID, n int) jobs.Job[DeliverPayload] {
+	retryAt := time.Now().Add(Backoff(n))
+	return jobs.Job[DeliverPayload]{Attempt: n, MaxAttempts: MaxAttempts, Payload: DeliverPayload{DeliveryID: id}, RetryAt: &retryAt}
+}
+
+func delivery(t *testing.T, st store.Stores, id uuid.UUID) model.WebhookDelivery {
+	t.Helper()
+	out, err := st.Webhooks.Delivery(t.Context(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out.Delivery
+}
+
+func TestDeliverSignsAndRecordsSuccess(t *testing.T) {
+	rc := newReceiver(t, http.StatusNoContent)
+	st, hook, id := hookFixture(t, rc.URL+"/hooks/craft")
+
+	before := time.Now().Unix()
+	if err := localDeliverer(st, rc).deliver(t.Context(), attempt(id, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	r, body := <-rc.requests, <-rc.bodies
+	if r.Method != http.MethodPost || r.URL.Path != "/hooks/craft" || string(body) != `{"event":"submission.created"}` {
+		t.Fatalf("%s %s %s", r.Method, r.URL.Path, body)
+	}
+	if r.Header.Get(EventHeader) != model.WebhookEventSubmissionCreated || r.Header.Get(DeliveryHeader) != id.String() {
+		t.Fatalf("event %q, delivery %q", r.Header.Get(EventHeader), r.Header.Get(DeliveryHeader))
+	}
+
+	// the receiver recomputes the signature from the timestamp it was sent
+	sig := r.Header.Get(SignatureHeader)
+	ts, _, _ := strings.Cut(strings.TrimPrefix(sig, "t="), ",")
+	sent, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sent < before || sent > time.Now().Unix() {
+		t.Fatalf("signature %q has a bad timestamp", sig)
+	}
+	if want := Sign(hook.Secret, time.Unix(sent, 0), body); sig != want {
+		t.Fatalf("signature %q, want %q", sig, want)
+	}
+	if Sign("whsec_other", time.Unix(sent, 0), body) == sig {
+		t.Fatal("signature does not depend on the secret")
+	}
+
+	d := delivery(t, st, id)
+	if d.Status != model.DeliveryStatusSucceeded || d.Attempts != 1 || d.DeliveredAt == nil || d.NextAttemptAt != nil {
+		t.Fatalf("delivery %+v", d)
+	}
+	if d.LastStatusCode == nil || *d.LastStatusCode != http.StatusNoContent || d.LastError != nil {
+		t.Fatalf("status %v, error %v", d.LastStatusCode, d.LastError)
+	}
+}
+
+func TestDeliverRetriesServerErrors(t *testing.T) {
+	rc := newReceiver(t, http.StatusServiceUnavailable, http.StatusOK)
+	st, _, id := hookFixture(t, rc.URL)
+	d := localDeliverer(st, rc)
+
+	first := attempt(id, 1)
+	err := d.deliver(t.Context(), first)
+	if err == nil {
+		t.Fatal("the 503 was taken for success")
+	}
+	got := delivery(t, st, id)
+	if got.Status != model.DeliveryStatusPending || got.Attempts != 1 || got.NextAttemptAt == nil || !got.NextAttemptAt.Equal(*first.RetryAt) {
+		t.Fatalf("after the 503: %+v", got)
+	}
+	if got.LastStatusCode == nil || *got.LastStatusCode != http.StatusServiceUnavailable || got.LastError == nil {
+		t.Fatalf("status %v, error %v", got.LastStatusCode, got.LastError)
+	}
+
+	if err := d.deliver(t.Context(), attempt(id, 2)); err != nil {
+		t.Fatal(err)
+	}
+	got = delivery(t, st, id)
+	if got.Status != model.DeliveryStatusSucceeded || got.Attempts != 2 || got.LastError != nil {
+		t.Fatalf("after the retry: %+v", got)
+	}
+	if n := rc.calls.Load(); n != 2 {
+		t.Fatalf("receiver called %d times, want 2", n)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		base     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{4, 4 * time.Minute},
+		{MaxAttempts, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		for range 20 {
+			if got := Backoff(tt.attempts); got < tt.base || got > tt.base+tt.base/10 {
+				t.Fatalf("Backoff(%d) = %v, want %v plus up to 10%%", tt.attempts, got, tt.base)
+			}
+		}
+	}
+}
+
+func TestDeliverRefusesPrivateAddresses(t *testing.T) {
+	rc := newReceiver(t, http.StatusOK)
+	st, _, id := hookFixture(t, rc.URL)
+
+	// the default client, as used in production
+	err := NewDeliverer(st.Webhooks).deliver(t.Context(), attempt(id, 1))
+	if err == nil {
+		t.Fatal("delivered to a loopback address")
+	}
+	if n := rc.calls.Load(); n != 0 {
+		t.Fatalf("receiver called %d times", n)
+	}
+	got := delivery(t, st, id)
+	if got.LastError == nil || !strings.Contains(*got.LastError, "not public") {
+		t.Fatalf("last error %v", got.LastError)
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	for _, url := range []string{
+		"https://hooks.example.com/craft",
+		"http://93.184.215.14:8080/in",
+		"https://[2606:2800:21f:cb07:6820:80da:af6b:8b2c]/in",
+	} {
+		if err := CheckURL(url); err != nil {
+			t.Errorf("CheckURL(%q) = %v", url, err)
+		}
+	}
+
+	for _, url := range []string{
+		"http://localhost:3000/hook",
+		"http://api.localhost/hook",
+		"http://127.0.0.1/hook",
+		"http://[::1]/hook",
+		"http://0.0.0.0/hook",
+		"http://10.0.0.8/hook",
+		"http://172.16.4.1/hook",
+		"http://192.168.1.1/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://metadata.google.internal/computeMetadata/v1/",
+		"http://100.64.0.1/hook",
+		"http://[fd00::1]/hook",
+		"http://[fe80::1]/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+		"ftp://hooks.example.com/craft",
+	} {
+		if err := CheckURL(url); err == nil {
+			t.Errorf("CheckURL(%q) accepted it", url)
+		}
+	}
+}
+
+func TestPublicAddr(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"8.8.8.8":              true,
+		"2001:4860:4860::8888": true,
+		"127.0.0.53":           false,
+		"10.1.2.3":             false,
+		"169.254.169.254":      false,
+		"::ffff:10.1.2.3":      false,
+		"224.0.0.1":            false,
+		"255.255.255.255":      false,
+	} {
+		if got := PublicAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("PublicAddr(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package webhooks
+
+import (
+	"craft/internal/model"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Payload is the JSON body of every delivery.
+type Payload struct {
+	Event      string                       `json:"event"`
+	OccurredAt time.Time                    `json:"occurred_at"`
+	Form       PayloadForm                  `json:"form"`
+	Submission *model.SubmissionWithAnswers `json:"submission,omitempty"`
+}
+
+type PayloadForm struct {
+	ID    uuid.UUID `json:"id"`
+	Title string    `json:"title"`
+}
+
+// SubmissionCreated builds the payload of a model.WebhookEventSubmissionCreated
+// event, with question titles and types filled in on the answers.
+func SubmissionCreated(f *model.Form, sub model.Submission, answers []model.Answer) (json.RawMessage, error) {
+	questions := make(map[uuid.UUID]model.Question, len(f.Questions))
+	for _, q := range f.Questions {
+		questions[q.ID] = q
+	}
+
+	withTitles := make([]model.Answer, len(answers))
+	for i, a := range answers {
+		if q, ok := questions[a.QuestionID]; ok {
+			a.QuestionTitle = q.Title
+			a.QuestionType = q.Type
+		}
+		withTitles[i] = a
+	}
+
+	// network details are not for third parties
+	sub.IPAddress = nil
+	sub.UserAgent = nil
+
+	return json.Marshal(Payload{
+		Event:      model.WebhookEventSubmissionCreated,
+		OccurredAt: sub.CreatedAt,
+		Form:       PayloadForm{ID: f.ID, Title: f.Title},
+		Submission: &model.SubmissionWithAnswers{Submission: sub, Answers: withTitles},
+	})
+}
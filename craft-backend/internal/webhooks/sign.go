@@ -0,0 +1,41 @@
+// Package webhooks builds, signs and delivers the payloads sent to a form's
+// webhook subscriptions.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+const (
+	// SignatureHeader carries "t=<unix seconds>,v1=<hex HMAC-SHA256>", where
+	// the HMAC is keyed with the webhook secret and computed over
+	// "<unix seconds>.<request body>". Receivers should reject stale
+	// timestamps to prevent replays.
+	SignatureHeader = "X-Craft-Signature"
+	EventHeader     = "X-Craft-Event"
+	DeliveryHeader  = "X-Craft-Delivery"
+
+	secretPrefix = "whsec_"
+)
+
+// NewSecret returns a random signing secret for a webhook.
+func NewSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return secretPrefix + hex.EncodeToString(b)
+}
+
+// Sign returns the SignatureHeader value for body sent at t.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
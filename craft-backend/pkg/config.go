@@ -1,7 +1,10 @@
 package pkg
 
 import (
+	"errors"
+	"io/fs"
 	"log"
+	"sort"
 
 	"github.com/joho/godotenv"
 )
@@ -13,22 +16,77 @@ type Config struct {
 	SECRET_KEY   string
 	ANON_KEY     string
 	DEV_MODE     bool
+
+	// APP_URL is the frontend's base URL, for links in emails.
+	APP_URL string
+	// Mail is logged instead of sent when SMTP_HOST is empty.
+	SMTP_HOST     string
+	SMTP_PORT     int
+	SMTP_USERNAME string
+	SMTP_PASSWORD string
+	SMTP_FROM     string
+
+	// STORAGE_DRIVER is "local", keeping uploads under STORAGE_DIR, or "s3"
+	// for any S3-compatible service.
+	STORAGE_DRIVER string
+	STORAGE_DIR    string
+	S3_ENDPOINT    string
+	S3_REGION      string
+	S3_BUCKET      string
+	S3_ACCESS_KEY  string
+	S3_SECRET_KEY  string
+	S3_PATH_STYLE  bool
 }
 
 var Envs = initConfig()
 
 func initConfig() Config {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
+	// the environment alone is enough, as in tests and containers
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Fatalf("Error loading .env file: %v", err)
 	}
 
 	return Config{
 		API_PORT:     GetEnvAsInt("PORT", 8080),
-		DATABASE_URL: GetEnv("DATABASE_URL", ""),
-		PROJECT_URL:  GetEnv("PROJECT_URL", ""),
-		SECRET_KEY:   GetEnv("SECRET_KEY", ""),
-		ANON_KEY:     GetEnv("ANON_KEY", ""),
+		DATABASE_URL: GetOptionalEnv("DATABASE_URL"),
+		PROJECT_URL:  GetOptionalEnv("PROJECT_URL"),
+		SECRET_KEY:   GetOptionalEnv("SECRET_KEY"),
+		ANON_KEY:     GetOptionalEnv("ANON_KEY"),
 		DEV_MODE:     GetEnv("DEV_MODE", "false") == "true",
+
+		APP_URL:       GetEnv("APP_URL", "http://localhost:5173"),
+		SMTP_HOST:     GetOptionalEnv("SMTP_HOST"),
+		SMTP_PORT:     GetEnvAsInt("SMTP_PORT", 587),
+		SMTP_USERNAME: GetOptionalEnv("SMTP_USERNAME"),
+		SMTP_PASSWORD: GetOptionalEnv("SMTP_PASSWORD"),
+		SMTP_FROM:     GetEnv("SMTP_FROM", "Craft <no-reply@localhost>"),
+
+		STORAGE_DRIVER: GetEnv("STORAGE_DRIVER", "local"),
+		STORAGE_DIR:    GetEnv("STORAGE_DIR", "uploads"),
+		S3_ENDPOINT:    GetOptionalEnv("S3_ENDPOINT"),
+		S3_REGION:      GetEnv("S3_REGION", "us-east-1"),
+		S3_BUCKET:      GetOptionalEnv("S3_BUCKET"),
+		S3_ACCESS_KEY:  GetOptionalEnv("S3_ACCESS_KEY"),
+		S3_SECRET_KEY:  GetOptionalEnv("S3_SECRET_KEY"),
+		S3_PATH_STYLE:  GetEnv("S3_PATH_STYLE", "false") == "true",
+	}
+}
+
+// Missing returns the names of the settings the server cannot run without
+// that are not set. They are checked on startup rather than on import, so
+// packages that only need helpers from pkg work without them.
+func (c Config) Missing() []string {
+	var missing []string
+	for name, value := range map[string]string{
+		"DATABASE_URL": c.DATABASE_URL,
+		"PROJECT_URL":  c.PROJECT_URL,
+		"SECRET_KEY":   c.SECRET_KEY,
+		"ANON_KEY":     c.ANON_KEY,
+	} {
+		if value == "" {
+			missing = append(missing, name)
+		}
 	}
+	sort.Strings(missing)
+	return missing
 }
@@ -25,6 +25,11 @@ func GetEnv(key, fallback string) string {
 	return fallback
 }
 
+// GetOptionalEnv is GetEnv for settings that may be left empty.
+func GetOptionalEnv(key string) string {
+	return strings.TrimSpace(os.Getenv(key))
+}
+
 func GetEnvAsInt(key string, fallback int) int {
 	if value, ok := os.LookupEnv(key); ok {
 		i, err := strconv.Atoi(value)